@@ -0,0 +1,157 @@
+// Package cinema plays cutscenes: camera flights along spline paths, with animations, body movements,
+// sounds and subtitles triggered along a timeline.
+package cinema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+	"github.com/lsmith130/space/univ"
+)
+
+// Interpolation is how a Path curves between its keyframes.
+type Interpolation string
+
+const (
+	// CatmullRom curves smoothly through every keyframe, shaped by its neighbouring keyframes.
+	CatmullRom Interpolation = "catmull-rom"
+	// Bezier curves between keyframes along their handles. Keyframes without handles are given
+	// Catmull-Rom ones.
+	Bezier Interpolation = "bezier"
+)
+
+// Keyframe is a point a camera passes through on a Path.
+type Keyframe struct {
+	// Time is the number of seconds into the path the camera reaches the keyframe.
+	Time float32 `json:"time"`
+	// Position is the location of the camera and LookAt is the point it looks at.
+	Position mgl32.Vec3 `json:"position"`
+	LookAt   mgl32.Vec3 `json:"lookAt"`
+	// Roll is the angle the camera is turned around the direction it looks in, in degrees.
+	Roll float32 `json:"roll"`
+	// FOV is the vertical field of view of the camera, in degrees. The default is used when it is zero.
+	FOV float32 `json:"fov"`
+	// In and Out are the Bezier handles of Position, relative to it, that the curve arrives along and
+	// leaves along.
+	In  mgl32.Vec3 `json:"in"`
+	Out mgl32.Vec3 `json:"out"`
+}
+
+// Path is a flight for a camera through keyframes.
+type Path struct {
+	Interpolation Interpolation `json:"interpolation"`
+	Keyframes     []Keyframe    `json:"keyframes"`
+}
+
+// validate sorts the keyframes of p by time and checks p can be sampled.
+func (p *Path) validate() error {
+	if len(p.Keyframes) == 0 {
+		return fmt.Errorf("path has no keyframes")
+	}
+	switch p.Interpolation {
+	case "":
+		p.Interpolation = CatmullRom
+	case CatmullRom, Bezier:
+	default:
+		return fmt.Errorf("unknown interpolation %q", p.Interpolation)
+	}
+	sort.SliceStable(p.Keyframes, func(i, j int) bool {
+		return p.Keyframes[i].Time < p.Keyframes[j].Time
+	})
+	return nil
+}
+
+// Duration returns the time of the last keyframe of p.
+func (p *Path) Duration() float32 {
+	if len(p.Keyframes) == 0 {
+		return 0
+	}
+	return p.Keyframes[len(p.Keyframes)-1].Time
+}
+
+// Sample returns the view of a camera t seconds along p. Before the first keyframe and after the last
+// the camera holds still.
+func (p *Path) Sample(t float32) univ.CameraView {
+	keys := p.Keyframes
+	i := sort.Search(len(keys), func(i int) bool { return keys[i].Time > t })
+	if i == 0 {
+		return keys[0].view()
+	}
+	if i == len(keys) {
+		return keys[len(keys)-1].view()
+	}
+
+	k0, k1 := keys[i-1], keys[i]
+	u := float32(0)
+	if span := k1.Time - k0.Time; span > 0 {
+		u = (t - k0.Time) / span
+	}
+
+	// The neighbours of the segment, repeated at the ends of the path.
+	prev, next := k0, k1
+	if i >= 2 {
+		prev = keys[i-2]
+	}
+	if i+1 < len(keys) {
+		next = keys[i+1]
+	}
+
+	var pos, lookAt mgl32.Vec3
+	switch p.Interpolation {
+	case Bezier:
+		out, in := k0.Out, k1.In
+		if out.Len() == 0 {
+			out = k1.Position.Sub(prev.Position).Mul(1.0 / 6)
+		}
+		if in.Len() == 0 {
+			in = k0.Position.Sub(next.Position).Mul(1.0 / 6)
+		}
+		pos = mgl32.CubicBezierCurve3D(u, k0.Position, k0.Position.Add(out), k1.Position.Add(in), k1.Position)
+		lookAt = catmullRom(prev.LookAt, k0.LookAt, k1.LookAt, next.LookAt, u)
+	default:
+		pos = catmullRom(prev.Position, k0.Position, k1.Position, next.Position, u)
+		lookAt = catmullRom(prev.LookAt, k0.LookAt, k1.LookAt, next.LookAt, u)
+	}
+
+	// Roll and FOV ease between keyframes rather than curve, so they never overshoot.
+	s := u * u * (3 - 2*u)
+	roll := k0.Roll + (k1.Roll-k0.Roll)*s
+	fov := k0.fov() + (k1.fov()-k0.fov())*s
+	return rolledView(pos, lookAt, roll, fov)
+}
+
+// view returns the view of a camera at k.
+func (k Keyframe) view() univ.CameraView {
+	return rolledView(k.Position, k.LookAt, k.Roll, k.fov())
+}
+
+// fov returns the field of view of k in radians.
+func (k Keyframe) fov() float32 {
+	if k.FOV == 0 {
+		return univ.DefaultFOV
+	}
+	return mgl32.DegToRad(k.FOV)
+}
+
+// rolledView returns the view from eye looking at center, turned by roll degrees.
+func rolledView(eye, center mgl32.Vec3, roll, fov float32) univ.CameraView {
+	projection := draw.DefaultProjection
+	projection.FOV = fov
+	v := univ.LookAtView(eye, center, mgl32.Vec3{0, 1, 0}, projection)
+	v.Rotation = v.Rotation.Mul(mgl32.QuatRotate(mgl32.DegToRad(roll), mgl32.Vec3{0, 0, 1}))
+	return v
+}
+
+// catmullRom returns the point u of the way from p1 to p2 on the uniform Catmull-Rom spline through
+// p0, p1, p2 and p3.
+func catmullRom(p0, p1, p2, p3 mgl32.Vec3, u float32) mgl32.Vec3 {
+	u2 := u * u
+	u3 := u2 * u
+	return p1.Mul(2).
+		Add(p2.Sub(p0).Mul(u)).
+		Add(p0.Mul(2).Sub(p1.Mul(5)).Add(p2.Mul(4)).Sub(p3).Mul(u2)).
+		Add(p1.Mul(3).Sub(p0).Sub(p2.Mul(3)).Add(p3).Mul(u3)).
+		Mul(0.5)
+}
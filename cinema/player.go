@@ -0,0 +1,327 @@
+package cinema
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep/speaker"
+	"github.com/faiface/beep/wav"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+	"github.com/lsmith130/space/univ"
+)
+
+// cameraName is the name a Player adds its camera to the camera manager with.
+const cameraName = "cinema"
+
+// Observer is an observer of a playing timeline. See Player.AddObserver and Player.RemoveObserver for
+// details on how to manage observers of a Player.
+type Observer interface {
+	// Subtitle is called on each observer when a subtitle should be shown for duration.
+	Subtitle(text string, duration time.Duration)
+	// Finished is called on each observer when the timeline ends, and reports whether it was skipped.
+	Finished(skipped bool)
+}
+
+// Player plays a Timeline in a Universe. While it plays, the universe is drawn from the timeline's
+// camera, and when it finishes the camera that was active before is restored.
+//
+// All Player functions are safe to use concurrently.
+type Player struct {
+	timeline *Timeline
+	u        *univ.Universe
+	bodies   map[string]*univ.Body
+	camera   *pathCamera
+	blend    time.Duration
+
+	mut       sync.Mutex
+	time      float32
+	next      int
+	moves     []*move
+	previous  string
+	started   bool
+	finished  bool
+	observers map[Observer]struct{}
+
+	ticker *draw.Ticker
+}
+
+// move is a body moving during a move event.
+type move struct {
+	event    Event
+	body     *univ.Body
+	from     mgl32.Vec3
+	fromRot  mgl32.Quat
+	toRot    mgl32.Quat
+	turn     bool
+	finished bool
+}
+
+// NewPlayer creates a paused Player of t in u. bodies holds the bodies t's events act on by name, and
+// blend is how long the camera takes to blend into and out of the timeline.
+func NewPlayer(t *Timeline, u *univ.Universe, bodies map[string]*univ.Body, blend time.Duration) (*Player, error) {
+	for _, name := range t.Bodies() {
+		if _, ok := bodies[name]; !ok {
+			return nil, fmt.Errorf("play timeline: no body %q", name)
+		}
+	}
+
+	p := &Player{
+		timeline:  t,
+		u:         u,
+		bodies:    bodies,
+		blend:     blend,
+		observers: make(map[Observer]struct{}),
+	}
+	if t.Camera != nil {
+		p.camera = &pathCamera{path: t.Camera}
+	}
+	p.ticker = draw.NewTicker(univ.DefaultRefreshRate, p.tick)
+	p.ticker.Stop()
+	return p, nil
+}
+
+// Destroy stops p and cleans up its resources. p should not be used after it is destroyed.
+func (p *Player) Destroy() {
+	p.ticker.Close()
+}
+
+// AddObserver adds an observer to p. If o is already observing p, AddObserver has no effect.
+func (p *Player) AddObserver(o Observer) {
+	p.mut.Lock()
+	p.observers[o] = struct{}{}
+	p.mut.Unlock()
+}
+
+// RemoveObserver removes an observer from p. If o isn't observing p, RemoveObserver has no effect.
+func (p *Player) RemoveObserver(o Observer) {
+	p.mut.Lock()
+	delete(p.observers, o)
+	p.mut.Unlock()
+}
+
+// Start starts or resumes playing the timeline.
+func (p *Player) Start() {
+	p.mut.Lock()
+	if p.finished {
+		p.mut.Unlock()
+		return
+	}
+	if !p.started {
+		p.started = true
+		if p.camera != nil {
+			p.previous = p.u.Cameras.Active()
+			p.u.Cameras.Add(cameraName, p.camera)
+			if err := p.u.Cameras.Activate(cameraName, p.blend); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	p.mut.Unlock()
+	p.ticker.Start()
+}
+
+// Pause pauses the timeline until Start is called.
+func (p *Player) Pause() {
+	p.ticker.Stop()
+}
+
+// Finished returns whether the timeline has ended.
+func (p *Player) Finished() bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return p.finished
+}
+
+// Time returns how many seconds into the timeline p is.
+func (p *Player) Time() float32 {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return p.time
+}
+
+// Skip ends the timeline immediately. Moves are finished where they would end, but sounds,
+// animations and subtitles that haven't happened yet are dropped.
+func (p *Player) Skip() {
+	p.mut.Lock()
+	if p.finished || !p.started {
+		p.mut.Unlock()
+		return
+	}
+
+	for ; p.next < len(p.timeline.Events); p.next++ {
+		if e := p.timeline.Events[p.next]; e.Type == EventMove {
+			p.moves = append(p.moves, p.startMove(e))
+		}
+	}
+	for _, m := range p.moves {
+		m.update(m.event.Time + m.event.Duration)
+	}
+	p.moves = nil
+
+	observers := p.finish()
+	p.mut.Unlock()
+
+	for _, o := range observers {
+		o.Finished(true)
+	}
+}
+
+func (p *Player) tick(elapsed float32) {
+	p.mut.Lock()
+	if p.finished {
+		p.mut.Unlock()
+		return
+	}
+	p.time += elapsed
+	now := p.time
+	if p.camera != nil {
+		p.camera.setTime(now)
+	}
+
+	var subtitles []Event
+	for ; p.next < len(p.timeline.Events); p.next++ {
+		e := p.timeline.Events[p.next]
+		if e.Time > now {
+			break
+		}
+		switch e.Type {
+		case EventAnimation:
+			if err := p.bodies[e.Body].PlayAnimation(e.Clip); err != nil {
+				log.Println(err)
+			}
+		case EventMove:
+			p.moves = append(p.moves, p.startMove(e))
+		case EventSound:
+			playSound(e.File)
+		case EventSubtitle:
+			subtitles = append(subtitles, e)
+		}
+	}
+
+	moves := p.moves[:0]
+	for _, m := range p.moves {
+		m.update(now)
+		if !m.finished {
+			moves = append(moves, m)
+		}
+	}
+	p.moves = moves
+
+	var observers []Observer
+	done := now >= p.timeline.Duration
+	if done {
+		observers = p.finish()
+	} else {
+		observers = p.observerList()
+	}
+	p.mut.Unlock()
+
+	for _, e := range subtitles {
+		for _, o := range observers {
+			o.Subtitle(e.Text, time.Duration(e.Duration*float32(time.Second)))
+		}
+	}
+	if done {
+		for _, o := range observers {
+			o.Finished(false)
+		}
+	}
+}
+
+// finish ends the timeline, hands the view back to the camera that was active before, and returns the
+// observers to notify. p.mut must be held.
+func (p *Player) finish() []Observer {
+	p.finished = true
+
+	if p.camera != nil {
+		if p.previous != "" {
+			if err := p.u.Cameras.Activate(p.previous, p.blend); err != nil {
+				log.Println(err)
+			}
+		}
+		p.u.Cameras.Remove(cameraName)
+	}
+	return p.observerList()
+}
+
+// observerList returns the observers of p. p.mut must be held.
+func (p *Player) observerList() []Observer {
+	observers := make([]Observer, 0, len(p.observers))
+	for o := range p.observers {
+		observers = append(observers, o)
+	}
+	return observers
+}
+
+// startMove returns a move for e, starting from where its body is now. p.mut must be held.
+func (p *Player) startMove(e Event) *move {
+	b := p.bodies[e.Body]
+	m := &move{
+		event:   e,
+		body:    b,
+		from:    b.Location(),
+		fromRot: b.Rotation(),
+		turn:    e.Facing.Len() > 0,
+	}
+	if m.turn {
+		m.toRot = univ.LookRotation(e.Facing, mgl32.Vec3{0, 1, 0})
+	}
+	b.SetVelocity(mgl32.Vec3{})
+	b.SetAngularV(mgl32.Vec3{})
+	return m
+}
+
+// update moves m's body to where it should be at now, easing in and out of the move.
+func (m *move) update(now float32) {
+	u := float32(1)
+	if m.event.Duration > 0 {
+		u = mgl32.Clamp((now-m.event.Time)/m.event.Duration, 0, 1)
+	}
+	m.finished = u >= 1
+	s := u * u * (3 - 2*u)
+
+	m.body.SetLocation(m.from.Add(m.event.To.Sub(m.from).Mul(s)))
+	if m.turn {
+		m.body.SetRotation(mgl32.QuatSlerp(m.fromRot.Normalize(), m.toRot, s))
+	}
+}
+
+// playSound plays the WAV file at path, logging any error.
+func playSound(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("play sound %s: %v", path, err)
+		return
+	}
+	s, _, err := wav.Decode(f)
+	if err != nil {
+		log.Printf("play sound %s: %v", path, err)
+		f.Close()
+		return
+	}
+	speaker.Play(s)
+}
+
+// pathCamera is the camera of a playing timeline, flying along its path.
+type pathCamera struct {
+	mut  sync.Mutex
+	path *Path
+	time float32
+}
+
+func (c *pathCamera) setTime(t float32) {
+	c.mut.Lock()
+	c.time = t
+	c.mut.Unlock()
+}
+
+// View conforms to univ.Camera.View
+func (c *pathCamera) View() univ.CameraView {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.path.Sample(c.time)
+}
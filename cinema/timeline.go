@@ -0,0 +1,122 @@
+package cinema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// EventType is the kind of thing an Event does.
+type EventType string
+
+const (
+	// EventAnimation plays the animation Clip on Body.
+	EventAnimation EventType = "animation"
+	// EventMove moves Body to To over Duration seconds, turning it to face along Facing if it is set.
+	EventMove EventType = "move"
+	// EventSound plays the WAV file File.
+	EventSound EventType = "sound"
+	// EventSubtitle shows Text for Duration seconds.
+	EventSubtitle EventType = "subtitle"
+)
+
+// Event is something a Timeline does at a set time.
+type Event struct {
+	// Time is the number of seconds into the timeline the event happens.
+	Time     float32    `json:"time"`
+	Type     EventType  `json:"type"`
+	Body     string     `json:"body"`
+	Clip     string     `json:"clip"`
+	To       mgl32.Vec3 `json:"to"`
+	Facing   mgl32.Vec3 `json:"facing"`
+	File     string     `json:"file"`
+	Text     string     `json:"text"`
+	Duration float32    `json:"duration"`
+}
+
+// Timeline is a cutscene: a camera flight and the events that happen during it. Timelines are loaded
+// from JSON files and played with a Player.
+type Timeline struct {
+	// Camera is the path the camera flies along, or nil to leave the camera alone.
+	Camera *Path `json:"camera"`
+	// Events is the events of the timeline, in order of time once loaded.
+	Events []Event `json:"events"`
+	// Duration is the length of the timeline in seconds. When it is zero, the timeline lasts until the
+	// camera stops and every event has finished.
+	Duration float32 `json:"duration"`
+}
+
+// Load reads the timeline file at path.
+func Load(path string) (*Timeline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open timeline %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var t Timeline
+	if err := json.NewDecoder(f).Decode(&t); err != nil {
+		return nil, fmt.Errorf("parse timeline %s: %v", path, err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("load timeline %s: %v", path, err)
+	}
+	return &t, nil
+}
+
+// validate puts the events and keyframes of t in order, checks they make sense and works out t's
+// duration if it isn't set.
+func (t *Timeline) validate() error {
+	if t.Camera != nil {
+		if err := t.Camera.validate(); err != nil {
+			return fmt.Errorf("camera: %v", err)
+		}
+	}
+
+	sort.SliceStable(t.Events, func(i, j int) bool {
+		return t.Events[i].Time < t.Events[j].Time
+	})
+	for i, e := range t.Events {
+		switch e.Type {
+		case EventAnimation, EventMove:
+			if e.Body == "" {
+				return fmt.Errorf("event %d: %s event has no body", i, e.Type)
+			}
+		case EventSound:
+			if e.File == "" {
+				return fmt.Errorf("event %d: sound event has no file", i)
+			}
+		case EventSubtitle:
+		default:
+			return fmt.Errorf("event %d: unknown type %q", i, e.Type)
+		}
+	}
+
+	if t.Duration == 0 {
+		if t.Camera != nil {
+			t.Duration = t.Camera.Duration()
+		}
+		for _, e := range t.Events {
+			if end := e.Time + e.Duration; end > t.Duration {
+				t.Duration = end
+			}
+		}
+	}
+	return nil
+}
+
+// Bodies returns the names of the bodies the events of t act on.
+func (t *Timeline) Bodies() []string {
+	seen := make(map[string]struct{})
+	var names []string
+	for _, e := range t.Events {
+		if _, ok := seen[e.Body]; e.Body != "" && !ok {
+			seen[e.Body] = struct{}{}
+			names = append(names, e.Body)
+		}
+	}
+	return names
+}
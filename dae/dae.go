@@ -0,0 +1,131 @@
+// Package dae reads scene information from Collada (.dae) files that the model loader doesn't provide,
+// such as the names and transforms of nodes and the materials of meshes.
+package dae
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// File is a parsed Collada file.
+type File struct {
+	doc document
+}
+
+// Node is a node of the visual scene of a Collada file.
+type Node struct {
+	Name string
+	// Transform is the transform of the node relative to the scene root, converted to Y up.
+	Transform mgl32.Mat4
+}
+
+// Open parses the Collada file at path.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var file File
+	if err := xml.NewDecoder(f).Decode(&file.doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	return &file, nil
+}
+
+// Nodes returns every node of the visual scenes of f, parents before their children.
+func (f *File) Nodes() []Node {
+	root := mgl32.Ident4()
+	if f.doc.Asset.UpAxis == "Z_UP" {
+		// Match the model loader, which rotates Z up scenes to be Y up.
+		root = mgl32.HomogRotate3DX(mgl32.DegToRad(-90))
+	}
+
+	var nodes []Node
+	var walk func(n xmlNode, parent mgl32.Mat4)
+	walk = func(n xmlNode, parent mgl32.Mat4) {
+		transform := parent.Mul4(n.transform())
+		nodes = append(nodes, Node{Name: n.Name, Transform: transform})
+		for _, child := range n.Nodes {
+			walk(child, transform)
+		}
+	}
+	for _, scene := range f.doc.Scenes {
+		for _, n := range scene.Nodes {
+			walk(n, root)
+		}
+	}
+	return nodes
+}
+
+type document struct {
+	Asset struct {
+		UpAxis string `xml:"up_axis"`
+	} `xml:"asset"`
+	Scenes []struct {
+		Nodes []xmlNode `xml:"node"`
+	} `xml:"library_visual_scenes>visual_scene"`
+	Images      []xmlImage      `xml:"library_images>image"`
+	Materials   []xmlMaterial   `xml:"library_materials>material"`
+	Effects     []xmlEffect     `xml:"library_effects>effect"`
+	Geometries  []xmlGeometry   `xml:"library_geometries>geometry"`
+	Controllers []xmlController `xml:"library_controllers>controller"`
+}
+
+type xmlNode struct {
+	Name        string        `xml:"name,attr"`
+	Nodes       []xmlNode     `xml:"node"`
+	Geometries  []xmlInstance `xml:"instance_geometry"`
+	Controllers []xmlInstance `xml:"instance_controller"`
+	Elements    []xmlElement  `xml:",any"`
+}
+
+type xmlElement struct {
+	XMLName xml.Name
+	Data    string `xml:",chardata"`
+}
+
+// transform combines the transform elements of n in the order they appear.
+func (n xmlNode) transform() mgl32.Mat4 {
+	transform := mgl32.Ident4()
+	for _, e := range n.Elements {
+		v := floats(e.Data)
+		switch {
+		case e.XMLName.Local == "matrix" && len(v) == 16:
+			// Collada matrices are row major.
+			transform = transform.Mul4(mgl32.Mat4{
+				v[0], v[4], v[8], v[12],
+				v[1], v[5], v[9], v[13],
+				v[2], v[6], v[10], v[14],
+				v[3], v[7], v[11], v[15],
+			})
+		case e.XMLName.Local == "translate" && len(v) == 3:
+			transform = transform.Mul4(mgl32.Translate3D(v[0], v[1], v[2]))
+		case e.XMLName.Local == "rotate" && len(v) == 4:
+			transform = transform.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(v[3]), mgl32.Vec3{v[0], v[1], v[2]}.Normalize()))
+		case e.XMLName.Local == "scale" && len(v) == 3:
+			transform = transform.Mul4(mgl32.Scale3D(v[0], v[1], v[2]))
+		}
+	}
+	return transform
+}
+
+// floats parses a whitespace separated list of numbers, skipping any that are malformed.
+func floats(s string) []float32 {
+	fields := strings.Fields(s)
+	v := make([]float32, 0, len(fields))
+	for _, field := range fields {
+		f, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			continue
+		}
+		v = append(v, float32(f))
+	}
+	return v
+}
@@ -0,0 +1,354 @@
+package dae
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Material is a material of a Collada file. Map fields hold the paths of image files as written in the
+// file, and are empty for channels given as plain colors.
+type Material struct {
+	Name string
+
+	DiffuseMap    string
+	DiffuseColor  mgl32.Vec3
+	SpecularMap   string
+	SpecularColor mgl32.Vec3
+	Shininess     float32
+	NormalMap     string
+	EmissiveMap   string
+	EmissiveColor mgl32.Vec3
+	// Opacity is how opaque the material is, from 0 to 1. TransparentMap is the image whose alpha
+	// cuts out the material, usually the diffuse map, or empty if it has none.
+	Opacity        float32
+	TransparentMap string
+}
+
+// MeshMaterial is the material of one of the meshes a model loader creates from a Collada file, one for
+// each group of triangles of each geometry placed in its visual scenes. Geometries placed more than once
+// with the same materials share their meshes.
+type MeshMaterial struct {
+	// Material is the name of the material of the mesh, or empty if it has none.
+	Material string
+	// Triangles is the number of triangles in the mesh.
+	Triangles int
+}
+
+// Materials returns the materials of f by name.
+func (f *File) Materials() map[string]Material {
+	effects := make(map[string]xmlEffect, len(f.doc.Effects))
+	for _, e := range f.doc.Effects {
+		effects[e.ID] = e
+	}
+	images := make(map[string]string, len(f.doc.Images))
+	for _, img := range f.doc.Images {
+		images[img.ID] = imagePath(img.InitFrom)
+	}
+
+	materials := make(map[string]Material, len(f.doc.Materials))
+	for _, m := range f.doc.Materials {
+		name := m.Name
+		if name == "" {
+			name = m.ID
+		}
+		material := Material{Name: name, DiffuseColor: mgl32.Vec3{1, 1, 1}, Opacity: 1}
+		if e, ok := effects[strings.TrimPrefix(m.Effect.URL, "#")]; ok {
+			e.apply(&material, images)
+		}
+		materials[name] = material
+	}
+	return materials
+}
+
+// MeshMaterials returns the materials of the meshes of f, in the order model loaders create them: each
+// geometry in the order the visual scenes first place it, and each group of triangles within it in
+// order.
+func (f *File) MeshMaterials() []MeshMaterial {
+	geometries := make(map[string]xmlGeometry, len(f.doc.Geometries))
+	for _, g := range f.doc.Geometries {
+		geometries[g.ID] = g
+	}
+	skins := make(map[string]string, len(f.doc.Controllers))
+	for _, c := range f.doc.Controllers {
+		skins[c.ID] = strings.TrimPrefix(c.Skin.Source, "#")
+	}
+	names := make(map[string]string, len(f.doc.Materials))
+	for _, m := range f.doc.Materials {
+		names[m.ID] = m.Name
+		if m.Name == "" {
+			names[m.ID] = m.ID
+		}
+	}
+
+	// Model loaders reuse the mesh of a group of triangles for every instance that binds it to the same
+	// material.
+	type meshKey struct {
+		geometry  string
+		primitive int
+		material  string
+	}
+	seen := make(map[meshKey]bool)
+
+	var meshes []MeshMaterial
+	var walk func(n xmlNode)
+	walk = func(n xmlNode) {
+		for _, inst := range append(n.Geometries, n.Controllers...) {
+			id := strings.TrimPrefix(inst.URL, "#")
+			if inst.XMLName.Local == "instance_controller" {
+				id = skins[id]
+			}
+			g, ok := geometries[id]
+			if !ok {
+				continue
+			}
+
+			// Triangles name their material by a symbol, which the instance binds to a material.
+			bound := make(map[string]string, len(inst.Materials))
+			for _, b := range inst.Materials {
+				bound[b.Symbol] = names[strings.TrimPrefix(b.Target, "#")]
+			}
+			for i, p := range g.Mesh.Primitives {
+				if !p.isTriangles() {
+					continue
+				}
+				material, ok := bound[p.Material]
+				if !ok {
+					material = names[p.Material]
+				}
+				key := meshKey{geometry: id, primitive: i, material: material}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				meshes = append(meshes, MeshMaterial{Material: material, Triangles: p.triangles()})
+			}
+		}
+		for _, child := range n.Nodes {
+			walk(child)
+		}
+	}
+	for _, scene := range f.doc.Scenes {
+		for _, n := range scene.Nodes {
+			walk(n)
+		}
+	}
+	return meshes
+}
+
+type xmlImage struct {
+	ID       string `xml:"id,attr"`
+	InitFrom string `xml:"init_from"`
+}
+
+type xmlMaterial struct {
+	ID     string `xml:"id,attr"`
+	Name   string `xml:"name,attr"`
+	Effect struct {
+		URL string `xml:"url,attr"`
+	} `xml:"instance_effect"`
+}
+
+type xmlEffect struct {
+	ID     string `xml:"id,attr"`
+	Params []struct {
+		SID     string `xml:"sid,attr"`
+		Surface string `xml:"surface>init_from"`
+		Source  string `xml:"sampler2D>source"`
+	} `xml:"profile_COMMON>newparam"`
+	Phongs   []xmlShader `xml:"profile_COMMON>technique>phong"`
+	Blinns   []xmlShader `xml:"profile_COMMON>technique>blinn"`
+	Lamberts []xmlShader `xml:"profile_COMMON>technique>lambert"`
+	// Normal maps aren't part of the common profile, and exporters put them in an extra technique.
+	Bump       xmlChannel `xml:"profile_COMMON>technique>extra>technique>bump"`
+	EffectBump xmlChannel `xml:"extra>technique>bump"`
+}
+
+type xmlShader struct {
+	Emission  xmlChannel `xml:"emission"`
+	Diffuse   xmlChannel `xml:"diffuse"`
+	Specular  xmlChannel `xml:"specular"`
+	Shininess string     `xml:"shininess>float"`
+	// Transparent is scaled by Transparency into how see-through the material is.
+	Transparent  xmlTransparent `xml:"transparent"`
+	Transparency string         `xml:"transparency>float"`
+}
+
+// xmlTransparent is a transparent channel, whose Opaque mode says how its color is read.
+type xmlTransparent struct {
+	xmlChannel
+	Opaque string `xml:"opaque,attr"`
+}
+
+type xmlChannel struct {
+	Color   string `xml:"color"`
+	Texture struct {
+		Texture string `xml:"texture,attr"`
+	} `xml:"texture"`
+}
+
+type xmlGeometry struct {
+	ID   string `xml:"id,attr"`
+	Mesh struct {
+		Primitives []xmlPrimitive `xml:",any"`
+	} `xml:"mesh"`
+}
+
+type xmlPrimitive struct {
+	XMLName  xml.Name
+	Material string `xml:"material,attr"`
+	Count    int    `xml:"count,attr"`
+	VCount   string `xml:"vcount"`
+}
+
+type xmlController struct {
+	ID   string `xml:"id,attr"`
+	Skin struct {
+		Source string `xml:"source,attr"`
+	} `xml:"skin"`
+}
+
+type xmlInstance struct {
+	XMLName   xml.Name
+	URL       string `xml:"url,attr"`
+	Materials []struct {
+		Symbol string `xml:"symbol,attr"`
+		Target string `xml:"target,attr"`
+	} `xml:"bind_material>technique_common>instance_material"`
+}
+
+// apply sets the channels of material from e, looking up the paths of its images in images.
+func (e xmlEffect) apply(material *Material, images map[string]string) {
+	var shaders []xmlShader
+	shaders = append(shaders, e.Phongs...)
+	shaders = append(shaders, e.Blinns...)
+	shaders = append(shaders, e.Lamberts...)
+	if len(shaders) == 0 {
+		return
+	}
+	s := shaders[0]
+
+	material.DiffuseMap = e.image(s.Diffuse, images)
+	if material.DiffuseMap == "" {
+		if c, ok := color(s.Diffuse.Color); ok {
+			material.DiffuseColor = c
+		}
+	}
+	material.SpecularMap = e.image(s.Specular, images)
+	if c, ok := color(s.Specular.Color); ok {
+		material.SpecularColor = c
+	} else if material.SpecularMap != "" {
+		material.SpecularColor = mgl32.Vec3{1, 1, 1}
+	}
+	if v := floats(s.Shininess); len(v) == 1 {
+		material.Shininess = v[0]
+	}
+	material.EmissiveMap = e.image(s.Emission, images)
+	if c, ok := color(s.Emission.Color); ok {
+		material.EmissiveColor = c
+	} else if material.EmissiveMap != "" {
+		material.EmissiveColor = mgl32.Vec3{1, 1, 1}
+	}
+
+	material.Opacity = s.opacity()
+	material.TransparentMap = e.image(s.Transparent.xmlChannel, images)
+
+	material.NormalMap = e.image(e.Bump, images)
+	if material.NormalMap == "" {
+		material.NormalMap = e.image(e.EffectBump, images)
+	}
+}
+
+// opacity returns how opaque s is. In the default A_ONE mode the alpha of the transparent color is its
+// opacity, and in RGB_ZERO mode black is opaque, each scaled by its transparency.
+func (s xmlShader) opacity() float32 {
+	factor := float32(1)
+	if v := floats(s.Transparency); len(v) == 1 {
+		factor = v[0]
+	}
+	v := floats(s.Transparent.Color)
+	if len(v) < 4 {
+		return 1
+	}
+	var opacity float32
+	switch s.Transparent.Opaque {
+	case "RGB_ZERO":
+		luminance := v[0]*0.212671 + v[1]*0.715160 + v[2]*0.072169
+		opacity = 1 - luminance*factor
+	case "A_ZERO":
+		opacity = 1 - v[3]*factor
+	case "RGB_ONE":
+		luminance := v[0]*0.212671 + v[1]*0.715160 + v[2]*0.072169
+		opacity = luminance * factor
+	default:
+		opacity = v[3] * factor
+	}
+	return mgl32.Clamp(opacity, 0, 1)
+}
+
+// image returns the path of the image c is textured with, or an empty string if it isn't textured.
+// Textures name a sampler parameter of e, which names a surface parameter holding the image, though
+// some exporters name the image directly.
+func (e xmlEffect) image(c xmlChannel, images map[string]string) string {
+	ref := c.Texture.Texture
+	if ref == "" {
+		return ""
+	}
+	for _, p := range e.Params {
+		if p.SID == ref && p.Source != "" {
+			ref = p.Source
+			break
+		}
+	}
+	for _, p := range e.Params {
+		if p.SID == ref && p.Surface != "" {
+			ref = p.Surface
+			break
+		}
+	}
+	return images[ref]
+}
+
+// isTriangles reports whether p is a group of polygons, rather than lines or other data of a mesh.
+func (p xmlPrimitive) isTriangles() bool {
+	switch p.XMLName.Local {
+	case "triangles", "polylist", "polygons", "trifans", "tristrips":
+		return true
+	}
+	return false
+}
+
+// triangles returns the number of triangles p becomes once its polygons are triangulated.
+func (p xmlPrimitive) triangles() int {
+	if p.XMLName.Local != "polylist" || p.VCount == "" {
+		return p.Count
+	}
+	n := 0
+	for _, v := range floats(p.VCount) {
+		if v >= 3 {
+			n += int(v) - 2
+		}
+	}
+	return n
+}
+
+// imagePath returns the file path of an image reference, which may be a URL.
+func imagePath(ref string) string {
+	ref = strings.TrimSpace(ref)
+	ref = strings.TrimPrefix(ref, "file://")
+	if p, err := url.PathUnescape(ref); err == nil {
+		ref = p
+	}
+	return ref
+}
+
+// color parses a Collada color, dropping its alpha.
+func color(s string) (mgl32.Vec3, bool) {
+	v := floats(s)
+	if len(v) < 3 {
+		return mgl32.Vec3{}, false
+	}
+	return mgl32.Vec3{v[0], v[1], v[2]}, true
+}
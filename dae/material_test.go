@@ -0,0 +1,134 @@
+package dae
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// instancedScene places a hull geometry with two groups of triangles three times, twice with the same
+// materials and once with its paint bound to a different material.
+const instancedScene = `<COLLADA>
+  <library_materials>
+    <material id="paint-material" name="paint"/>
+    <material id="metal-material" name="metal"/>
+    <material id="rust-material" name="rust"/>
+  </library_materials>
+  <library_geometries>
+    <geometry id="hull">
+      <mesh>
+        <triangles material="paint-symbol" count="10"/>
+        <polylist material="metal-symbol" count="2"><vcount>4 5</vcount></polylist>
+      </mesh>
+    </geometry>
+  </library_geometries>
+  <library_visual_scenes>
+    <visual_scene>
+      <node name="left">
+        <instance_geometry url="#hull">
+          <bind_material><technique_common>
+            <instance_material symbol="paint-symbol" target="#paint-material"/>
+            <instance_material symbol="metal-symbol" target="#metal-material"/>
+          </technique_common></bind_material>
+        </instance_geometry>
+      </node>
+      <node name="right">
+        <instance_geometry url="#hull">
+          <bind_material><technique_common>
+            <instance_material symbol="paint-symbol" target="#paint-material"/>
+            <instance_material symbol="metal-symbol" target="#metal-material"/>
+          </technique_common></bind_material>
+        </instance_geometry>
+      </node>
+      <node name="wreck">
+        <instance_geometry url="#hull">
+          <bind_material><technique_common>
+            <instance_material symbol="paint-symbol" target="#rust-material"/>
+            <instance_material symbol="metal-symbol" target="#metal-material"/>
+          </technique_common></bind_material>
+        </instance_geometry>
+      </node>
+    </visual_scene>
+  </library_visual_scenes>
+</COLLADA>`
+
+func TestMeshMaterials(t *testing.T) {
+	var f File
+	if err := xml.NewDecoder(strings.NewReader(instancedScene)).Decode(&f.doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MeshMaterial{
+		{Material: "paint", Triangles: 10},
+		{Material: "metal", Triangles: 5},
+		{Material: "rust", Triangles: 10},
+	}
+	if got := f.MeshMaterials(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// effectScene is a scene with a lambert1 material whose transparent and transparency elements are
+// filled in, as the FBX exporter writes them.
+const effectScene = `<COLLADA xmlns="http://www.collada.org/2005/11/COLLADASchema" version="1.4.1">
+  <library_images>
+    <image id="file1-image"><init_from>file:///sourceimages/grate.png</init_from></image>
+  </library_images>
+  <library_materials>
+    <material id="lambert1" name="lambert1"><instance_effect url="#lambert1-fx"/></material>
+  </library_materials>
+  <library_effects>
+    <effect id="lambert1-fx">
+      <profile_COMMON><technique sid="standard"><lambert>
+        <diffuse><texture texture="file1-image" texcoord="CHANNEL0"/></diffuse>
+        %s
+      </lambert></technique></profile_COMMON>
+    </effect>
+  </library_effects>
+</COLLADA>`
+
+func TestMaterialsOpacity(t *testing.T) {
+	transparent := func(opaque, color, transparency string) string {
+		return fmt.Sprintf(`<transparent opaque="%s"><color sid="transparent">%s</color></transparent>
+			<transparency><float sid="transparency">%s</float></transparency>`, opaque, color, transparency)
+	}
+
+	tests := []struct {
+		name    string
+		shader  string
+		opacity float32
+		mapped  bool
+	}{
+		{"none", "", 1, false},
+		{"shipped", transparent("RGB_ZERO", "0.000000  0.000000 0.000000 1.000000", "1.000000"), 1, false},
+		{"RGB_ZERO white", transparent("RGB_ZERO", "1 1 1 1", "1"), 0, false},
+		{"RGB_ZERO scaled", transparent("RGB_ZERO", "1 1 1 1", "0.25"), 0.75, false},
+		{"RGB_ONE white", transparent("RGB_ONE", "1 1 1 1", "1"), 1, false},
+		{"RGB_ONE black", transparent("RGB_ONE", "0 0 0 1", "1"), 0, false},
+		{"A_ONE half", transparent("A_ONE", "0 0 0 0.5", "1"), 0.5, false},
+		{"A_ONE scaled", transparent("A_ONE", "1 1 1 1", "0.25"), 0.25, false},
+		{"A_ZERO half", transparent("A_ZERO", "1 1 1 0.5", "1"), 0.5, false},
+		{"A_ZERO opaque", transparent("A_ZERO", "1 1 1 0", "1"), 1, false},
+		{"default mode", `<transparent><color>1 1 1 0.5</color></transparent>`, 0.5, false},
+		{"no alpha", `<transparent opaque="A_ONE"><color>0 0 0</color></transparent>`, 1, false},
+		{"mapped", `<transparent opaque="A_ONE"><texture texture="file1-image" texcoord="CHANNEL0"/></transparent>`, 1, true},
+	}
+	for _, test := range tests {
+		var f File
+		if err := xml.NewDecoder(strings.NewReader(fmt.Sprintf(effectScene, test.shader))).Decode(&f.doc); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		m, ok := f.Materials()["lambert1"]
+		if !ok {
+			t.Fatalf("%s: no lambert1 material", test.name)
+		}
+		if d := m.Opacity - test.opacity; d < -1e-5 || d > 1e-5 {
+			t.Errorf("%s: got opacity %v, want %v", test.name, m.Opacity, test.opacity)
+		}
+		if mapped := m.TransparentMap != ""; mapped != test.mapped {
+			t.Errorf("%s: got transparent map %q", test.name, m.TransparentMap)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package draw
 
 import (
+	"fmt"
 	"log"
 	"math"
 	"sync"
@@ -26,25 +27,92 @@ func NewAnimator(bones []gombz.Bone, animations []gombz.Animation, mesh *Mesh) *
 		bones:      bones,
 		mesh:       mesh,
 		animations: animations,
-		startTime:  time.Now(),
-		channels:   make(map[int32]gombz.AnimationChannel, len(bones)),
 	}
 	log.Println(len(animations))
 
-	for _, bone := range bones {
-		for _, channel := range animations[a.currentAnimation].Channels {
+	a.setAnimation(0)
+
+	a.ticker = NewTicker(time.Millisecond*16, a.tick)
+
+	return a
+}
+
+// setAnimation switches a to the animation at index i, starting from its beginning. a.mut must be held,
+// except while a is being created.
+func (a *Animator) setAnimation(i int) {
+	a.currentAnimation = i
+	a.startTime = time.Now()
+	a.channels = make(map[int32]gombz.AnimationChannel, len(a.bones))
+	for _, bone := range a.bones {
+		for _, channel := range a.animations[i].Channels {
 			if channel.BoneId == bone.Id {
 				a.channels[bone.Id] = channel
 			}
 		}
 	}
+}
 
-	a.ticker = NewTicker(time.Millisecond*16, a.tick)
+// Animations returns the names of the animations a can play.
+func (a *Animator) Animations() []string {
+	names := make([]string, len(a.animations))
+	for i, anim := range a.animations {
+		names[i] = anim.Name
+	}
+	return names
+}
 
-	return a
+// BoneID returns the id of the bone of a's mesh called name, and whether it has one.
+func (a *Animator) BoneID(name string) (int32, bool) {
+	for _, bone := range a.bones {
+		if bone.Name == name {
+			return bone.Id, true
+		}
+	}
+	return 0, false
+}
+
+// Joint is where a bone of an animated mesh is in its current pose.
+type Joint struct {
+	Name string
+	// Parent is the index of the joint of the bone's parent, or -1 for a root bone.
+	Parent int
+	// Position is the location of the bone's origin in its mesh's model space.
+	Position mgl32.Vec3
+}
+
+// Joints returns the joints of the bones of a's mesh, indexed by bone id.
+func (a *Animator) Joints() []Joint {
+	joints := make([]Joint, len(a.bones))
+	for i, bone := range a.bones {
+		// The transform of a bone applies its offset to the bind pose first, which undoing leaves only
+		// the bone's own pose.
+		pose := a.mesh.BoneTransform(bone.Id).Mul4(bone.Offset.Inv())
+		joints[i] = Joint{
+			Name:     bone.Name,
+			Parent:   int(bone.Parent),
+			Position: pose.Col(3).Vec3(),
+		}
+	}
+	return joints
+}
+
+// Play switches a to the animation called name, starting from its beginning.
+func (a *Animator) Play(name string) error {
+	for i, anim := range a.animations {
+		if anim.Name == name {
+			a.mut.Lock()
+			a.setAnimation(i)
+			a.mut.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("play animation: no animation %q", name)
 }
 
 func (a *Animator) tick(elapsed float32) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
 	anim := a.animations[a.currentAnimation]
 	animTime := float64(time.Now().Sub(a.startTime)) / float64(time.Second)
 	duration := anim.Duration / 4
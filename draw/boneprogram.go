@@ -2,7 +2,6 @@ package draw
 
 import (
 	"fmt"
-	"io/ioutil"
 	"strings"
 	"sync"
 
@@ -22,24 +21,26 @@ type BoneProgram struct {
 	TextureLocID  uint32
 	VertexID      uint32
 	NormalID      uint32
+	TangentID     uint32
 	VertBonesID   uint32
 	VertWeightsID uint32
 
 	view        mgl32.Mat4
 	camPosition mgl32.Vec3
 	projection  mgl32.Mat4
+	materialIDs materialIDs
 	meshesMut   sync.Mutex
 	meshes      map[*Mesh]struct{}
 }
 
 func newBoneProgram(vertShaderPath, fragShaderPath string) *BoneProgram {
 
-	vertSource, err := ioutil.ReadFile(vertShaderPath)
+	vertSource, err := readShader(vertShaderPath)
 	if err != nil {
 		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
 	}
 
-	fragSource, err := ioutil.ReadFile(fragShaderPath)
+	fragSource, err := readShader(fragShaderPath)
 	if err != nil {
 
 		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
@@ -76,6 +77,9 @@ func newBoneProgram(vertShaderPath, fragShaderPath string) *BoneProgram {
 	gl.DeleteShader(vertexShader)
 	gl.DeleteShader(fragmentShader)
 
+	bindLights(id)
+	bindShadows(id)
+
 	p := &BoneProgram{
 		ID:            id,
 		ProjectionID:  gl.GetUniformLocation(id, gl.Str("projection\x00")),
@@ -88,15 +92,17 @@ func newBoneProgram(vertShaderPath, fragShaderPath string) *BoneProgram {
 		VertexID:      uint32(gl.GetAttribLocation(id, gl.Str("vert\x00"))),
 		TextureLocID:  uint32(gl.GetAttribLocation(id, gl.Str("vertTexCoord\x00"))),
 		NormalID:      uint32(gl.GetAttribLocation(id, gl.Str("vertNormal\x00"))),
+		TangentID:     uint32(gl.GetAttribLocation(id, gl.Str("vertTangent\x00"))),
 		VertBonesID:   uint32(gl.GetAttribLocation(id, gl.Str("vertBones\x00"))),
 		VertWeightsID: uint32(gl.GetAttribLocation(id, gl.Str("vertWeights\x00"))),
 
 		projection: mgl32.Ident4(),
 		view:       mgl32.Ident4(),
 		meshes:     make(map[*Mesh]struct{}),
+
+		materialIDs: newMaterialIDs(id),
 	}
 
-	gl.Uniform1i(p.TextureID, 0)
 	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
 
 	return p
@@ -117,35 +123,53 @@ func (p *BoneProgram) setProjection(projection mgl32.Mat4) {
 
 func (p *BoneProgram) Draw(state *GLState) {
 
+	p.begin(state)
+
+	p.meshesMut.Lock()
+	for mesh := range p.meshes {
+		if state.cull(mesh) || state.deferBlended(mesh) {
+			continue
+		}
+		p.drawMesh(mesh, state)
+	}
+	p.meshesMut.Unlock()
+}
+
+// begin makes p the current program, seen from its view.
+func (p *BoneProgram) begin(state *GLState) {
 	p.use()
 	gl.UniformMatrix4fv(p.CameraID, 1, false, &p.view[0])
 	gl.UniformMatrix4fv(p.ProjectionID, 1, false, &p.projection[0])
 	gl.Uniform3fv(p.CamPositionID, 1, &p.camPosition[0])
+}
 
-	p.meshesMut.Lock()
-	for mesh := range p.meshes {
-		mesh.bonesMut.Lock()
-		gl.UniformMatrix4fv(p.BonesID, int32(len(mesh.bones)), false, &mesh.bones[0][0])
-		mesh.bonesMut.Unlock()
+// drawMesh draws mesh in its current pose with p, which begin must have made the current program.
+func (p *BoneProgram) drawMesh(mesh *Mesh, state *GLState) {
+	mesh.bonesMut.Lock()
+	gl.UniformMatrix4fv(p.BonesID, int32(len(mesh.bones)), false, &mesh.bones[0][0])
+	mesh.bonesMut.Unlock()
 
-		mesh.Draw(state)
-	}
-	p.meshesMut.Unlock()
+	mesh.Draw(state)
 }
 
-func (p *BoneProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, vertBones []VertBone, boneWeights []mgl32.Vec4, bones []mgl32.Mat4) *Mesh {
+func (p *BoneProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, tangents []mgl32.Vec3, vertBones []VertBone, boneWeights []mgl32.Vec4, bones []mgl32.Mat4) *Mesh {
 
 	mesh := &Mesh{
 		count:    int32(len(faces) * 3),
 		program:  p,
 		rotation: mgl32.QuatIdent(),
+		material: NewMaterial(""),
 		bones:    bones,
 
 		// Save references to data so it won't get garbage-collected prematurely
 		vertexes: vertexes,
+		faces:    faces,
 		uvCoords: uvCoords,
 		normals:  normals,
+		tangents: meshTangents(vertexes, faces, uvCoords, normals, tangents),
+		bounds:   newBounds(vertexes),
 	}
+	mesh.radius = mesh.bounds.Radius() * boneBoundsPadding
 
 	gl.GenVertexArrays(1, &mesh.vao)
 	gl.BindVertexArray(mesh.vao)
@@ -183,6 +207,14 @@ func (p *BoneProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords
 	gl.EnableVertexAttribArray(p.NormalID)
 	gl.VertexAttribPointer(p.NormalID, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
 
+	gl.GenBuffers(1, &mesh.tangentVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.tangentVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(mesh.tangents)*4*4, gl.Ptr(mesh.tangents), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.TangentID)
+	gl.VertexAttribPointer(p.TangentID, 4, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
+
 	gl.GenBuffers(1, &mesh.boneIDsVBO)
 	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.boneIDsVBO)
 	// buffer type - length in bytes - data pointer - draw type
@@ -212,6 +244,28 @@ func (p *BoneProgram) RemoveMesh(d *Mesh) {
 	p.meshesMut.Unlock()
 }
 
+// drawDepth draws the meshes of p that are in view of pass into a shadow map, posed as they are drawn.
+func (p *BoneProgram) drawDepth(s *shadowMaps, pass depthPass) {
+	s.boned.use(pass)
+
+	p.meshesMut.Lock()
+	for mesh := range p.meshes {
+		if mesh.hidden || mesh.blended() || !pass.frustum.containsMesh(mesh) {
+			continue
+		}
+		mesh.bonesMut.Lock()
+		gl.UniformMatrix4fv(s.boned.bonesID, int32(len(mesh.bones)), false, &mesh.bones[0][0])
+		mesh.bonesMut.Unlock()
+
+		mesh.drawDepth(s.boned.modelID)
+	}
+	p.meshesMut.Unlock()
+}
+
+func (p *BoneProgram) getMaterialIDs() materialIDs {
+	return p.materialIDs
+}
+
 func (p *BoneProgram) GetModelID() int32 {
 	return p.ModelID
 }
@@ -0,0 +1,426 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// debugVertexSize is the number of floats in a debug line vertex: its position and color.
+	debugVertexSize = 7
+	// debugCircleSegments is the number of lines each circle of a debug sphere is drawn with.
+	debugCircleSegments = 24
+	// debugArrowHead is the length of the head of a debug arrow, as a fraction of the arrow.
+	debugArrowHead = 0.2
+	// debugTextSize is the size of debug labels, in pixels per em.
+	debugTextSize = 14
+)
+
+// DebugDrawer draws debugging shapes every frame while a window's debug drawing is enabled.
+type DebugDrawer interface {
+	// DrawDebug draws the shapes of a frame with d.
+	DrawDebug(d *DebugDraw)
+}
+
+// debugLabel is text drawn centered on a point in the world.
+type debugLabel struct {
+	position mgl32.Vec3
+	text     string
+	color    mgl32.Vec4
+}
+
+// debugFrame is the shapes drawn in a frame.
+type debugFrame struct {
+	vertices []float32
+	labels   []debugLabel
+}
+
+// DebugDraw draws lines, shapes and labels in the world over a window's scene, in every viewport, for
+// finding out what is going wrong with it. They are drawn on top of everything but the overlay, without
+// lighting or post-processing.
+//
+// Shapes are drawn once, in the next frame, so they must be drawn again every frame to stay. Shapes
+// drawn from DrawDebug are drawn in the frame being drawn. Nothing is drawn, and shapes are dropped,
+// while d is disabled.
+//
+// All DebugDraw functions are safe to use concurrently.
+type DebugDraw struct {
+	mut     sync.Mutex
+	enabled bool
+	drawers []DebugDrawer
+	pending debugFrame
+
+	font    *Font
+	program debugProgram
+	labels  overlayProgram
+	batch   overlayBatch
+}
+
+// Debug returns the debug drawing of w, which is disabled until it is enabled or toggled.
+func (w *Window) Debug() *DebugDraw {
+	return w.debug
+}
+
+// Enabled returns whether d is drawing.
+func (d *DebugDraw) Enabled() bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.enabled
+}
+
+// SetEnabled sets whether d draws.
+func (d *DebugDraw) SetEnabled(enabled bool) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.enabled = enabled
+	d.pending = debugFrame{}
+}
+
+// Toggle enables d if it is disabled, or disables it if it is enabled.
+func (d *DebugDraw) Toggle() {
+	d.SetEnabled(!d.Enabled())
+}
+
+// AddDrawer calls dr to draw with d every frame while d is enabled.
+func (d *DebugDraw) AddDrawer(dr DebugDrawer) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.drawers = append(d.drawers, dr)
+}
+
+// RemoveDrawer stops calling dr.
+func (d *DebugDraw) RemoveDrawer(dr DebugDrawer) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	for i, other := range d.drawers {
+		if other == dr {
+			d.drawers = append(d.drawers[:i], d.drawers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Line draws a line from a to b.
+func (d *DebugDraw) Line(a, b mgl32.Vec3, color mgl32.Vec4) {
+	d.lines(color, a, b)
+}
+
+// Arrow draws an arrow from from along vector, with its head at from + vector.
+func (d *DebugDraw) Arrow(from, vector mgl32.Vec3, color mgl32.Vec4) {
+	length := vector.Len()
+	if length < 1e-6 {
+		return
+	}
+	dir := vector.Mul(1 / length)
+	side := perpendicular(dir)
+	up := dir.Cross(side)
+
+	tip := from.Add(vector)
+	head := length * debugArrowHead
+	base := tip.Sub(dir.Mul(head))
+	side, up = side.Mul(head/2), up.Mul(head/2)
+	d.lines(color,
+		from, tip,
+		tip, base.Add(side),
+		tip, base.Sub(side),
+		tip, base.Add(up),
+		tip, base.Sub(up),
+	)
+}
+
+// Cross draws three lines size long through point, along the axes of the world.
+func (d *DebugDraw) Cross(point mgl32.Vec3, size float32, color mgl32.Vec4) {
+	h := size / 2
+	d.lines(color,
+		point.Sub(mgl32.Vec3{h, 0, 0}), point.Add(mgl32.Vec3{h, 0, 0}),
+		point.Sub(mgl32.Vec3{0, h, 0}), point.Add(mgl32.Vec3{0, h, 0}),
+		point.Sub(mgl32.Vec3{0, 0, h}), point.Add(mgl32.Vec3{0, 0, h}),
+	)
+}
+
+// Box draws the edges of a box around center, reaching halfSize along each of its axes, turned by
+// rotation.
+func (d *DebugDraw) Box(center, halfSize mgl32.Vec3, rotation mgl32.Quat, color mgl32.Vec4) {
+	transform := mgl32.Translate3D(center.Elem()).Mul4(rotation.Normalize().Mat4())
+	d.Bounds(Bounds{Min: halfSize.Mul(-1), Max: halfSize}, transform, color)
+}
+
+// Bounds draws the edges of b, moved from model space into the world by transform.
+func (d *DebugDraw) Bounds(b Bounds, transform mgl32.Mat4, color mgl32.Vec4) {
+	var corners [8]mgl32.Vec3
+	for i := range corners {
+		c := b.Min
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<uint(axis)) != 0 {
+				c[axis] = b.Max[axis]
+			}
+		}
+		corners[i] = mgl32.TransformCoordinate(c, transform)
+	}
+	d.boxEdges(corners, color)
+}
+
+// Sphere draws a sphere at center with radius as three circles around its axes.
+func (d *DebugDraw) Sphere(center mgl32.Vec3, radius float32, color mgl32.Vec4) {
+	points := make([]mgl32.Vec3, 0, 3*debugCircleSegments*2)
+	for axis := 0; axis < 3; axis++ {
+		point := func(i int) mgl32.Vec3 {
+			angle := 2 * math.Pi * float64(i) / debugCircleSegments
+			var p mgl32.Vec3
+			p[(axis+1)%3] = radius * float32(math.Cos(angle))
+			p[(axis+2)%3] = radius * float32(math.Sin(angle))
+			return center.Add(p)
+		}
+		for i := 0; i < debugCircleSegments; i++ {
+			points = append(points, point(i), point(i+1))
+		}
+	}
+	d.lines(color, points...)
+}
+
+// Frustum draws the edges of the volume seen through the combined projection and view matrix m, which
+// must have a far plane and the standard minus one to one depth range.
+func (d *DebugDraw) Frustum(m mgl32.Mat4, color mgl32.Vec4) {
+	inverse := m.Inv()
+	var corners [8]mgl32.Vec3
+	for i := range corners {
+		var ndc mgl32.Vec3
+		for axis := 0; axis < 3; axis++ {
+			ndc[axis] = -1
+			if i&(1<<uint(axis)) != 0 {
+				ndc[axis] = 1
+			}
+		}
+		corners[i] = mgl32.TransformCoordinate(ndc, inverse)
+	}
+	d.boxEdges(corners, color)
+}
+
+// Text draws text centered on position.
+func (d *DebugDraw) Text(position mgl32.Vec3, text string, color mgl32.Vec4) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if !d.enabled {
+		return
+	}
+	d.pending.labels = append(d.pending.labels, debugLabel{position: position, text: text, color: color})
+}
+
+// boxEdges draws the twelve edges between corners, which are indexed by a bit for each axis set when
+// the corner is at the maximum of that axis.
+func (d *DebugDraw) boxEdges(corners [8]mgl32.Vec3, color mgl32.Vec4) {
+	points := make([]mgl32.Vec3, 0, 24)
+	for i := range corners {
+		for axis := uint(0); axis < 3; axis++ {
+			if i&(1<<axis) == 0 {
+				points = append(points, corners[i], corners[i|1<<axis])
+			}
+		}
+	}
+	d.lines(color, points...)
+}
+
+// lines draws a line between each pair of points.
+func (d *DebugDraw) lines(color mgl32.Vec4, points ...mgl32.Vec3) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if !d.enabled {
+		return
+	}
+	for _, p := range points {
+		d.pending.vertices = append(d.pending.vertices, p[0], p[1], p[2], color[0], color[1], color[2], color[3])
+	}
+}
+
+// frame calls the drawers of d and returns everything drawn since the last frame, or nil if d is
+// disabled.
+func (d *DebugDraw) frame() *debugFrame {
+	d.mut.Lock()
+	enabled := d.enabled
+	drawers := append([]DebugDrawer(nil), d.drawers...)
+	d.mut.Unlock()
+	if !enabled {
+		return nil
+	}
+
+	for _, dr := range drawers {
+		dr.DrawDebug(d)
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	f := d.pending
+	d.pending = debugFrame{}
+	return &f
+}
+
+// draw draws f in each of viewports of a window width by height pixels.
+func (d *DebugDraw) draw(f *debugFrame, viewports []*Viewport, width, height int) {
+	if f == nil {
+		return
+	}
+
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	d.program.upload(f.vertices)
+
+	d.batch.reset()
+	for _, v := range viewports {
+		x, y, w, h := v.pixels(width, height)
+		if w <= 0 || h <= 0 {
+			continue
+		}
+		view, _, proj := v.state()
+		m := proj.Matrix(float32(w)/float32(h), false).Mul4(view)
+
+		gl.Viewport(x, y, w, h)
+		gl.Enable(gl.SCISSOR_TEST)
+		gl.Scissor(x, y, w, h)
+		d.program.draw(m, int32(len(f.vertices)/debugVertexSize))
+
+		for _, l := range f.labels {
+			clip := m.Mul4x1(l.position.Vec4(1))
+			if clip.W() <= 0 {
+				continue
+			}
+			ndc := clip.Vec3().Mul(1 / clip.W())
+			if ndc.X() < -1 || ndc.X() > 1 || ndc.Y() < -1 || ndc.Y() > 1 {
+				continue
+			}
+			// Labels are placed in pixels from the top left of the window.
+			point := mgl32.Vec2{
+				float32(x) + (ndc.X()*0.5+0.5)*float32(w),
+				float32(height) - (float32(y) + (ndc.Y()*0.5+0.5)*float32(h)),
+			}
+			tw, th := d.font.Measure(l.text)
+			origin := point.Sub(mgl32.Vec2{tw / 2, th / 2})
+			origin = mgl32.Vec2{float32(math.Floor(float64(origin[0]))), float32(math.Floor(float64(origin[1])))}
+			d.batch.text(d.font, l.text, origin, l.color)
+		}
+	}
+
+	gl.Disable(gl.SCISSOR_TEST)
+	gl.Viewport(0, 0, int32(width), int32(height))
+	d.labels.draw(&d.batch, mgl32.Vec2{float32(width), float32(height)})
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// Attribute locations of the debug program.
+const (
+	debugPositionLocation = 0
+	debugColorLocation    = 1
+)
+
+type debugProgram struct {
+	id               uint32
+	vao              uint32
+	vbo              uint32
+	size             int
+	viewProjectionID int32
+}
+
+func newDebugDraw() *DebugDraw {
+	return &DebugDraw{
+		font:    DefaultFont(debugTextSize),
+		program: newDebugProgram("shaders/debug.vert", "shaders/debug.frag"),
+		labels:  newOverlayProgram("shaders/overlay.vert", "shaders/overlay.frag"),
+	}
+}
+
+func newDebugProgram(vertShaderPath, fragShaderPath string) debugProgram {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic("compile fragment shader: " + err.Error())
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+	gl.BindAttribLocation(id, debugPositionLocation, gl.Str("position\x00"))
+	gl.BindAttribLocation(id, debugColorLocation, gl.Str("color\x00"))
+	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	p := debugProgram{
+		id:               id,
+		viewProjectionID: gl.GetUniformLocation(id, gl.Str("viewProjection\x00")),
+	}
+
+	gl.GenVertexArrays(1, &p.vao)
+	gl.BindVertexArray(p.vao)
+	gl.GenBuffers(1, &p.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	stride := int32(debugVertexSize * 4)
+	gl.EnableVertexAttribArray(debugPositionLocation)
+	gl.VertexAttribPointer(debugPositionLocation, 3, gl.FLOAT, false, stride, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(debugColorLocation)
+	gl.VertexAttribPointer(debugColorLocation, 4, gl.FLOAT, false, stride, gl.PtrOffset(3*4))
+	gl.BindVertexArray(0)
+
+	return p
+}
+
+// upload replaces the lines p draws with vertices.
+func (p *debugProgram) upload(vertices []float32) {
+	if len(vertices) == 0 {
+		return
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	if len(vertices) > p.size {
+		p.size = cap(vertices)
+		gl.BufferData(gl.ARRAY_BUFFER, p.size*4, nil, gl.STREAM_DRAW)
+	}
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices))
+}
+
+// draw draws the first count vertices uploaded to p as lines, seen through the combined projection and
+// view matrix m.
+func (p *debugProgram) draw(m mgl32.Mat4, count int32) {
+	if count == 0 {
+		return
+	}
+	gl.UseProgram(p.id)
+	gl.UniformMatrix4fv(p.viewProjectionID, 1, false, &m[0])
+	gl.BindVertexArray(p.vao)
+	gl.DrawArrays(gl.LINES, 0, count)
+	gl.BindVertexArray(0)
+}
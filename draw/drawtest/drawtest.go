@@ -0,0 +1,172 @@
+// Package drawtest compares images drawn by package draw against golden PNGs, so changes to shaders
+// and the renderer can be tested.
+//
+// Goldens are rewritten from what's drawn by running tests with -drawtest.update. Drawing in tests
+// needs a headless window from Window, which is only available when built with the egl tag.
+package drawtest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("drawtest.update", false, "rewrite golden images with what's drawn")
+
+// Tolerance is how far a drawn image may stray from its golden, allowing for differences between
+// drivers and rasterizers.
+type Tolerance struct {
+	// Channel is the largest difference in any channel of a pixel for it to still match.
+	Channel uint8
+	// Pixels is the fraction of pixels, from 0 to 1, that may not match.
+	Pixels float64
+}
+
+// DefaultTolerance allows a little rounding in every pixel and a few pixels of different rasterization
+// along edges.
+var DefaultTolerance = Tolerance{Channel: 2, Pixels: 0.001}
+
+// Diff is the difference between two images.
+type Diff struct {
+	// Mismatched is the number of pixels that differ by more than the channel tolerance, out of Total.
+	Mismatched, Total int
+	// MaxDelta is the largest difference in any channel of any pixel.
+	MaxDelta uint8
+	// Image shows mismatched pixels in red over a faded copy of the expected image.
+	Image *image.RGBA
+}
+
+// Fraction returns the fraction of pixels that are mismatched.
+func (d Diff) Fraction() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return float64(d.Mismatched) / float64(d.Total)
+}
+
+// Compare compares got against want pixel by pixel, counting pixels that differ by more than channel
+// in any channel as mismatched. The images must be the same size.
+func Compare(got, want image.Image, channel uint8) (Diff, error) {
+	gb, wb := got.Bounds(), want.Bounds()
+	if gb.Dx() != wb.Dx() || gb.Dy() != wb.Dy() {
+		return Diff{}, fmt.Errorf("compare images: size %vx%v doesn't match %vx%v", gb.Dx(), gb.Dy(), wb.Dx(), wb.Dy())
+	}
+
+	d := Diff{Total: gb.Dx() * gb.Dy(), Image: image.NewRGBA(image.Rect(0, 0, gb.Dx(), gb.Dy()))}
+	for y := 0; y < gb.Dy(); y++ {
+		for x := 0; x < gb.Dx(); x++ {
+			g := color.NRGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.NRGBA)
+			w := color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+			delta := maxDelta(g, w)
+			if delta > d.MaxDelta {
+				d.MaxDelta = delta
+			}
+			if delta > channel {
+				d.Mismatched++
+				d.Image.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			d.Image.SetRGBA(x, y, color.RGBA{fade(w.R), fade(w.G), fade(w.B), 255})
+		}
+	}
+	return d, nil
+}
+
+// maxDelta returns the largest difference between any channel of a and b.
+func maxDelta(a, b color.NRGBA) uint8 {
+	var max uint8
+	for _, c := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		delta := c[0] - c[1]
+		if c[1] > c[0] {
+			delta = c[1] - c[0]
+		}
+		if delta > max {
+			max = delta
+		}
+	}
+	return max
+}
+
+// fade lightens c towards white, so mismatches stand out in a diff image.
+func fade(c uint8) uint8 {
+	return 191 + c/4
+}
+
+// LoadPNG loads the PNG image at path.
+func LoadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("load png %s: %v", path, err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("load png %s: %v", path, err)
+	}
+	return img, nil
+}
+
+// SavePNG saves img as a PNG image at path, creating its directory if needed.
+func SavePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("save png %s: %v", path, err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("save png %s: %v", path, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("save png %s: %v", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("save png %s: %v", path, err)
+	}
+	return nil
+}
+
+// AssertGolden fails t if got doesn't match the golden PNG at path within tol. When it doesn't, what was
+// drawn and the difference are saved beside the golden, as .got.png and .diff.png, to be looked at.
+//
+// With -drawtest.update, the golden is rewritten with got instead.
+func AssertGolden(t testing.TB, got image.Image, path string, tol Tolerance) {
+	t.Helper()
+
+	if *update {
+		if err := SavePNG(path, got); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Fatalf("no golden image %s; run with -drawtest.update to create it", path)
+	}
+	want, err := LoadPNG(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Compare(got, want, tol.Channel)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if d.Fraction() <= tol.Pixels {
+		return
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if err := SavePNG(base+".got.png", got); err != nil {
+		t.Log(err)
+	}
+	if err := SavePNG(base+".diff.png", d.Image); err != nil {
+		t.Log(err)
+	}
+	t.Errorf("%s: %v of %v pixels differ, up to %v per channel, more than %.2f%% allowed; see %s.got.png and %s.diff.png",
+		path, d.Mismatched, d.Total, d.MaxDelta, tol.Pixels*100, base, base)
+}
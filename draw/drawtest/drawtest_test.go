@@ -0,0 +1,74 @@
+package drawtest
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// solid returns a width by height image filled with c.
+func solid(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestCompare(t *testing.T) {
+	gray := color.RGBA{100, 100, 100, 255}
+	want := solid(4, 4, gray)
+
+	got := solid(4, 4, gray)
+	got.SetRGBA(0, 0, color.RGBA{102, 99, 100, 255})
+	got.SetRGBA(3, 2, color.RGBA{100, 140, 100, 255})
+	got.SetRGBA(1, 3, color.RGBA{90, 100, 100, 255})
+
+	tests := []struct {
+		name       string
+		channel    uint8
+		mismatched int
+	}{
+		{"exact", 0, 3},
+		{"rounding", 2, 2},
+		{"loose", 10, 1},
+		{"everything", 40, 0},
+	}
+	for _, test := range tests {
+		d, err := Compare(got, want, test.channel)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if d.Mismatched != test.mismatched || d.Total != 16 {
+			t.Errorf("%s: got %v of %v pixels mismatched, want %v of 16", test.name, d.Mismatched, d.Total, test.mismatched)
+		}
+		if d.MaxDelta != 40 {
+			t.Errorf("%s: got max delta %v, want 40", test.name, d.MaxDelta)
+		}
+		if f := d.Fraction(); f != float64(test.mismatched)/16 {
+			t.Errorf("%s: got fraction %v, want %v", test.name, f, float64(test.mismatched)/16)
+		}
+		if mismatched := d.Image.RGBAAt(3, 2) == (color.RGBA{255, 0, 0, 255}); mismatched != (test.channel < 40) {
+			t.Errorf("%s: diff image shows (3, 2) as %v", test.name, d.Image.RGBAAt(3, 2))
+		}
+	}
+}
+
+func TestCompareOffsetBounds(t *testing.T) {
+	// Images are compared pixel for pixel from their own corners.
+	want := solid(4, 4, color.RGBA{10, 20, 30, 255})
+	got := solid(6, 6, color.RGBA{10, 20, 30, 255}).SubImage(image.Rect(2, 2, 6, 6))
+	d, err := Compare(got, want, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Mismatched != 0 {
+		t.Errorf("got %v pixels mismatched, want 0", d.Mismatched)
+	}
+}
+
+func TestCompareSize(t *testing.T) {
+	if _, err := Compare(solid(4, 4, color.RGBA{}), solid(4, 3, color.RGBA{}), 0); err == nil {
+		t.Error("compared images of different sizes without an error")
+	}
+}
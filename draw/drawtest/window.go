@@ -0,0 +1,61 @@
+//go:build egl
+// +build egl
+
+package drawtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lsmith130/space/draw"
+)
+
+// Window creates a headless window of width by height pixels for t to draw in, skipping t if no OpenGL
+// 4.1 context can be created, such as on machines without Mesa.
+//
+// The window's context is current on t's goroutine, which is locked to its thread until t finishes, so t
+// must draw and call RenderImage itself. The window is destroyed when t finishes. Shaders are loaded from the nearest directory above the working
+// directory that has them.
+func Window(t testing.TB, width, height int) *draw.Window {
+	t.Helper()
+
+	root, err := shaderRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := draw.ShaderRoot()
+	draw.SetShaderRoot(root)
+	t.Cleanup(func() { draw.SetShaderRoot(previous) })
+
+	runtime.LockOSThread()
+	w, err := draw.NewHeadlessWindow(width, height)
+	if err != nil {
+		runtime.UnlockOSThread()
+		t.Skip(err)
+	}
+	// Cleanups run last first, so the context is destroyed before the thread is unlocked.
+	t.Cleanup(runtime.UnlockOSThread)
+	t.Cleanup(w.Destroy)
+	return w
+}
+
+// shaderRoot returns the nearest directory above the working directory containing shaders.
+func shaderRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("find shaders: %v", err)
+	}
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "shaders")); err == nil && info.IsDir() {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("find shaders: no shaders directory above the working directory")
+		}
+		dir = parent
+	}
+}
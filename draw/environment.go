@@ -0,0 +1,484 @@
+package draw
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The texture units the maps of a window's environment are bound to while the PBR program draws.
+const (
+	irradianceUnit  = 8
+	reflectionUnit  = 9
+	brdfUnit        = 10
+	environmentSize = 128 // the size of the faces of the sharpest reflection map
+	// environmentLevels is the number of reflection maps, each half the size and rougher than the last.
+	environmentLevels  = 5
+	environmentSamples = 64
+	irradianceSize     = 16
+	brdfSize           = 32
+	brdfSamples        = 256
+)
+
+// Environment is the light surrounding a scene, from every direction, which the PBR program lights and
+// reflects on meshes. It is filtered once when created, into maps of the light reaching surfaces of
+// every direction and roughness.
+type Environment struct {
+	irradiance uint32
+	reflection uint32
+}
+
+// NewEnvironment creates a new environment from the six images at faces of a cube map, in the order
+// +x, -x, +y, -y, +z, -z, oriented as OpenGL cube maps are. Faces must be square, and are read as sRGB.
+//
+// Like textures, environments must be created on the thread running the window's loop.
+func NewEnvironment(faces [6]string) (*Environment, error) {
+	var cube cubeImage
+	for i, path := range faces {
+		img, err := loadImage(path)
+		if err != nil {
+			return nil, fmt.Errorf("load environment: %v", err)
+		}
+		if size := img.Bounds().Size(); size.X != size.Y {
+			return nil, fmt.Errorf("load environment: face %s is %dx%d, not square", path, size.X, size.Y)
+		}
+		cube[i] = resampleFace(img, environmentSize)
+	}
+	return newEnvironment(cube), nil
+}
+
+// newEnvironment filters cube into a new environment.
+func newEnvironment(cube cubeImage) *Environment {
+	mips := []cubeImage{cube}
+	for mips[len(mips)-1].size() > 1 {
+		mips = append(mips, mips[len(mips)-1].downsample())
+	}
+
+	levels := make([]cubeImage, environmentLevels)
+	levels[0] = cube
+	for level := 1; level < environmentLevels; level++ {
+		roughness := float32(level) / (environmentLevels - 1)
+		levels[level] = prefilter(mips, environmentSize>>uint(level), roughness)
+	}
+
+	e := &Environment{
+		irradiance: newCubeTexture([]cubeImage{irradiance(mips, irradianceSize)}),
+		reflection: newCubeTexture(levels),
+	}
+	return e
+}
+
+// Environment returns the environment the PBR program lights w's meshes with, or nil if it has none.
+func (w *Window) Environment() *Environment {
+	w.lightsMut.Lock()
+	defer w.lightsMut.Unlock()
+	return w.environment
+}
+
+// SetEnvironment sets the environment the PBR program lights w's meshes with. With no environment, they
+// are lit by w's ambient light from every direction.
+func (w *Window) SetEnvironment(e *Environment) {
+	w.lightsMut.Lock()
+	w.environment = e
+	w.lightsMut.Unlock()
+}
+
+// cubeFace is a square face of a cube map, holding linear colors row by row from the top.
+type cubeFace struct {
+	size int
+	pix  []mgl32.Vec3
+}
+
+// at returns the color at x, y of f, clamped to its edges.
+func (f cubeFace) at(x, y int) mgl32.Vec3 {
+	x = clampInt(x, 0, f.size-1)
+	y = clampInt(y, 0, f.size-1)
+	return f.pix[y*f.size+x]
+}
+
+// cubeImage is the faces of a cube map in the order +x, -x, +y, -y, +z, -z.
+type cubeImage [6]cubeFace
+
+func (c cubeImage) size() int {
+	return c[0].size
+}
+
+// downsample returns c at half the size, averaging each two by two square of texels.
+func (c cubeImage) downsample() cubeImage {
+	var out cubeImage
+	for i, f := range c {
+		size := f.size / 2
+		out[i] = cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				sum := f.at(2*x, 2*y).Add(f.at(2*x+1, 2*y)).Add(f.at(2*x, 2*y+1)).Add(f.at(2*x+1, 2*y+1))
+				out[i].pix[y*size+x] = sum.Mul(0.25)
+			}
+		}
+	}
+	return out
+}
+
+// sample returns the color of c in direction dir, filtered bilinearly within the face it falls on.
+func (c cubeImage) sample(dir mgl32.Vec3) mgl32.Vec3 {
+	face, s, t := cubeCoords(dir)
+	f := c[face]
+	x := s*float32(f.size) - 0.5
+	y := t*float32(f.size) - 0.5
+	x0, y0 := int(math.Floor(float64(x))), int(math.Floor(float64(y)))
+	fx, fy := x-float32(x0), y-float32(y0)
+
+	top := f.at(x0, y0).Mul(1 - fx).Add(f.at(x0+1, y0).Mul(fx))
+	bottom := f.at(x0, y0+1).Mul(1 - fx).Add(f.at(x0+1, y0+1).Mul(fx))
+	return top.Mul(1 - fy).Add(bottom.Mul(fy))
+}
+
+// cubeDirection returns the direction through the center of texel x, y of face of a cube map with faces
+// size texels across.
+func cubeDirection(face, x, y, size int) mgl32.Vec3 {
+	u := 2*(float32(x)+0.5)/float32(size) - 1
+	v := 2*(float32(y)+0.5)/float32(size) - 1
+	var dir mgl32.Vec3
+	switch face {
+	case 0:
+		dir = mgl32.Vec3{1, -v, -u}
+	case 1:
+		dir = mgl32.Vec3{-1, -v, u}
+	case 2:
+		dir = mgl32.Vec3{u, 1, v}
+	case 3:
+		dir = mgl32.Vec3{u, -1, -v}
+	case 4:
+		dir = mgl32.Vec3{u, -v, 1}
+	default:
+		dir = mgl32.Vec3{-u, -v, -1}
+	}
+	return dir.Normalize()
+}
+
+// cubeCoords returns the face of a cube map dir points at, and where on it from zero to one.
+func cubeCoords(dir mgl32.Vec3) (face int, s, t float32) {
+	ax, ay, az := abs32(dir[0]), abs32(dir[1]), abs32(dir[2])
+	var sc, tc, ma float32
+	switch {
+	case ax >= ay && ax >= az:
+		ma = ax
+		if dir[0] > 0 {
+			face, sc, tc = 0, -dir[2], -dir[1]
+		} else {
+			face, sc, tc = 1, dir[2], -dir[1]
+		}
+	case ay >= az:
+		ma = ay
+		if dir[1] > 0 {
+			face, sc, tc = 2, dir[0], dir[2]
+		} else {
+			face, sc, tc = 3, dir[0], -dir[2]
+		}
+	default:
+		ma = az
+		if dir[2] > 0 {
+			face, sc, tc = 4, dir[0], -dir[1]
+		} else {
+			face, sc, tc = 5, -dir[0], -dir[1]
+		}
+	}
+	return face, (sc/ma + 1) / 2, (tc/ma + 1) / 2
+}
+
+// texelSolidAngle returns the solid angle texel x, y covers on a face of a cube map size texels across.
+func texelSolidAngle(x, y, size int) float32 {
+	area := func(u, v float64) float64 {
+		return math.Atan2(u*v, math.Sqrt(u*u+v*v+1))
+	}
+	u0 := 2*float64(x)/float64(size) - 1
+	v0 := 2*float64(y)/float64(size) - 1
+	u1 := u0 + 2/float64(size)
+	v1 := v0 + 2/float64(size)
+	return float32(area(u0, v0) - area(u0, v1) - area(u1, v0) + area(u1, v1))
+}
+
+// irradiance returns the light reaching a white diffuse surface facing each direction of a cube map size
+// texels across, from the environment mips, which must hold a mip of that size or smaller. The result
+// is scaled by one over pi, so it only needs multiplying by the surface's color.
+func irradiance(mips []cubeImage, size int) cubeImage {
+	// The light on a diffuse surface changes so slowly with its direction that a small copy of the
+	// environment is as good as the whole thing.
+	src := mips[len(mips)-1]
+	for _, m := range mips {
+		if m.size() <= size {
+			src = m
+			break
+		}
+	}
+
+	type texel struct {
+		dir, radiance mgl32.Vec3
+	}
+	var texels []texel
+	for face, f := range src {
+		for y := 0; y < f.size; y++ {
+			for x := 0; x < f.size; x++ {
+				solidAngle := texelSolidAngle(x, y, f.size)
+				texels = append(texels, texel{cubeDirection(face, x, y, f.size), f.pix[y*f.size+x].Mul(solidAngle)})
+			}
+		}
+	}
+
+	var out cubeImage
+	for face := range out {
+		out[face] = cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				n := cubeDirection(face, x, y, size)
+				var sum mgl32.Vec3
+				for _, t := range texels {
+					if cos := n.Dot(t.dir); cos > 0 {
+						sum = sum.Add(t.radiance.Mul(cos))
+					}
+				}
+				out[face].pix[y*size+x] = sum.Mul(1 / math.Pi)
+			}
+		}
+	}
+	return out
+}
+
+// prefilter returns the environment mips reflected by a surface of roughness, in a cube map size texels
+// across. Surfaces are assumed to be viewed head on, as in Karis' split sum approximation, and each
+// sample is read from the mip that covers about as much of the environment as it stands for, which
+// hides the noise of using few samples.
+func prefilter(mips []cubeImage, size int, roughness float32) cubeImage {
+	texelSolid := 4 * math.Pi / (6 * float64(mips[0].size()*mips[0].size()))
+
+	var out cubeImage
+	for face := range out {
+		out[face] = cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				n := cubeDirection(face, x, y, size)
+				var sum mgl32.Vec3
+				var weight float32
+				for i := 0; i < environmentSamples; i++ {
+					h := importanceSampleGGX(hammersley(i, environmentSamples), n, roughness)
+					nDotH := n.Dot(h)
+					l := h.Mul(2 * nDotH).Sub(n)
+					nDotL := n.Dot(l)
+					if nDotL <= 0 {
+						continue
+					}
+
+					// With the view along the normal, the chance of sampling l is D / 4.
+					pdf := float64(distributionGGX(nDotH, roughness))/4 + 1e-4
+					sampleSolid := 1 / (environmentSamples * pdf)
+					mip := 0.5*math.Log2(sampleSolid/texelSolid) + 1
+					level := clampInt(int(math.Round(mip)), 0, len(mips)-1)
+
+					sum = sum.Add(mips[level].sample(l).Mul(nDotL))
+					weight += nDotL
+				}
+				if weight > 0 {
+					sum = sum.Mul(1 / weight)
+				}
+				out[face].pix[y*size+x] = sum
+			}
+		}
+	}
+	return out
+}
+
+// brdfTable returns the scale and bias to the Fresnel reflectance of a surface that give the share of
+// light it reflects, for each view angle cosine along x and roughness along y of a size by size table.
+func brdfTable(size int) []mgl32.Vec2 {
+	table := make([]mgl32.Vec2, size*size)
+	n := mgl32.Vec3{0, 0, 1}
+	for y := 0; y < size; y++ {
+		roughness := (float32(y) + 0.5) / float32(size)
+		for x := 0; x < size; x++ {
+			nDotV := (float32(x) + 0.5) / float32(size)
+			v := mgl32.Vec3{float32(math.Sqrt(float64(1 - nDotV*nDotV))), 0, nDotV}
+
+			var scale, bias float32
+			for i := 0; i < brdfSamples; i++ {
+				h := importanceSampleGGX(hammersley(i, brdfSamples), n, roughness)
+				vDotH := v.Dot(h)
+				l := h.Mul(2 * vDotH).Sub(v)
+				nDotL, nDotH := l[2], h[2]
+				if nDotL <= 0 {
+					continue
+				}
+
+				g := geometrySmith(nDotV, nDotL, roughness)
+				visibility := g * vDotH / (nDotH * nDotV)
+				fresnel := float32(math.Pow(float64(1-vDotH), 5))
+				scale += (1 - fresnel) * visibility
+				bias += fresnel * visibility
+			}
+			table[y*size+x] = mgl32.Vec2{scale / brdfSamples, bias / brdfSamples}
+		}
+	}
+	return table
+}
+
+// hammersley returns the ith of n points spread evenly over the unit square.
+func hammersley(i, n int) mgl32.Vec2 {
+	bits := uint32(i)
+	bits = (bits << 16) | (bits >> 16)
+	bits = ((bits & 0x55555555) << 1) | ((bits & 0xAAAAAAAA) >> 1)
+	bits = ((bits & 0x33333333) << 2) | ((bits & 0xCCCCCCCC) >> 2)
+	bits = ((bits & 0x0F0F0F0F) << 4) | ((bits & 0xF0F0F0F0) >> 4)
+	bits = ((bits & 0x00FF00FF) << 8) | ((bits & 0xFF00FF00) >> 8)
+	return mgl32.Vec2{float32(i) / float32(n), float32(bits) * 2.3283064365386963e-10}
+}
+
+// importanceSampleGGX returns the halfway vector around normal n that point xi of the unit square maps
+// to, spread as the microfacets of a surface of roughness are.
+func importanceSampleGGX(xi mgl32.Vec2, n mgl32.Vec3, roughness float32) mgl32.Vec3 {
+	a := float64(roughness * roughness)
+	phi := 2 * math.Pi * float64(xi[0])
+	cosTheta := math.Sqrt((1 - float64(xi[1])) / (1 + (a*a-1)*float64(xi[1])))
+	sinTheta := math.Sqrt(1 - cosTheta*cosTheta)
+	h := mgl32.Vec3{
+		float32(math.Cos(phi) * sinTheta),
+		float32(math.Sin(phi) * sinTheta),
+		float32(cosTheta),
+	}
+
+	up := mgl32.Vec3{0, 0, 1}
+	if abs32(n[2]) > 0.999 {
+		up = mgl32.Vec3{1, 0, 0}
+	}
+	tangent := up.Cross(n).Normalize()
+	bitangent := n.Cross(tangent)
+	return tangent.Mul(h[0]).Add(bitangent.Mul(h[1])).Add(n.Mul(h[2])).Normalize()
+}
+
+// distributionGGX returns the density of microfacets of a surface of roughness facing halfway between
+// the light and view, nDotH being the cosine between that and the normal.
+func distributionGGX(nDotH, roughness float32) float32 {
+	a2 := float64(roughness * roughness * roughness * roughness)
+	d := float64(nDotH*nDotH)*(a2-1) + 1
+	return float32(a2 / (math.Pi * d * d))
+}
+
+// geometrySmith returns the share of microfacets of a surface of roughness that neither the view nor the
+// light is blocked from, as used for image based lighting.
+func geometrySmith(nDotV, nDotL, roughness float32) float32 {
+	k := roughness * roughness / 2
+	return nDotV / (nDotV*(1-k) + k) * nDotL / (nDotL*(1-k) + k)
+}
+
+// loadImage decodes the image file at path.
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %v", path, err)
+	}
+	return img, nil
+}
+
+// resampleFace returns the square image img as a cube map face size texels across, in linear color.
+// Each texel averages the pixels of img it covers.
+func resampleFace(img image.Image, size int) cubeFace {
+	f := cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	for y := 0; y < size; y++ {
+		// Texels smaller than a pixel still cover the pixel they are in.
+		y0, y1 := y*h/size, (y+1)*h/size
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < size; x++ {
+			x0, x1 := x*w/size, (x+1)*w/size
+			if x1 == x0 {
+				x1++
+			}
+			var sum mgl32.Vec3
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					sum = sum.Add(linearColor(img.At(b.Min.X+px, b.Min.Y+py)))
+				}
+			}
+			f.pix[y*size+x] = sum.Mul(1 / float32((x1-x0)*(y1-y0)))
+		}
+	}
+	return f
+}
+
+// linearColor returns the sRGB color c in linear color.
+func linearColor(c color.Color) mgl32.Vec3 {
+	r, g, b, _ := c.RGBA()
+	return mgl32.Vec3{srgbToLinear(float32(r) / 0xffff), srgbToLinear(float32(g) / 0xffff), srgbToLinear(float32(b) / 0xffff)}
+}
+
+// srgbToLinear decodes a channel of an sRGB color.
+func srgbToLinear(c float32) float32 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return float32(math.Pow((float64(c)+0.055)/1.055, 2.4))
+}
+
+func abs32(f float32) float32 {
+	return float32(math.Abs(float64(f)))
+}
+
+func clampInt(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+// newCubeTexture uploads levels as the mip levels of a new cube map texture.
+func newCubeTexture(levels []cubeImage) uint32 {
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, tex)
+	for level, c := range levels {
+		for face, f := range c {
+			gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(face), int32(level), gl.RGB16F,
+				int32(f.size), int32(f.size), 0, gl.RGB, gl.FLOAT, gl.Ptr(f.pix))
+		}
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAX_LEVEL, int32(len(levels)-1))
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, 0)
+	return tex
+}
+
+// newBRDFTexture uploads the table of brdfTable as a new texture.
+func newBRDFTexture() uint32 {
+	table := brdfTable(brdfSize)
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RG16F, brdfSize, brdfSize, 0, gl.RG, gl.FLOAT, gl.Ptr(table))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return tex
+}
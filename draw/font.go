@@ -0,0 +1,330 @@
+package draw
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+const (
+	// glyphPadding is the space left around each glyph in a font's atlas, so glyphs don't bleed into
+	// each other when sampled.
+	glyphPadding = 1
+	// minAtlasSize and maxAtlasSize bound the width and height of a font's atlas.
+	minAtlasSize = 256
+	maxAtlasSize = 4096
+)
+
+// fontRunes are the characters rasterized into a font's atlas: printable ASCII and Latin-1. Others are
+// drawn as fallbackRune.
+var fontRunes = func() []rune {
+	var runes []rune
+	for r := rune(32); r < 127; r++ {
+		runes = append(runes, r)
+	}
+	for r := rune(160); r < 256; r++ {
+		runes = append(runes, r)
+	}
+	return runes
+}()
+
+const fallbackRune = '?'
+
+// glyph is a character rasterized into a font's atlas.
+type glyph struct {
+	index sfnt.GlyphIndex
+	// min and max are the corners of the glyph relative to its origin on the baseline, in pixels with y
+	// increasing down, and uvMin and uvMax its corners in the atlas.
+	min, max     mgl32.Vec2
+	uvMin, uvMax mgl32.Vec2
+	advance      float32
+}
+
+// Font is a TrueType or OpenType font at one size, with its characters rasterized into an atlas texture
+// for drawing text on a window's overlay.
+//
+// All Font functions are safe to use concurrently.
+type Font struct {
+	size   float32
+	ascent float32
+	height float32
+	glyphs map[rune]glyph
+
+	mut  sync.Mutex
+	sfnt *sfnt.Font
+	buf  sfnt.Buffer
+
+	// The atlas is uploaded the first time the font is drawn, from the window's loop.
+	atlas     *image.Alpha
+	textureID uint32
+}
+
+// NewFont creates a font from the TrueType or OpenType data ttf at size pixels per em.
+func NewFont(ttf []byte, size float32) (*Font, error) {
+	f, err := sfnt.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("parse font: %v", err)
+	}
+	ft := &Font{
+		size:   size,
+		sfnt:   f,
+		glyphs: make(map[rune]glyph, len(fontRunes)),
+	}
+	ppem := fixed.Int26_6(size * 64)
+
+	metrics, err := f.Metrics(&ft.buf, ppem, font.HintingNone)
+	if err != nil {
+		return nil, fmt.Errorf("read font metrics: %v", err)
+	}
+	ft.ascent = float32(metrics.Ascent) / 64
+	ft.height = float32(metrics.Height) / 64
+
+	masks := make(map[rune]*image.Alpha, len(fontRunes))
+	for _, r := range fontRunes {
+		g, mask, err := ft.rasterize(r, ppem)
+		if err != nil {
+			continue
+		}
+		ft.glyphs[r] = g
+		if mask != nil {
+			masks[r] = mask
+		}
+	}
+	if _, ok := ft.glyphs[fallbackRune]; !ok {
+		return nil, fmt.Errorf("create font: no glyph for %q", fallbackRune)
+	}
+
+	for size := minAtlasSize; size <= maxAtlasSize; size *= 2 {
+		if ft.pack(masks, size) {
+			return ft, nil
+		}
+	}
+	return nil, fmt.Errorf("create font: glyphs at size %v don't fit in an atlas", size)
+}
+
+// LoadFont loads the TrueType or OpenType font file at path at size pixels per em.
+func LoadFont(path string, size float32) (*Font, error) {
+	ttf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load font %s: %v", path, err)
+	}
+	f, err := NewFont(ttf, size)
+	if err != nil {
+		return nil, fmt.Errorf("load font %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// DefaultFont creates the Go Regular font at size pixels per em, which needs no font file.
+func DefaultFont(size float32) *Font {
+	f, err := NewFont(goregular.TTF, size)
+	if err != nil {
+		panic(fmt.Sprintf("create default font: %v", err))
+	}
+	return f
+}
+
+// rasterize returns the glyph of r at ppem and its coverage mask, which is nil for glyphs with no
+// outline such as spaces.
+func (f *Font) rasterize(r rune, ppem fixed.Int26_6) (glyph, *image.Alpha, error) {
+	index, err := f.sfnt.GlyphIndex(&f.buf, r)
+	if err != nil || index == 0 {
+		return glyph{}, nil, fmt.Errorf("no glyph for %q", r)
+	}
+	advance, err := f.sfnt.GlyphAdvance(&f.buf, index, ppem, font.HintingNone)
+	if err != nil {
+		return glyph{}, nil, err
+	}
+	segments, err := f.sfnt.LoadGlyph(&f.buf, index, ppem, nil)
+	if err != nil {
+		return glyph{}, nil, err
+	}
+	g := glyph{index: index, advance: float32(advance) / 64}
+	if len(segments) == 0 {
+		return g, nil, nil
+	}
+
+	// The control points of a glyph's outline bound it.
+	min := fixed.Point26_6{X: math.MaxInt32, Y: math.MaxInt32}
+	max := fixed.Point26_6{X: math.MinInt32, Y: math.MinInt32}
+	for _, s := range segments {
+		for _, p := range s.Args {
+			if p.X < min.X {
+				min.X = p.X
+			}
+			if p.Y < min.Y {
+				min.Y = p.Y
+			}
+			if p.X > max.X {
+				max.X = p.X
+			}
+			if p.Y > max.Y {
+				max.Y = p.Y
+			}
+		}
+	}
+	// Snap the glyph's box to whole pixels, so its mask is drawn texel for pixel.
+	g.min = mgl32.Vec2{float32(min.X.Floor()), float32(min.Y.Floor())}
+	g.max = mgl32.Vec2{float32(max.X.Ceil()), float32(max.Y.Ceil())}
+	width, height := int(g.max[0]-g.min[0]), int(g.max[1]-g.min[1])
+	if width <= 0 || height <= 0 {
+		return g, nil, nil
+	}
+
+	z := vector.NewRasterizer(width, height)
+	point := func(p fixed.Point26_6) (float32, float32) {
+		return float32(p.X)/64 - g.min[0], float32(p.Y)/64 - g.min[1]
+	}
+	for _, s := range segments {
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			z.MoveTo(point(s.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			z.LineTo(point(s.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			bx, by := point(s.Args[0])
+			cx, cy := point(s.Args[1])
+			z.QuadTo(bx, by, cx, cy)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := point(s.Args[0])
+			cx, cy := point(s.Args[1])
+			dx, dy := point(s.Args[2])
+			z.CubeTo(bx, by, cx, cy, dx, dy)
+		}
+	}
+	z.ClosePath()
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	return g, mask, nil
+}
+
+// pack places masks in rows across an atlas size pixels square, filling in the atlas coordinates of
+// f's glyphs. It reports false if they don't fit.
+func (f *Font) pack(masks map[rune]*image.Alpha, size int) bool {
+	atlas := image.NewAlpha(image.Rect(0, 0, size, size))
+	x, y, rowHeight := glyphPadding, glyphPadding, 0
+	placed := make(map[rune]image.Point, len(masks))
+	for _, r := range fontRunes {
+		mask, ok := masks[r]
+		if !ok {
+			continue
+		}
+		b := mask.Bounds()
+		if x+b.Dx()+glyphPadding > size {
+			x, y = glyphPadding, y+rowHeight+glyphPadding
+			rowHeight = 0
+		}
+		if y+b.Dy()+glyphPadding > size || b.Dx()+2*glyphPadding > size {
+			return false
+		}
+		for row := 0; row < b.Dy(); row++ {
+			copy(atlas.Pix[(y+row)*atlas.Stride+x:], mask.Pix[row*mask.Stride:row*mask.Stride+b.Dx()])
+		}
+		placed[r] = image.Point{x, y}
+		x += b.Dx() + glyphPadding
+		if b.Dy() > rowHeight {
+			rowHeight = b.Dy()
+		}
+	}
+
+	for r, p := range placed {
+		g := f.glyphs[r]
+		b := masks[r].Bounds()
+		g.uvMin = mgl32.Vec2{float32(p.X) / float32(size), float32(p.Y) / float32(size)}
+		g.uvMax = mgl32.Vec2{float32(p.X+b.Dx()) / float32(size), float32(p.Y+b.Dy()) / float32(size)}
+		f.glyphs[r] = g
+	}
+	f.atlas = atlas
+	return true
+}
+
+// Size returns the size of f in pixels per em.
+func (f *Font) Size() float32 {
+	return f.size
+}
+
+// LineHeight returns the distance between lines of text in f, in pixels.
+func (f *Font) LineHeight() float32 {
+	return f.height
+}
+
+// glyph returns the glyph f draws r with.
+func (f *Font) glyph(r rune) glyph {
+	if g, ok := f.glyphs[r]; ok {
+		return g
+	}
+	return f.glyphs[fallbackRune]
+}
+
+// kern returns the adjustment to the space between a and b, in pixels.
+func (f *Font) kern(a, b glyph) float32 {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	k, err := f.sfnt.Kern(&f.buf, a.index, b.index, fixed.Int26_6(f.size*64), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float32(k) / 64
+}
+
+// Measure returns the width and height text takes up drawn in f, in pixels. Lines are broken at
+// newlines.
+func (f *Font) Measure(text string) (width, height float32) {
+	f.layout(text, func(g glyph, dot mgl32.Vec2) {
+		if end := dot[0] + g.advance; end > width {
+			width = end
+		}
+	})
+	return width, float32(strings.Count(text, "\n")+1) * f.height
+}
+
+// layout calls place with each glyph of text and where its origin is, in pixels from the top left of
+// the text.
+func (f *Font) layout(text string, place func(g glyph, dot mgl32.Vec2)) {
+	dot := mgl32.Vec2{0, f.ascent}
+	var prev *glyph
+	for _, r := range text {
+		if r == '\n' {
+			dot = mgl32.Vec2{0, dot[1] + f.height}
+			prev = nil
+			continue
+		}
+		g := f.glyph(r)
+		if prev != nil {
+			dot[0] += f.kern(*prev, g)
+		}
+		place(g, dot)
+		dot[0] += g.advance
+		prev = &g
+	}
+}
+
+// texture returns the atlas of f, uploading it the first time. It must be called from the window's
+// loop.
+func (f *Font) texture() uint32 {
+	if f.textureID != 0 {
+		return f.textureID
+	}
+	size := f.atlas.Bounds().Dx()
+	gl.GenTextures(1, &f.textureID)
+	gl.BindTexture(gl.TEXTURE_2D, f.textureID)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R8, int32(size), int32(size), 0, gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(f.atlas.Pix))
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	return f.textureID
+}
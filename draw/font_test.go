@@ -0,0 +1,160 @@
+package draw
+
+import (
+	"image"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFontAtlas(t *testing.T) {
+	for _, size := range []float32{12, 48, 160} {
+		f, err := NewFont(goregular.TTF, size)
+		if err != nil {
+			t.Fatalf("size %v: %v", size, err)
+		}
+		atlasSize := f.atlas.Bounds().Dx()
+
+		// Each glyph with an outline has its own part of the atlas, holding its mask.
+		var rects []image.Rectangle
+		var runes []rune
+		for _, r := range fontRunes {
+			g, ok := f.glyphs[r]
+			if !ok || g.uvMax == g.uvMin {
+				continue
+			}
+			rect := image.Rect(
+				int(g.uvMin[0]*float32(atlasSize)+0.5), int(g.uvMin[1]*float32(atlasSize)+0.5),
+				int(g.uvMax[0]*float32(atlasSize)+0.5), int(g.uvMax[1]*float32(atlasSize)+0.5),
+			)
+			if rect.Dx() != int(g.max[0]-g.min[0]) || rect.Dy() != int(g.max[1]-g.min[1]) {
+				t.Errorf("size %v: %q takes %v of the atlas, want %vx%v", size, r, rect, g.max[0]-g.min[0], g.max[1]-g.min[1])
+			}
+			if !rect.In(f.atlas.Bounds()) {
+				t.Errorf("size %v: %q at %v is outside the %vx%v atlas", size, r, rect, atlasSize, atlasSize)
+			}
+			for i, other := range rects {
+				if rect.Overlaps(other) {
+					t.Errorf("size %v: %q at %v overlaps %q at %v", size, r, rect, runes[i], other)
+				}
+			}
+			rects = append(rects, rect)
+			runes = append(runes, r)
+		}
+		if len(rects) < len(fontRunes)/2 {
+			t.Errorf("size %v: only %v glyphs are in the atlas", size, len(rects))
+		}
+
+		g := f.glyphs['A']
+		x, y := int(g.uvMin[0]*float32(atlasSize)+0.5), int(g.uvMin[1]*float32(atlasSize)+0.5)
+		covered := false
+		for row := y; row < y+int(g.max[1]-g.min[1]); row++ {
+			for col := x; col < x+int(g.max[0]-g.min[0]); col++ {
+				covered = covered || f.atlas.AlphaAt(col, row).A > 0
+			}
+		}
+		if !covered {
+			t.Errorf("size %v: 'A' is blank in the atlas", size)
+		}
+	}
+}
+
+func TestFontAtlasGrows(t *testing.T) {
+	small, err := NewFont(goregular.TTF, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := NewFont(goregular.TTF, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := small.atlas.Bounds().Dx(); size != minAtlasSize {
+		t.Errorf("got a %v pixel atlas at size 12, want %v", size, minAtlasSize)
+	}
+	if size := large.atlas.Bounds().Dx(); size <= minAtlasSize || size > maxAtlasSize {
+		t.Errorf("got a %v pixel atlas at size 160, want more than %v and at most %v", size, minAtlasSize, maxAtlasSize)
+	}
+}
+
+func TestFontFallback(t *testing.T) {
+	f, err := NewFont(goregular.TTF, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback := f.glyphs[fallbackRune]
+	for _, r := range []rune{'€', '✓', '中'} {
+		if g := f.glyph(r); g != fallback {
+			t.Errorf("%q is drawn as glyph %v, want %q's glyph %v", r, g.index, fallbackRune, fallback.index)
+		}
+	}
+	if g := f.glyph('é'); g == fallback {
+		t.Errorf("'é' is drawn as %q", fallbackRune)
+	}
+
+	w, _ := f.Measure("€")
+	want, _ := f.Measure("?")
+	if w != want {
+		t.Errorf("got width %v for \"€\", want the width of \"?\", %v", w, want)
+	}
+}
+
+func TestFontLayout(t *testing.T) {
+	f, err := NewFont(goregular.TTF, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Go Regular has no kerning table, which leaves glyphs spaced by their advances alone.
+	a, v := f.glyph('A'), f.glyph('V')
+	kern := f.kern(a, v)
+	if kern != 0 {
+		t.Errorf("got kerning %v between 'A' and 'V', want 0", kern)
+	}
+
+	var dots []mgl32.Vec2
+	f.layout("AV\nA", func(g glyph, dot mgl32.Vec2) {
+		dots = append(dots, dot)
+	})
+	want := []mgl32.Vec2{
+		{0, f.ascent},
+		{a.advance + kern, f.ascent},
+		{0, f.ascent + f.height},
+	}
+	if len(dots) != len(want) {
+		t.Fatalf("got %v glyphs placed, want %v", len(dots), len(want))
+	}
+	for i := range want {
+		if !dots[i].ApproxEqual(want[i]) {
+			t.Errorf("glyph %v placed at %v, want %v", i, dots[i], want[i])
+		}
+	}
+}
+
+func TestFontMeasure(t *testing.T) {
+	f, err := NewFont(goregular.TTF, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	widest, _ := f.Measure("wide line")
+
+	tests := []struct {
+		text          string
+		width, height float32
+	}{
+		{"", 0, f.LineHeight()},
+		{"wide line", widest, f.LineHeight()},
+		{"wide line\nshort", widest, 2 * f.LineHeight()},
+		{"short\nwide line", widest, 2 * f.LineHeight()},
+		{"wide line\n", widest, 2 * f.LineHeight()},
+		{"\n\nwide line", widest, 3 * f.LineHeight()},
+	}
+	for _, test := range tests {
+		width, height := f.Measure(test.text)
+		if width != test.width || height != test.height {
+			t.Errorf("%q: got %vx%v, want %vx%v", test.text, width, height, test.width, test.height)
+		}
+	}
+	if short, _ := f.Measure("short"); short >= widest {
+		t.Errorf("got width %v for \"short\", want less than %v", short, widest)
+	}
+}
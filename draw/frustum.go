@@ -0,0 +1,96 @@
+package draw
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// boneBoundsPadding scales the bounds of animated meshes, which are measured in their bind pose but can
+// reach further once posed.
+const boneBoundsPadding = 1.5
+
+// Bounds is an axis aligned box around a mesh, in model space.
+type Bounds struct {
+	Min, Max mgl32.Vec3
+}
+
+// newBounds returns the bounds of vertexes.
+func newBounds(vertexes []mgl32.Vec3) Bounds {
+	if len(vertexes) == 0 {
+		return Bounds{}
+	}
+	b := Bounds{Min: vertexes[0], Max: vertexes[0]}
+	for _, v := range vertexes[1:] {
+		for i := 0; i < 3; i++ {
+			b.Min[i] = float32(math.Min(float64(b.Min[i]), float64(v[i])))
+			b.Max[i] = float32(math.Max(float64(b.Max[i]), float64(v[i])))
+		}
+	}
+	return b
+}
+
+// Center returns the center of b.
+func (b Bounds) Center() mgl32.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Radius returns the radius of the smallest sphere around b's center that contains b.
+func (b Bounds) Radius() float32 {
+	return b.Max.Sub(b.Min).Len() / 2
+}
+
+// frustum is the volume a camera can see, as planes facing into it. A point p is inside plane n when
+// n.Vec3().Dot(p) + n.W() >= 0.
+type frustum []mgl32.Vec4
+
+// newFrustum returns the frustum of the combined projection and view matrix m. reversed is whether m
+// uses a reversed-Z projection with a zero to one depth range.
+func newFrustum(m mgl32.Mat4, reversed bool) frustum {
+	r0, r1, r2, r3 := m.Row(0), m.Row(1), m.Row(2), m.Row(3)
+
+	planes := []mgl32.Vec4{
+		r3.Add(r0), r3.Sub(r0), // left, right
+		r3.Add(r1), r3.Sub(r1), // bottom, top
+	}
+	if reversed {
+		planes = append(planes, r3.Sub(r2), r2) // near, far
+	} else {
+		planes = append(planes, r3.Add(r2), r3.Sub(r2))
+	}
+
+	// Normalize the planes so sphere radii can be compared against their distances. An infinite far
+	// plane has no normal, and never culls anything.
+	f := planes[:0]
+	for _, p := range planes {
+		l := p.Vec3().Len()
+		if l < 1e-6 {
+			continue
+		}
+		f = append(f, p.Mul(1/l))
+	}
+	return frustum(f)
+}
+
+// intersectsSphere reports whether any part of the sphere at center with radius is inside f.
+func (f frustum) intersectsSphere(center mgl32.Vec3, radius float32) bool {
+	for _, p := range f {
+		if p.Vec3().Dot(center)+p.W() < -radius {
+			return false
+		}
+	}
+	return true
+}
+
+// containsMesh reports whether any part of m's bounds, placed at its location and rotation, is inside f.
+func (f frustum) containsMesh(m *Mesh) bool {
+	center := m.position.Add(m.rotation.Normalize().Rotate(m.bounds.Center()))
+	return f.intersectsSphere(center, m.radius)
+}
+
+// RenderStats counts the meshes drawn in a frame.
+type RenderStats struct {
+	// Drawn is the number of meshes drawn, and Culled is the number skipped for being out of view. A
+	// mesh drawn in more than one viewport is counted once for each.
+	Drawn, Culled int
+}
@@ -0,0 +1,164 @@
+//go:build egl
+// +build egl
+
+package draw
+
+/*
+#cgo LDFLAGS: -lEGL
+
+#include <string.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+#ifndef EGL_PLATFORM_SURFACELESS_MESA
+#define EGL_PLATFORM_SURFACELESS_MESA 0x31DD
+#endif
+#ifndef EGL_NO_CONFIG_KHR
+#define EGL_NO_CONFIG_KHR ((EGLConfig)0)
+#endif
+#ifndef EGL_CONTEXT_MAJOR_VERSION_KHR
+#define EGL_CONTEXT_MAJOR_VERSION_KHR 0x3098
+#endif
+#ifndef EGL_CONTEXT_MINOR_VERSION_KHR
+#define EGL_CONTEXT_MINOR_VERSION_KHR 0x30FB
+#endif
+#ifndef EGL_CONTEXT_OPENGL_PROFILE_MASK_KHR
+#define EGL_CONTEXT_OPENGL_PROFILE_MASK_KHR 0x30FD
+#endif
+#ifndef EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT_KHR
+#define EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT_KHR 0x00000001
+#endif
+
+typedef EGLDisplay (*getPlatformDisplayEXT)(EGLenum platform, void *nativeDisplay, const EGLint *attribs);
+
+// surfacelessDisplay returns a display that needs no window system, such as Mesa's surfaceless
+// platform, falling back to the default display.
+static EGLDisplay surfacelessDisplay() {
+	const char *extensions = eglQueryString(EGL_NO_DISPLAY, EGL_EXTENSIONS);
+	getPlatformDisplayEXT get = (getPlatformDisplayEXT)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (extensions != NULL && get != NULL && strstr(extensions, "EGL_MESA_platform_surfaceless") != NULL) {
+		EGLDisplay display = get(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+		if (display != EGL_NO_DISPLAY) {
+			return display;
+		}
+	}
+	return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+}
+
+// createContext creates an OpenGL 4.1 core context and makes it current with no surface, returning
+// EGL_SUCCESS or the error that stopped it. The display and context are stored in out.
+static EGLint createContext(EGLDisplay *outDisplay, EGLContext *outContext) {
+	EGLDisplay display = surfacelessDisplay();
+	if (display == EGL_NO_DISPLAY) {
+		return EGL_BAD_DISPLAY;
+	}
+	if (!eglInitialize(display, NULL, NULL) || !eglBindAPI(EGL_OPENGL_API)) {
+		return eglGetError();
+	}
+
+	const EGLint configAttribs[] = {
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+		EGL_NONE,
+	};
+	EGLConfig config = EGL_NO_CONFIG_KHR;
+	EGLint count = 0;
+	if (!eglChooseConfig(display, configAttribs, &config, 1, &count) || count == 0) {
+		config = EGL_NO_CONFIG_KHR;
+	}
+
+	const EGLint contextAttribs[] = {
+		EGL_CONTEXT_MAJOR_VERSION_KHR, 4,
+		EGL_CONTEXT_MINOR_VERSION_KHR, 1,
+		EGL_CONTEXT_OPENGL_PROFILE_MASK_KHR, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT_KHR,
+		EGL_NONE,
+	};
+	EGLContext context = eglCreateContext(display, config, EGL_NO_CONTEXT, contextAttribs);
+	if (context == EGL_NO_CONTEXT) {
+		EGLint err = eglGetError();
+		eglTerminate(display);
+		return err;
+	}
+	if (!eglMakeCurrent(display, EGL_NO_SURFACE, EGL_NO_SURFACE, context)) {
+		EGLint err = eglGetError();
+		eglDestroyContext(display, context);
+		eglTerminate(display);
+		return err;
+	}
+	*outDisplay = display;
+	*outContext = context;
+	return EGL_SUCCESS;
+}
+
+// destroyContext releases context from the calling thread, destroys it and terminates display.
+static void destroyContext(EGLDisplay display, EGLContext context) {
+	eglMakeCurrent(display, EGL_NO_SURFACE, EGL_NO_SURFACE, EGL_NO_CONTEXT);
+	eglDestroyContext(display, context);
+	eglTerminate(display);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// platform is the EGL display and context a headless Window draws with.
+type platform struct {
+	display C.EGLDisplay
+	context C.EGLContext
+}
+
+// NewHeadlessWindow creates a window that draws offscreen at width by height pixels, with an EGL context
+// that needs no display server, such as Mesa's surfaceless platform with its software rasterizer. Scenes
+// are set up as in any window, and each frame is drawn and read back with RenderImage instead of Loop.
+//
+// The context is current on the calling thread, which must be locked with runtime.LockOSThread and make
+// every call to RenderImage and Destroy. It's only available when built with the egl tag, which also has OpenGL
+// loaded through EGL, and takes the place of NewWindow so that GLFW and a window system aren't needed
+// to build.
+func NewHeadlessWindow(width, height int) (*Window, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("create headless window: invalid size %vx%v", width, height)
+	}
+
+	var display C.EGLDisplay
+	var context C.EGLContext
+	if err := C.createContext(&display, &context); err != C.EGL_SUCCESS {
+		return nil, fmt.Errorf("create headless window: create OpenGL 4.1 context: EGL error 0x%x", err)
+	}
+	if err := gl.Init(); err != nil {
+		C.destroyContext(display, context)
+		return nil, fmt.Errorf("create headless window: %v", err)
+	}
+
+	// Frames are presented to a renderbuffer rather than a window, to be read back from.
+	var fbo, color uint32
+	gl.GenRenderbuffers(1, &color)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, color)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.RGBA8, int32(width), int32(height))
+	gl.GenFramebuffers(1, &fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, color)
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		C.destroyContext(display, context)
+		return nil, fmt.Errorf("create headless window: framebuffer status 0x%x", status)
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	w := newWindow(fbo)
+	w.width, w.height = width, height
+	w.display, w.context = display, context
+	return w, nil
+}
+
+// Destroy destroys the EGL context of headless window w, along with everything created in it, and
+// releases it from the calling thread, which must be the one that created w. w should not be used after
+// it is destroyed.
+func (w *Window) Destroy() {
+	C.destroyContext(w.display, w.context)
+}
+
+// SetCursorCaptured does nothing, since headless windows have no cursor.
+func (w *Window) SetCursorCaptured(captured bool) {}
@@ -0,0 +1,318 @@
+package draw
+
+import (
+	"math"
+	"sync"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// MaxLights is the number of lights that can shine on a frame. Lights added to a window beyond
+	// MaxLights are ignored until others are removed.
+	MaxLights = 64
+
+	// lightsBinding is the uniform buffer binding point of the light block of every program.
+	lightsBinding = 0
+)
+
+// DefaultAmbient is the light that reaches every surface of a window's scene from no particular light.
+var DefaultAmbient = mgl32.Vec3{0.4, 0.4, 0.4}
+
+// LightType is the shape of the light a Light gives off.
+type LightType int32
+
+const (
+	// LightPoint shines in every direction from its location.
+	LightPoint LightType = iota
+	// LightSpot shines in a cone from its location.
+	LightSpot
+	// LightDirectional shines in one direction everywhere, like a distant sun.
+	LightDirectional
+)
+
+// Light lights the meshes of a window. Lights shine once added to a window with AddLight.
+//
+// All Light functions are safe to use concurrently.
+type Light struct {
+	mut        sync.Mutex
+	lightType  LightType
+	color      mgl32.Vec3
+	intensity  float32
+	lightRange float32
+	innerCone  float32
+	outerCone  float32
+	location   mgl32.Vec3
+	direction  mgl32.Vec3
+	shadows    bool
+}
+
+// NewPointLight creates a new point light of color and intensity that reaches rng units. A range of
+// zero reaches forever.
+func NewPointLight(color mgl32.Vec3, intensity, rng float32) *Light {
+	return &Light{
+		lightType:  LightPoint,
+		color:      color,
+		intensity:  intensity,
+		lightRange: rng,
+		direction:  mgl32.Vec3{0, 0, 1},
+	}
+}
+
+// NewSpotLight creates a new spot light of color and intensity that reaches rng units, shining along
+// +z until it is given a direction. It is brightest within inner radians of its direction and fades out
+// at outer radians.
+func NewSpotLight(color mgl32.Vec3, intensity, rng, inner, outer float32) *Light {
+	return &Light{
+		lightType:  LightSpot,
+		color:      color,
+		intensity:  intensity,
+		lightRange: rng,
+		innerCone:  inner,
+		outerCone:  outer,
+		direction:  mgl32.Vec3{0, 0, 1},
+	}
+}
+
+// NewDirectionalLight creates a new directional light of color and intensity shining in direction.
+func NewDirectionalLight(color mgl32.Vec3, intensity float32, direction mgl32.Vec3) *Light {
+	return &Light{
+		lightType: LightDirectional,
+		color:     color,
+		intensity: intensity,
+		direction: direction.Normalize(),
+	}
+}
+
+// Type returns the shape of the light l gives off.
+func (l *Light) Type() LightType {
+	return l.lightType
+}
+
+// Color returns the color of l.
+func (l *Light) Color() mgl32.Vec3 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.color
+}
+
+// SetColor sets the color of l.
+func (l *Light) SetColor(color mgl32.Vec3) {
+	l.mut.Lock()
+	l.color = color
+	l.mut.Unlock()
+}
+
+// Intensity returns the brightness l's color is scaled by.
+func (l *Light) Intensity() float32 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.intensity
+}
+
+// SetIntensity sets the brightness l's color is scaled by. Zero switches l off.
+func (l *Light) SetIntensity(intensity float32) {
+	l.mut.Lock()
+	l.intensity = intensity
+	l.mut.Unlock()
+}
+
+// Range returns the distance l reaches, or zero if it reaches forever.
+func (l *Light) Range() float32 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.lightRange
+}
+
+// SetRange sets the distance l reaches, fading out smoothly towards it. Zero reaches forever.
+// Directional lights always reach forever.
+func (l *Light) SetRange(rng float32) {
+	l.mut.Lock()
+	l.lightRange = rng
+	l.mut.Unlock()
+}
+
+// Cone returns the angles from its direction, in radians, that a spot light is brightest within and
+// fades out at.
+func (l *Light) Cone() (inner, outer float32) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.innerCone, l.outerCone
+}
+
+// SetCone sets the angles from its direction, in radians, that a spot light is brightest within and
+// fades out at.
+func (l *Light) SetCone(inner, outer float32) {
+	l.mut.Lock()
+	l.innerCone, l.outerCone = inner, outer
+	l.mut.Unlock()
+}
+
+// Location returns the location of l. It has no effect on directional lights.
+func (l *Light) Location() mgl32.Vec3 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.location
+}
+
+// SetLocation sets the location of l.
+func (l *Light) SetLocation(loc mgl32.Vec3) {
+	l.mut.Lock()
+	l.location = loc
+	l.mut.Unlock()
+}
+
+// Direction returns the direction l shines in. It has no effect on point lights.
+func (l *Light) Direction() mgl32.Vec3 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.direction
+}
+
+// SetDirection sets the direction l shines in.
+func (l *Light) SetDirection(direction mgl32.Vec3) {
+	l.mut.Lock()
+	l.direction = direction.Normalize()
+	l.mut.Unlock()
+}
+
+// CastsShadows returns whether l casts shadows.
+func (l *Light) CastsShadows() bool {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.shadows
+}
+
+// SetCastsShadows sets whether l casts shadows. Only the first directional light, and up to
+// MaxSpotShadows spot lights and MaxPointShadows point lights, added to a window cast shadows at once.
+func (l *Light) SetCastsShadows(shadows bool) {
+	l.mut.Lock()
+	l.shadows = shadows
+	l.mut.Unlock()
+}
+
+// lightData is a light laid out as the Light struct of the shaders, by std140 rules.
+type lightData struct {
+	// position holds the location, then the type.
+	position [4]float32
+	// direction holds the direction, then the range.
+	direction [4]float32
+	// color holds the color scaled by the intensity.
+	color [4]float32
+	// cone holds the cosines of the inner and outer cone angles, then the index of the light's shadow
+	// map, or -1 if it casts no shadows.
+	cone [4]float32
+}
+
+// lightBlock is the Lights uniform block of the shaders, by std140 rules.
+type lightBlock struct {
+	ambient [4]float32
+	count   int32
+	_       [3]int32
+	lights  [MaxLights]lightData
+}
+
+// data returns l laid out for the shaders, using the shadow map at shadow.
+func (l *Light) data(shadow int) lightData {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	color := l.color.Mul(l.intensity)
+	return lightData{
+		position:  [4]float32{l.location[0], l.location[1], l.location[2], float32(l.lightType)},
+		direction: [4]float32{l.direction[0], l.direction[1], l.direction[2], l.lightRange},
+		color:     [4]float32{color[0], color[1], color[2], 1},
+		cone: [4]float32{
+			float32(math.Cos(float64(l.innerCone))),
+			float32(math.Cos(float64(l.outerCone))),
+			float32(shadow),
+		},
+	}
+}
+
+// newLightBuffer creates the uniform buffer lights are uploaded to and binds it for every program.
+func newLightBuffer() uint32 {
+	var ubo uint32
+	gl.GenBuffers(1, &ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo)
+	gl.BufferData(gl.UNIFORM_BUFFER, int(unsafe.Sizeof(lightBlock{})), nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, lightsBinding, ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	return ubo
+}
+
+// bindLights connects the Lights uniform block of program, if it has one, to the light buffer.
+func bindLights(program uint32) {
+	index := gl.GetUniformBlockIndex(program, gl.Str("Lights\x00"))
+	if index != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(program, index, lightsBinding)
+	}
+}
+
+// AddLight adds l to the lights that shine on w. If l has already been added, AddLight has no effect.
+func (w *Window) AddLight(l *Light) {
+	w.lightsMut.Lock()
+	defer w.lightsMut.Unlock()
+	for _, existing := range w.lights {
+		if existing == l {
+			return
+		}
+	}
+	w.lights = append(w.lights, l)
+}
+
+// RemoveLight removes l from the lights that shine on w. If l hasn't been added, RemoveLight has no
+// effect.
+func (w *Window) RemoveLight(l *Light) {
+	w.lightsMut.Lock()
+	defer w.lightsMut.Unlock()
+	for i, existing := range w.lights {
+		if existing == l {
+			w.lights = append(w.lights[:i], w.lights[i+1:]...)
+			return
+		}
+	}
+}
+
+// Ambient returns the light that reaches every surface of w's scene.
+func (w *Window) Ambient() mgl32.Vec3 {
+	w.lightsMut.Lock()
+	defer w.lightsMut.Unlock()
+	return w.ambient
+}
+
+// SetAmbient sets the light that reaches every surface of w's scene.
+func (w *Window) SetAmbient(ambient mgl32.Vec3) {
+	w.lightsMut.Lock()
+	w.ambient = ambient
+	w.lightsMut.Unlock()
+}
+
+// frameLights returns the lights of w that shine this frame.
+func (w *Window) frameLights() []*Light {
+	w.lightsMut.Lock()
+	defer w.lightsMut.Unlock()
+	lights := w.lights
+	if len(lights) > MaxLights {
+		lights = lights[:MaxLights]
+	}
+	return append([]*Light(nil), lights...)
+}
+
+// uploadLights copies lights to the light buffer, for every program to use this frame.
+func (w *Window) uploadLights(lights []*Light) {
+	ambient := w.Ambient()
+	block := lightBlock{
+		ambient: [4]float32{ambient[0], ambient[1], ambient[2], 1},
+		count:   int32(len(lights)),
+	}
+	for i, l := range lights {
+		block.lights[i] = l.data(w.shadows.shadowIndex(l))
+	}
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, w.lightUBO)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, int(unsafe.Sizeof(block)), unsafe.Pointer(&block))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+}
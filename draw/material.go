@@ -0,0 +1,178 @@
+package draw
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The texture units the maps of a material are bound to while drawing.
+const (
+	diffuseMapUnit  = 0
+	specularMapUnit = 4
+	normalMapUnit   = 5
+	emissiveMapUnit = 6
+	// metallicRoughnessMapUnit is only used by the PBR program.
+	metallicRoughnessMapUnit = 7
+)
+
+// AlphaMode is how a material uses its alpha, which is its Opacity times the alpha of its diffuse map.
+type AlphaMode int
+
+const (
+	// AlphaOpaque ignores alpha and draws the surface solid.
+	AlphaOpaque AlphaMode = iota
+	// AlphaMask cuts the surface away where alpha is below the material's AlphaCutoff and draws the rest
+	// solid, for grates and decals. Shadow maps are drawn without materials, so masked meshes still cast
+	// shadows as if they were solid.
+	AlphaMask
+	// AlphaBlend blends the surface over what is behind it by alpha, for glass and visors. Blended meshes
+	// are drawn after solid ones, furthest from the camera first, and cast no shadows.
+	AlphaBlend
+)
+
+// Material is how the surface of a mesh looks. Each channel is its color, multiplied by its map where
+// the material has one. A material can be shared by many meshes, and changes to it show on all of them
+// from the next frame.
+type Material struct {
+	Name string
+
+	// DiffuseMap and DiffuseColor are the color of the surface.
+	DiffuseMap   *Texture
+	DiffuseColor mgl32.Vec3
+	// SpecularMap and SpecularColor are the color of the highlights lights make on the surface, and
+	// Shininess is how tight those highlights are. The alpha of SpecularMap is the gloss of the surface,
+	// which scales Shininess.
+	SpecularMap   *Texture
+	SpecularColor mgl32.Vec3
+	Shininess     float32
+	// NormalMap bends the normals of the surface, in the tangent space of the mesh's texture
+	// coordinates, with green pointing along v.
+	NormalMap *Texture
+	// EmissiveMap and EmissiveColor are the light the surface gives off itself, which shows even in the
+	// dark.
+	EmissiveMap   *Texture
+	EmissiveColor mgl32.Vec3
+
+	// Metallic and Roughness are how metallic and how rough the surface is, from zero to one, for the PBR
+	// program, which treats the diffuse channel as the base color of the surface and ignores the
+	// specular channel. MetallicRoughnessMap scales them by its blue and green channels, as in glTF.
+	Metallic             float32
+	Roughness            float32
+	MetallicRoughnessMap *Texture
+
+	// Alpha is how the surface uses its alpha, Opacity times the alpha of DiffuseMap, and AlphaCutoff
+	// the alpha below which masked surfaces are cut away.
+	Alpha       AlphaMode
+	Opacity     float32
+	AlphaCutoff float32
+}
+
+// NewMaterial creates a new plain, opaque white material called name, with the faint highlights meshes are
+// drawn with by default.
+func NewMaterial(name string) *Material {
+	return &Material{
+		Name:          name,
+		DiffuseColor:  mgl32.Vec3{1, 1, 1},
+		SpecularColor: mgl32.Vec3{0.4, 0.4, 0.4},
+		Shininess:     3,
+		Roughness:     ShininessRoughness(3),
+		Opacity:       1,
+		AlphaCutoff:   0.5,
+	}
+}
+
+// ShininessRoughness returns the roughness that gives highlights about as tight as shininess does, for
+// drawing materials made for the standard programs with the PBR program.
+func ShininessRoughness(shininess float32) float32 {
+	return float32(math.Sqrt(2 / (math.Max(float64(shininess), 0) + 2)))
+}
+
+// NewTextureMaterial creates a new material called name that shows texture, with the faint highlights
+// meshes are drawn with by default.
+func NewTextureMaterial(name string, texture *Texture) *Material {
+	m := NewMaterial(name)
+	m.DiffuseMap = texture
+	return m
+}
+
+// materialIDs are the locations of the material uniforms of a program.
+type materialIDs struct {
+	diffuseColor   int32
+	specularColor  int32
+	shininess      int32
+	emissiveColor  int32
+	hasDiffuseMap  int32
+	hasSpecularMap int32
+	hasNormalMap   int32
+	hasEmissiveMap int32
+
+	metallic                int32
+	roughness               int32
+	hasMetallicRoughnessMap int32
+
+	alphaMode   int32
+	opacity     int32
+	alphaCutoff int32
+}
+
+// newMaterialIDs looks up the material uniforms of program and connects its map samplers to their
+// texture units.
+func newMaterialIDs(program uint32) materialIDs {
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("tex\x00")), diffuseMapUnit)
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("specularMap\x00")), specularMapUnit)
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("normalMap\x00")), normalMapUnit)
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("emissiveMap\x00")), emissiveMapUnit)
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("metallicRoughnessMap\x00")), metallicRoughnessMapUnit)
+
+	return materialIDs{
+		diffuseColor:   gl.GetUniformLocation(program, gl.Str("diffuseColor\x00")),
+		specularColor:  gl.GetUniformLocation(program, gl.Str("specularColor\x00")),
+		shininess:      gl.GetUniformLocation(program, gl.Str("shininess\x00")),
+		emissiveColor:  gl.GetUniformLocation(program, gl.Str("emissiveColor\x00")),
+		hasDiffuseMap:  gl.GetUniformLocation(program, gl.Str("hasDiffuseMap\x00")),
+		hasSpecularMap: gl.GetUniformLocation(program, gl.Str("hasSpecularMap\x00")),
+		hasNormalMap:   gl.GetUniformLocation(program, gl.Str("hasNormalMap\x00")),
+		hasEmissiveMap: gl.GetUniformLocation(program, gl.Str("hasEmissiveMap\x00")),
+
+		metallic:                gl.GetUniformLocation(program, gl.Str("metallic\x00")),
+		roughness:               gl.GetUniformLocation(program, gl.Str("roughness\x00")),
+		hasMetallicRoughnessMap: gl.GetUniformLocation(program, gl.Str("hasMetallicRoughnessMap\x00")),
+
+		alphaMode:   gl.GetUniformLocation(program, gl.Str("alphaMode\x00")),
+		opacity:     gl.GetUniformLocation(program, gl.Str("opacity\x00")),
+		alphaCutoff: gl.GetUniformLocation(program, gl.Str("alphaCutoff\x00")),
+	}
+}
+
+// use sets the material uniforms ids of the current program to m, and binds m's maps.
+func (m *Material) use(ids materialIDs) {
+	gl.Uniform3fv(ids.diffuseColor, 1, &m.DiffuseColor[0])
+	gl.Uniform3fv(ids.specularColor, 1, &m.SpecularColor[0])
+	gl.Uniform1f(ids.shininess, m.Shininess)
+	gl.Uniform3fv(ids.emissiveColor, 1, &m.EmissiveColor[0])
+
+	useMap(ids.hasDiffuseMap, m.DiffuseMap, diffuseMapUnit)
+	useMap(ids.hasSpecularMap, m.SpecularMap, specularMapUnit)
+	useMap(ids.hasNormalMap, m.NormalMap, normalMapUnit)
+	useMap(ids.hasEmissiveMap, m.EmissiveMap, emissiveMapUnit)
+
+	gl.Uniform1f(ids.metallic, m.Metallic)
+	gl.Uniform1f(ids.roughness, m.Roughness)
+	useMap(ids.hasMetallicRoughnessMap, m.MetallicRoughnessMap, metallicRoughnessMapUnit)
+
+	gl.Uniform1i(ids.alphaMode, int32(m.Alpha))
+	gl.Uniform1f(ids.opacity, m.Opacity)
+	gl.Uniform1f(ids.alphaCutoff, m.AlphaCutoff)
+}
+
+// useMap binds t to unit if it isn't nil, and sets the uniform has to whether it is.
+func useMap(has int32, t *Texture, unit uint32) {
+	if t == nil {
+		gl.Uniform1i(has, 0)
+		return
+	}
+	gl.Uniform1i(has, 1)
+	t.Use(gl.TEXTURE0 + unit)
+}
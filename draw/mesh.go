@@ -13,10 +13,13 @@ type Mesh struct {
 	vertexes         []mgl32.Vec3
 	vertexVBO        uint32
 	indexBufferID    uint32
+	faces            []MeshFace
 	uvCoords         []mgl32.Vec2
 	uvVBO            uint32
 	normals          []mgl32.Vec3
 	normalVBO        uint32
+	tangents         []mgl32.Vec4
+	tangentVBO       uint32
 	boneIDs          []mgl32.Vec3
 	boneIDsVBO       uint32
 	bonesMut         sync.Mutex
@@ -27,7 +30,10 @@ type Mesh struct {
 	position         mgl32.Vec3
 	count            int32
 	program          Program
-	texture          *Texture
+	material         *Material
+	hidden           bool
+	bounds           Bounds
+	radius           float32
 	animations       []gombz.Animation
 	currentAnimation int
 	ticker           *Ticker
@@ -36,24 +42,92 @@ type Mesh struct {
 type MeshFace [3]uint32
 type VertBone [4]int32
 
+// SetTexture gives m a plain material that shows texture.
 func (m *Mesh) SetTexture(texture *Texture) {
-	m.texture = texture
+	m.material = NewTextureMaterial(m.material.Name, texture)
+}
+
+// Material returns the material m is drawn with.
+func (m *Mesh) Material() *Material {
+	return m.material
+}
+
+// SetMaterial sets the material m is drawn with.
+func (m *Mesh) SetMaterial(material *Material) {
+	m.material = material
+}
+
+// blended reports whether m's material blends it over what is behind it.
+func (m *Mesh) blended() bool {
+	return m.material.Alpha == AlphaBlend
+}
+
+// center returns the center of m's bounds where m is.
+func (m *Mesh) center() mgl32.Vec3 {
+	return m.position.Add(m.rotation.Normalize().Rotate(m.bounds.Center()))
+}
+
+// BoneTransform returns the transform the bone of m with id applies to the vertexes it moves, from
+// where they are in m's model to where its animation has put them. Meshes without the bone return the
+// identity.
+func (m *Mesh) BoneTransform(id int32) mgl32.Mat4 {
+	m.bonesMut.Lock()
+	defer m.bonesMut.Unlock()
+	if id < 0 || int(id) >= len(m.bones) {
+		return mgl32.Ident4()
+	}
+	return m.bones[id]
 }
 
 func (m *Mesh) Draw(state *GLState) {
+	if m.hidden {
+		return
+	}
 
 	transform := mgl32.Translate3D(m.position.Elem()).Mul4(m.rotation.Normalize().Mat4())
 
 	// Set Model Transform
 	gl.UniformMatrix4fv(m.program.GetModelID(), 1, false, &transform[0])
 
-	if m.texture != nil {
-		m.texture.Use(gl.TEXTURE0)
-	}
+	m.material.use(m.program.getMaterialIDs())
 	gl.BindVertexArray(m.vao)
 	gl.DrawElements(gl.TRIANGLES, m.count, gl.UNSIGNED_INT, nil)
 }
 
+// drawDepth draws m into a shadow map with the model matrix uniform modelID.
+func (m *Mesh) drawDepth(modelID int32) {
+	transform := mgl32.Translate3D(m.position.Elem()).Mul4(m.rotation.Normalize().Mat4())
+	gl.UniformMatrix4fv(modelID, 1, false, &transform[0])
+
+	gl.BindVertexArray(m.vao)
+	gl.DrawElements(gl.TRIANGLES, m.count, gl.UNSIGNED_INT, nil)
+}
+
+// Bounds returns the box around m's vertexes in model space.
+func (m *Mesh) Bounds() Bounds {
+	return m.bounds
+}
+
+// Vertexes returns the vertex positions of m in model space.
+func (m *Mesh) Vertexes() []mgl32.Vec3 {
+	return m.vertexes
+}
+
+// Faces returns the triangles of m as indexes into Vertexes.
+func (m *Mesh) Faces() []MeshFace {
+	return m.faces
+}
+
+// Hidden returns whether m is skipped when drawing.
+func (m *Mesh) Hidden() bool {
+	return m.hidden
+}
+
+// SetHidden sets whether m is skipped when drawing.
+func (m *Mesh) SetHidden(hidden bool) {
+	m.hidden = hidden
+}
+
 func (m *Mesh) SetLocation(loc mgl32.Vec3) {
 	m.position = loc
 }
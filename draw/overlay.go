@@ -0,0 +1,488 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Anchor is the point of the window an overlay element is placed from, which it keeps to as the window
+// is resized.
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// factor returns how far across and down the window a is, from 0 to 1. The same point of an element is
+// placed on it, so elements anchored to an edge lie inside the window.
+func (a Anchor) factor() mgl32.Vec2 {
+	return mgl32.Vec2{float32(a%3) / 2, float32(a/3) / 2}
+}
+
+// Element is something drawn on a window's overlay: a Text, Sprite or Bar.
+type Element interface {
+	// Place puts the element at offset pixels from anchor, with x to the right and y down.
+	Place(anchor Anchor, offset mgl32.Vec2)
+	// SetVisible shows or hides the element.
+	SetVisible(visible bool)
+
+	// draw adds the element to b for a window of screen pixels.
+	draw(b *overlayBatch, screen mgl32.Vec2)
+}
+
+// element is the placement shared by all elements, guarded by mut along with the rest of the element.
+type element struct {
+	mut    sync.Mutex
+	anchor Anchor
+	offset mgl32.Vec2
+	hidden bool
+}
+
+// Place puts the element at offset pixels from anchor, with x to the right and y down.
+func (e *element) Place(anchor Anchor, offset mgl32.Vec2) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	e.anchor, e.offset = anchor, offset
+}
+
+// SetVisible shows or hides the element.
+func (e *element) SetVisible(visible bool) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	e.hidden = !visible
+}
+
+// Visible returns whether the element is shown.
+func (e *element) Visible() bool {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return !e.hidden
+}
+
+// corner returns the top left of an element size pixels across on a window of screen pixels, snapped to
+// a whole pixel so textures are drawn sharp. e.mut must be held.
+func (e *element) corner(size, screen mgl32.Vec2) mgl32.Vec2 {
+	f := e.anchor.factor()
+	return mgl32.Vec2{
+		float32(math.Floor(float64((screen[0]-size[0])*f[0] + e.offset[0]))),
+		float32(math.Floor(float64((screen[1]-size[1])*f[1] + e.offset[1]))),
+	}
+}
+
+// Text is a line or lines of text on a window's overlay.
+//
+// All Text functions are safe to use concurrently.
+type Text struct {
+	element
+	font  *Font
+	text  string
+	color mgl32.Vec4
+}
+
+// NewText creates white text drawn in font, at the top left of the window.
+func NewText(font *Font, text string) *Text {
+	return &Text{font: font, text: text, color: mgl32.Vec4{1, 1, 1, 1}}
+}
+
+// Text returns the text t draws.
+func (t *Text) Text() string {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.text
+}
+
+// SetText sets the text t draws. Lines are broken at newlines.
+func (t *Text) SetText(text string) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.text = text
+}
+
+// SetColor sets the color and opacity of t.
+func (t *Text) SetColor(color mgl32.Vec4) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.color = color
+}
+
+func (t *Text) draw(b *overlayBatch, screen mgl32.Vec2) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.hidden || t.text == "" {
+		return
+	}
+
+	width, height := t.font.Measure(t.text)
+	b.text(t.font, t.text, t.corner(mgl32.Vec2{width, height}, screen), t.color)
+}
+
+// Sprite is an image on a window's overlay.
+//
+// All Sprite functions are safe to use concurrently.
+type Sprite struct {
+	element
+	texture *Texture
+	size    mgl32.Vec2
+	color   mgl32.Vec4
+}
+
+// NewSprite creates a sprite of texture drawn width by height pixels, at the top left of the window.
+func NewSprite(texture *Texture, width, height float32) *Sprite {
+	return &Sprite{texture: texture, size: mgl32.Vec2{width, height}, color: mgl32.Vec4{1, 1, 1, 1}}
+}
+
+// SetTexture sets the image s draws.
+func (s *Sprite) SetTexture(texture *Texture) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.texture = texture
+}
+
+// SetSize sets the size s is drawn in pixels.
+func (s *Sprite) SetSize(width, height float32) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.size = mgl32.Vec2{width, height}
+}
+
+// SetColor sets the color s's image is multiplied by, and its opacity.
+func (s *Sprite) SetColor(color mgl32.Vec4) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.color = color
+}
+
+func (s *Sprite) draw(b *overlayBatch, screen mgl32.Vec2) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.hidden || s.texture == nil {
+		return
+	}
+	min := s.corner(s.size, screen)
+	b.quad(overlaySprite, s.texture.ID, min, min.Add(s.size), mgl32.Vec2{0, 0}, mgl32.Vec2{1, 1}, s.color)
+}
+
+// Bar is a horizontal gauge on a window's overlay, filled from the left by its value.
+//
+// All Bar functions are safe to use concurrently.
+type Bar struct {
+	element
+	size       mgl32.Vec2
+	value      float32
+	color      mgl32.Vec4
+	background mgl32.Vec4
+}
+
+// NewBar creates a full white bar width by height pixels on a dark background, at the top left of the
+// window.
+func NewBar(width, height float32) *Bar {
+	return &Bar{
+		size:       mgl32.Vec2{width, height},
+		value:      1,
+		color:      mgl32.Vec4{1, 1, 1, 1},
+		background: mgl32.Vec4{0, 0, 0, .5},
+	}
+}
+
+// Value returns how full b is, from 0 to 1.
+func (b *Bar) Value() float32 {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.value
+}
+
+// SetValue sets how full b is, from 0 to 1.
+func (b *Bar) SetValue(value float32) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.value = mgl32.Clamp(value, 0, 1)
+}
+
+// SetSize sets the size of b in pixels.
+func (b *Bar) SetSize(width, height float32) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.size = mgl32.Vec2{width, height}
+}
+
+// SetColor sets the color of the filled part of b.
+func (b *Bar) SetColor(color mgl32.Vec4) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.color = color
+}
+
+// SetBackground sets the color of the empty part of b.
+func (b *Bar) SetBackground(color mgl32.Vec4) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.background = color
+}
+
+func (b *Bar) draw(batch *overlayBatch, screen mgl32.Vec2) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if b.hidden {
+		return
+	}
+	min := b.corner(b.size, screen)
+	batch.quad(overlaySolid, 0, min, min.Add(b.size), mgl32.Vec2{}, mgl32.Vec2{}, b.background)
+	if b.value > 0 {
+		fill := mgl32.Vec2{float32(math.Floor(float64(b.size[0]*b.value) + .5)), b.size[1]}
+		batch.quad(overlaySolid, 0, min, min.Add(fill), mgl32.Vec2{}, mgl32.Vec2{}, b.color)
+	}
+}
+
+// Overlay is drawn over everything else in a window, after post-processing, in pixels of the window.
+// Elements are drawn in the order they were added, later elements over earlier ones.
+//
+// All Overlay functions are safe to use concurrently.
+type Overlay struct {
+	mut      sync.Mutex
+	elements []Element
+
+	program overlayProgram
+	batch   overlayBatch
+}
+
+// Overlay returns the overlay of w.
+func (w *Window) Overlay() *Overlay {
+	return w.overlay
+}
+
+// Add draws e on o.
+func (o *Overlay) Add(e Element) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	o.elements = append(o.elements, e)
+}
+
+// Remove stops drawing e on o.
+func (o *Overlay) Remove(e Element) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	for i, el := range o.elements {
+		if el == e {
+			o.elements = append(o.elements[:i], o.elements[i+1:]...)
+			return
+		}
+	}
+}
+
+// draw draws the elements of o over the window, which is width by height pixels.
+func (o *Overlay) draw(width, height int) {
+	o.mut.Lock()
+	elements := append([]Element(nil), o.elements...)
+	o.mut.Unlock()
+
+	o.batch.reset()
+	screen := mgl32.Vec2{float32(width), float32(height)}
+	for _, e := range elements {
+		e.draw(&o.batch, screen)
+	}
+	o.program.draw(&o.batch, screen)
+}
+
+// Modes of drawing an overlay quad, matching shaders/overlay.frag.
+const (
+	overlaySolid int32 = iota
+	overlaySprite
+	overlayGlyph
+)
+
+// overlayVertexSize is the number of floats in an overlay vertex: its position, texture coordinates and
+// color.
+const overlayVertexSize = 8
+
+// overlayBatch collects the quads of a frame's overlay, to be drawn in as few draws as the textures
+// they use allow.
+type overlayBatch struct {
+	vertices []float32
+	draws    []overlayDraw
+}
+
+// overlayDraw is a run of vertices of an overlayBatch drawn in one mode and texture.
+type overlayDraw struct {
+	mode         int32
+	texture      uint32
+	first, count int32
+}
+
+func (b *overlayBatch) reset() {
+	b.vertices = b.vertices[:0]
+	b.draws = b.draws[:0]
+}
+
+// quad adds a rectangle from min to max pixels from the top left of the window, textured from uvMin to
+// uvMax.
+func (b *overlayBatch) quad(mode int32, texture uint32, min, max, uvMin, uvMax mgl32.Vec2, color mgl32.Vec4) {
+	first := int32(len(b.vertices) / overlayVertexSize)
+	corners := [6][2]int{{0, 0}, {1, 0}, {0, 1}, {0, 1}, {1, 0}, {1, 1}}
+	for _, c := range corners {
+		pos := mgl32.Vec2{min[0], min[1]}
+		uv := mgl32.Vec2{uvMin[0], uvMin[1]}
+		if c[0] == 1 {
+			pos[0], uv[0] = max[0], uvMax[0]
+		}
+		if c[1] == 1 {
+			pos[1], uv[1] = max[1], uvMax[1]
+		}
+		b.vertices = append(b.vertices, pos[0], pos[1], uv[0], uv[1], color[0], color[1], color[2], color[3])
+	}
+
+	if n := len(b.draws); n > 0 && b.draws[n-1].mode == mode && b.draws[n-1].texture == texture {
+		b.draws[n-1].count += 6
+		return
+	}
+	b.draws = append(b.draws, overlayDraw{mode: mode, texture: texture, first: first, count: 6})
+}
+
+// text adds the glyphs of text drawn in f, with its top left at origin.
+func (b *overlayBatch) text(f *Font, text string, origin mgl32.Vec2, color mgl32.Vec4) {
+	texture := f.texture()
+	f.layout(text, func(g glyph, dot mgl32.Vec2) {
+		if g.uvMin == g.uvMax {
+			return
+		}
+		// Glyph boxes are whole pixels, so glyphs placed on a whole pixel are drawn texel for pixel.
+		dot = origin.Add(mgl32.Vec2{float32(math.Floor(float64(dot[0]) + .5)), float32(math.Floor(float64(dot[1]) + .5))})
+		b.quad(overlayGlyph, texture, dot.Add(g.min), dot.Add(g.max), g.uvMin, g.uvMax, color)
+	})
+}
+
+// Attribute locations of the overlay program.
+const (
+	overlayPositionLocation = 0
+	overlayTexCoordLocation = 1
+	overlayColorLocation    = 2
+)
+
+type overlayProgram struct {
+	id       uint32
+	vao      uint32
+	vbo      uint32
+	size     int
+	screenID int32
+	modeID   int32
+}
+
+func newOverlay() *Overlay {
+	return &Overlay{program: newOverlayProgram("shaders/overlay.vert", "shaders/overlay.frag")}
+}
+
+func newOverlayProgram(vertShaderPath, fragShaderPath string) overlayProgram {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic("compile fragment shader: " + err.Error())
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+	gl.BindAttribLocation(id, overlayPositionLocation, gl.Str("position\x00"))
+	gl.BindAttribLocation(id, overlayTexCoordLocation, gl.Str("texCoord\x00"))
+	gl.BindAttribLocation(id, overlayColorLocation, gl.Str("color\x00"))
+	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	p := overlayProgram{
+		id:       id,
+		screenID: gl.GetUniformLocation(id, gl.Str("screen\x00")),
+		modeID:   gl.GetUniformLocation(id, gl.Str("mode\x00")),
+	}
+	gl.ProgramUniform1i(id, gl.GetUniformLocation(id, gl.Str("tex\x00")), 0)
+
+	gl.GenVertexArrays(1, &p.vao)
+	gl.BindVertexArray(p.vao)
+	gl.GenBuffers(1, &p.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	stride := int32(overlayVertexSize * 4)
+	gl.EnableVertexAttribArray(overlayPositionLocation)
+	gl.VertexAttribPointer(overlayPositionLocation, 2, gl.FLOAT, false, stride, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(overlayTexCoordLocation)
+	gl.VertexAttribPointer(overlayTexCoordLocation, 2, gl.FLOAT, false, stride, gl.PtrOffset(2*4))
+	gl.EnableVertexAttribArray(overlayColorLocation)
+	gl.VertexAttribPointer(overlayColorLocation, 4, gl.FLOAT, false, stride, gl.PtrOffset(4*4))
+	gl.BindVertexArray(0)
+
+	return p
+}
+
+// draw draws the quads of b over the current framebuffer, which is screen pixels.
+func (p *overlayProgram) draw(b *overlayBatch, screen mgl32.Vec2) {
+	if len(b.draws) == 0 {
+		return
+	}
+
+	gl.BindVertexArray(p.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	if len(b.vertices) > p.size {
+		p.size = cap(b.vertices)
+		gl.BufferData(gl.ARRAY_BUFFER, p.size*4, nil, gl.STREAM_DRAW)
+	}
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(b.vertices)*4, gl.Ptr(b.vertices))
+
+	gl.UseProgram(p.id)
+	gl.Uniform2fv(p.screenID, 1, &screen[0])
+
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.SCISSOR_TEST)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	for _, d := range b.draws {
+		gl.Uniform1i(p.modeID, d.mode)
+		gl.BindTexture(gl.TEXTURE_2D, d.texture)
+		gl.DrawArrays(gl.TRIANGLES, d.first, d.count)
+	}
+
+	gl.BindVertexArray(0)
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.DEPTH_TEST)
+}
@@ -0,0 +1,575 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// maxParticleKeys is the most color or size keys a ParticleEffect can have. Keys past it are ignored.
+	maxParticleKeys = 4
+	// minParticleCapacity is the fewest particles an emitter makes room for.
+	minParticleCapacity = 16
+	// particleFloats is the number of floats a particle takes in an emitter's buffer.
+	particleFloats = 8
+)
+
+// Particle attribute locations, fixed so emitters can set up their vertex arrays before the particle
+// program is linked.
+const (
+	particleSpawnLocation = iota
+	particleMotionLocation
+)
+
+// ParticleBlend is how particles are blended with what is drawn behind them.
+type ParticleBlend int
+
+const (
+	// ParticleAdditive adds particles' light to what is behind them, for fire, exhaust and sparks.
+	ParticleAdditive ParticleBlend = iota
+	// ParticleAlpha covers what is behind particles by their alpha, for smoke and dust.
+	ParticleAlpha
+)
+
+// ColorKey is the color of particles at Age, the fraction of their lifetime they have lived. Colors
+// between keys are interpolated.
+type ColorKey struct {
+	Age   float32
+	Color mgl32.Vec4
+}
+
+// SizeKey is the width of particles at Age, the fraction of their lifetime they have lived. Sizes
+// between keys are interpolated.
+type SizeKey struct {
+	Age  float32
+	Size float32
+}
+
+// ParticleEffect describes the particles an Emitter emits and how they look over their lives.
+type ParticleEffect struct {
+	// Rate is the number of particles emitted each second while the emitter is on, until the emitter's
+	// rate is changed.
+	Rate float32
+	// Lifetime is how long each particle lives.
+	Lifetime time.Duration
+	// Speed is the speed particles leave the emitter at, varied by up to SpeedSpread either way. The
+	// emitter's own velocity is added to it.
+	Speed, SpeedSpread float32
+	// Cone is the angle in radians from the emitter's direction particles can leave at. Pi emits in
+	// every direction.
+	Cone float32
+	// Radius spreads particles' starting points over a sphere around the emitter.
+	Radius float32
+	// Acceleration accelerates particles throughout their lives, such as by gravity.
+	Acceleration mgl32.Vec3
+	// Drag is the fraction of their velocity particles lose each second, which slows them without
+	// stopping them.
+	Drag float32
+	// Colors and Sizes are keys of particles' color and size over their lives, up to four each.
+	// Without keys, particles are white and one unit wide.
+	Colors []ColorKey
+	Sizes  []SizeKey
+	// Blend is how particles are blended with what is drawn behind them.
+	Blend ParticleBlend
+	// Texture is the image drawn on each particle, multiplied by its color. Without one, particles are
+	// soft round dots.
+	Texture *Texture
+	// MaxParticles is the most particles alive at once, after which the oldest are replaced. Zero makes
+	// room for the particles Rate emits over their lifetime.
+	MaxParticles int
+}
+
+// capacity returns the number of particles an emitter of e makes room for.
+func (e ParticleEffect) capacity() int {
+	n := e.MaxParticles
+	if n <= 0 {
+		// Leave room for frames emitting more than their share.
+		n = int(math.Ceil(float64(e.Rate)*e.Lifetime.Seconds()*1.25)) + 1
+	}
+	if n < minParticleCapacity {
+		n = minParticleCapacity
+	}
+	return n
+}
+
+// Emitter emits particles from a point, continuously while it is on or in bursts. Particles are
+// simulated on the GPU from where and when they were emitted, so emitting them is the only work done
+// for them on the CPU. An emitter must be added to a Window to be drawn.
+//
+// All Emitter functions are safe to use concurrently.
+type Emitter struct {
+	effect ParticleEffect
+
+	mut       sync.Mutex
+	location  mgl32.Vec3
+	direction mgl32.Vec3
+	velocity  mgl32.Vec3
+	on        bool
+	rate      float32
+	bursts    []burst
+
+	// The rest is only used by the window's loop.
+	rng          *rand.Rand
+	vao, vbo     uint32
+	capacity     int
+	next         int
+	owed         float32
+	lastLocation mgl32.Vec3
+	lastTime     float32
+	placed       bool
+	data         []float32
+}
+
+// burst is a number of particles to emit at once, from where the emitter was when they were asked for.
+type burst struct {
+	count     int
+	location  mgl32.Vec3
+	direction mgl32.Vec3
+	velocity  mgl32.Vec3
+}
+
+// NewEmitter creates an emitter of effect at the origin, pointing along +z. It is off until started.
+func NewEmitter(effect ParticleEffect) *Emitter {
+	if len(effect.Colors) > maxParticleKeys {
+		effect.Colors = effect.Colors[:maxParticleKeys]
+	}
+	if len(effect.Sizes) > maxParticleKeys {
+		effect.Sizes = effect.Sizes[:maxParticleKeys]
+	}
+	return &Emitter{
+		effect:    effect,
+		direction: mgl32.Vec3{0, 0, 1},
+		rate:      effect.Rate,
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		capacity:  effect.capacity(),
+	}
+}
+
+// Effect returns the effect e emits.
+func (e *Emitter) Effect() ParticleEffect {
+	return e.effect
+}
+
+// Start turns e on, emitting particles at its rate.
+func (e *Emitter) Start() {
+	e.SetOn(true)
+}
+
+// Stop turns e off. Particles already emitted live out their lifetimes.
+func (e *Emitter) Stop() {
+	e.SetOn(false)
+}
+
+// On returns whether e is emitting particles continuously.
+func (e *Emitter) On() bool {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.on
+}
+
+// SetOn turns e on or off.
+func (e *Emitter) SetOn(on bool) {
+	e.mut.Lock()
+	e.on = on
+	e.mut.Unlock()
+}
+
+// Rate returns the number of particles e emits each second while it is on.
+func (e *Emitter) Rate() float32 {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.rate
+}
+
+// SetRate sets the number of particles e emits each second while it is on, such as to follow a
+// throttle. Rates above its effect's only live as long as there is room for their particles.
+func (e *Emitter) SetRate(rate float32) {
+	e.mut.Lock()
+	e.rate = rate
+	e.mut.Unlock()
+}
+
+// Burst emits n particles at once from where e is now, whether or not it is on.
+func (e *Emitter) Burst(n int) {
+	e.mut.Lock()
+	e.bursts = append(e.bursts, burst{count: n, location: e.location, direction: e.direction, velocity: e.velocity})
+	e.mut.Unlock()
+}
+
+// Location returns the point e emits particles from.
+func (e *Emitter) Location() mgl32.Vec3 {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.location
+}
+
+// SetLocation moves e to loc. Particles emitted while it moves are spread along its path.
+func (e *Emitter) SetLocation(loc mgl32.Vec3) {
+	e.mut.Lock()
+	e.location = loc
+	e.mut.Unlock()
+}
+
+// Direction returns the direction e emits particles in.
+func (e *Emitter) Direction() mgl32.Vec3 {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.direction
+}
+
+// SetDirection points e in direction. A zero direction leaves e pointing where it was.
+func (e *Emitter) SetDirection(direction mgl32.Vec3) {
+	if direction.Len() < 1e-6 {
+		return
+	}
+	e.mut.Lock()
+	e.direction = direction.Normalize()
+	e.mut.Unlock()
+}
+
+// Velocity returns the velocity e's particles inherit.
+func (e *Emitter) Velocity() mgl32.Vec3 {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.velocity
+}
+
+// SetVelocity sets the velocity e's particles inherit, usually that of the body carrying it.
+func (e *Emitter) SetVelocity(velocity mgl32.Vec3) {
+	e.mut.Lock()
+	e.velocity = velocity
+	e.mut.Unlock()
+}
+
+// emit emits the particles due since the last frame, at time now in seconds on the window's clock, and
+// uploads them. It must be called from the window's loop.
+func (e *Emitter) emit(now float32) {
+	if e.vao == 0 {
+		e.createBuffers()
+	}
+
+	e.mut.Lock()
+	on, rate, loc, dir, vel := e.on, e.rate, e.location, e.direction, e.velocity
+	bursts := e.bursts
+	e.bursts = nil
+	e.mut.Unlock()
+
+	if !e.placed {
+		e.lastLocation, e.lastTime, e.placed = loc, now, true
+	}
+	elapsed := now - e.lastTime
+
+	e.data = e.data[:0]
+	for _, b := range bursts {
+		for i := 0; i < b.count; i++ {
+			e.spawn(b.location, b.direction, b.velocity, now)
+		}
+	}
+	if on {
+		e.owed += rate * elapsed
+		n := int(e.owed)
+		e.owed -= float32(n)
+		for i := 0; i < n; i++ {
+			// Spread particles over the frame, from where e was at each moment, so they leave a smooth
+			// trail rather than clumps.
+			f := (float32(i) + e.rng.Float32()) / float32(n)
+			at := e.lastLocation.Add(loc.Sub(e.lastLocation).Mul(f))
+			e.spawn(at, dir, vel, now-elapsed*(1-f))
+		}
+	} else {
+		e.owed = 0
+	}
+	e.lastLocation, e.lastTime = loc, now
+
+	e.upload()
+}
+
+// spawn adds a particle emitted at loc and time born to e.data.
+func (e *Emitter) spawn(loc, dir, vel mgl32.Vec3, born float32) {
+	fx := e.effect
+
+	// Pick a direction in the cone evenly over its solid angle.
+	cosCone := float32(math.Cos(float64(fx.Cone)))
+	cosTheta := 1 - e.rng.Float32()*(1-cosCone)
+	sinTheta := float32(math.Sqrt(float64(1 - cosTheta*cosTheta)))
+	phi := 2 * math.Pi * e.rng.Float64()
+	t := perpendicular(dir)
+	b := dir.Cross(t)
+	out := dir.Mul(cosTheta).
+		Add(t.Mul(sinTheta * float32(math.Cos(phi)))).
+		Add(b.Mul(sinTheta * float32(math.Sin(phi))))
+
+	speed := fx.Speed + (e.rng.Float32()*2-1)*fx.SpeedSpread
+	velocity := vel.Add(out.Mul(speed))
+
+	if fx.Radius > 0 {
+		loc = loc.Add(randomInSphere(e.rng).Mul(fx.Radius))
+	}
+
+	e.data = append(e.data,
+		loc[0], loc[1], loc[2], born,
+		velocity[0], velocity[1], velocity[2], float32(fx.Lifetime.Seconds()),
+	)
+}
+
+// randomInSphere returns a random point in the unit sphere.
+func randomInSphere(r *rand.Rand) mgl32.Vec3 {
+	for {
+		p := mgl32.Vec3{r.Float32()*2 - 1, r.Float32()*2 - 1, r.Float32()*2 - 1}
+		if p.Len() <= 1 {
+			return p
+		}
+	}
+}
+
+// upload writes the particles in e.data over the oldest in e's buffer.
+func (e *Emitter) upload() {
+	n := len(e.data) / particleFloats
+	if n == 0 {
+		return
+	}
+	data := e.data
+	if n > e.capacity {
+		// Only the newest particles fit.
+		data = data[(n-e.capacity)*particleFloats:]
+		n = e.capacity
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, e.vbo)
+	for n > 0 {
+		count := n
+		if e.next+count > e.capacity {
+			count = e.capacity - e.next
+		}
+		gl.BufferSubData(gl.ARRAY_BUFFER, e.next*particleFloats*4, count*particleFloats*4, gl.Ptr(data[:count*particleFloats]))
+		data = data[count*particleFloats:]
+		n -= count
+		e.next = (e.next + count) % e.capacity
+	}
+}
+
+// createBuffers creates the buffer e's particles are kept in, and the vertex array that reads one
+// particle for each quad drawn.
+func (e *Emitter) createBuffers() {
+	gl.GenVertexArrays(1, &e.vao)
+	gl.BindVertexArray(e.vao)
+
+	// Start with every particle dead, born before the clock started and living no time.
+	empty := make([]float32, e.capacity*particleFloats)
+	for i := 0; i < e.capacity; i++ {
+		empty[i*particleFloats+3] = -1
+	}
+	gl.GenBuffers(1, &e.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, e.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(empty)*4, gl.Ptr(empty), gl.DYNAMIC_DRAW)
+
+	gl.EnableVertexAttribArray(particleSpawnLocation)
+	gl.VertexAttribPointer(particleSpawnLocation, 4, gl.FLOAT, false, particleFloats*4, gl.PtrOffset(0))
+	gl.VertexAttribDivisor(particleSpawnLocation, 1)
+	gl.EnableVertexAttribArray(particleMotionLocation)
+	gl.VertexAttribPointer(particleMotionLocation, 4, gl.FLOAT, false, particleFloats*4, gl.PtrOffset(4*4))
+	gl.VertexAttribDivisor(particleMotionLocation, 1)
+
+	gl.BindVertexArray(0)
+}
+
+// delete frees e's buffers. It must be called from the window's loop.
+func (e *Emitter) delete() {
+	if e.vao == 0 {
+		return
+	}
+	gl.DeleteVertexArrays(1, &e.vao)
+	gl.DeleteBuffers(1, &e.vbo)
+	e.vao, e.vbo, e.next, e.placed = 0, 0, 0, false
+}
+
+// AddEmitter adds e to the emitters drawn in w. If e has already been added, AddEmitter has no effect.
+func (w *Window) AddEmitter(e *Emitter) {
+	w.emittersMut.Lock()
+	defer w.emittersMut.Unlock()
+	for _, existing := range w.emitters {
+		if existing == e {
+			return
+		}
+	}
+	w.emitters = append(w.emitters, e)
+}
+
+// RemoveEmitter removes e from the emitters drawn in w, along with its particles. If e hasn't been
+// added, RemoveEmitter has no effect.
+func (w *Window) RemoveEmitter(e *Emitter) {
+	w.emittersMut.Lock()
+	defer w.emittersMut.Unlock()
+	for i, existing := range w.emitters {
+		if existing == e {
+			w.emitters = append(w.emitters[:i], w.emitters[i+1:]...)
+			w.Do(e.delete)
+			return
+		}
+	}
+}
+
+// frameEmitters emits the particles due this frame from each emitter in w and returns them.
+func (w *Window) frameEmitters(now float32) []*Emitter {
+	w.emittersMut.Lock()
+	emitters := append([]*Emitter(nil), w.emitters...)
+	w.emittersMut.Unlock()
+
+	for _, e := range emitters {
+		e.emit(now)
+	}
+	return emitters
+}
+
+// particleProgram draws emitters' particles.
+type particleProgram struct {
+	id                        uint32
+	projectionID, cameraID    int32
+	timeID                    int32
+	accelerationID, dragID    int32
+	colorCountID, colorAgesID int32
+	colorsID                  int32
+	sizeCountID, sizeAgesID   int32
+	sizesID                   int32
+	hasTextureID, textureID   int32
+	ages, sizes               [maxParticleKeys]float32
+	colors                    [maxParticleKeys]mgl32.Vec4
+}
+
+func newParticleProgram(vertShaderPath, fragShaderPath string) particleProgram {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic("compile fragment shader: " + err.Error())
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+	gl.BindAttribLocation(id, particleSpawnLocation, gl.Str("spawn\x00"))
+	gl.BindAttribLocation(id, particleMotionLocation, gl.Str("motion\x00"))
+	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	uniform := func(name string) int32 {
+		return gl.GetUniformLocation(id, gl.Str(name+"\x00"))
+	}
+	p := particleProgram{
+		id:             id,
+		projectionID:   uniform("projection"),
+		cameraID:       uniform("camera"),
+		timeID:         uniform("time"),
+		accelerationID: uniform("acceleration"),
+		dragID:         uniform("drag"),
+		colorCountID:   uniform("colorCount"),
+		colorAgesID:    uniform("colorAges"),
+		colorsID:       uniform("colors"),
+		sizeCountID:    uniform("sizeCount"),
+		sizeAgesID:     uniform("sizeAges"),
+		sizesID:        uniform("sizes"),
+		hasTextureID:   uniform("hasTexture"),
+		textureID:      uniform("tex"),
+	}
+	gl.ProgramUniform1i(id, p.textureID, 0)
+	return p
+}
+
+// draw draws the particles of emitters in the current viewport at time now, seen with view and
+// projection. Particles are hidden behind what has already been drawn, but don't hide each other.
+func (p *particleProgram) draw(emitters []*Emitter, view, projection mgl32.Mat4, now float32) {
+	if len(emitters) == 0 {
+		return
+	}
+
+	gl.UseProgram(p.id)
+	gl.UniformMatrix4fv(p.projectionID, 1, false, &projection[0])
+	gl.UniformMatrix4fv(p.cameraID, 1, false, &view[0])
+	gl.Uniform1f(p.timeID, now)
+
+	gl.Enable(gl.BLEND)
+	gl.DepthMask(false)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	for _, e := range emitters {
+		if e.vao == 0 {
+			continue
+		}
+		fx := e.effect
+
+		switch fx.Blend {
+		case ParticleAlpha:
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		default:
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+		}
+
+		gl.Uniform3fv(p.accelerationID, 1, &fx.Acceleration[0])
+		gl.Uniform1f(p.dragID, fx.Drag)
+
+		for i, k := range fx.Colors {
+			p.ages[i], p.colors[i] = k.Age, k.Color
+		}
+		gl.Uniform1i(p.colorCountID, int32(len(fx.Colors)))
+		gl.Uniform1fv(p.colorAgesID, maxParticleKeys, &p.ages[0])
+		gl.Uniform4fv(p.colorsID, maxParticleKeys, &p.colors[0][0])
+
+		for i, k := range fx.Sizes {
+			p.ages[i], p.sizes[i] = k.Age, k.Size
+		}
+		gl.Uniform1i(p.sizeCountID, int32(len(fx.Sizes)))
+		gl.Uniform1fv(p.sizeAgesID, maxParticleKeys, &p.ages[0])
+		gl.Uniform1fv(p.sizesID, maxParticleKeys, &p.sizes[0])
+
+		if fx.Texture != nil {
+			gl.Uniform1i(p.hasTextureID, 1)
+			gl.BindTexture(gl.TEXTURE_2D, fx.Texture.ID)
+		} else {
+			gl.Uniform1i(p.hasTextureID, 0)
+		}
+
+		gl.BindVertexArray(e.vao)
+		gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(e.capacity))
+	}
+
+	gl.BindVertexArray(0)
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+}
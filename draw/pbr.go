@@ -0,0 +1,349 @@
+package draw
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The attribute locations of the PBR program, bound before it is linked so that its shadows can be drawn
+// from the same meshes.
+const (
+	pbrVertexLocation = iota
+	pbrTexCoordLocation
+	pbrNormalLocation
+	pbrTangentLocation
+	pbrBonesLocation
+	pbrWeightsLocation
+)
+
+// PBRProgram draws meshes with physically based metallic-roughness materials, lit by the window's lights
+// and reflecting its environment. It draws both still and bone animated meshes.
+type PBRProgram struct {
+	ID            uint32
+	ProjectionID  int32
+	ModelID       int32
+	CameraID      int32
+	CamPositionID int32
+	BonesID       int32
+	SkinnedID     int32
+
+	TextureLocID  uint32
+	VertexID      uint32
+	NormalID      uint32
+	TangentID     uint32
+	VertBonesID   uint32
+	VertWeightsID uint32
+
+	hasEnvironmentID    int32
+	environmentLevelsID int32
+	brdf                uint32
+
+	view        mgl32.Mat4
+	camPosition mgl32.Vec3
+	projection  mgl32.Mat4
+	materialIDs materialIDs
+	meshesMut   sync.Mutex
+	meshes      map[*Mesh]struct{}
+}
+
+func newPBRProgram(vertShaderPath, fragShaderPath string) *PBRProgram {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic("compile fragment shader: " + err.Error())
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+
+	gl.BindAttribLocation(id, pbrVertexLocation, gl.Str("vert\x00"))
+	gl.BindAttribLocation(id, pbrTexCoordLocation, gl.Str("vertTexCoord\x00"))
+	gl.BindAttribLocation(id, pbrNormalLocation, gl.Str("vertNormal\x00"))
+	gl.BindAttribLocation(id, pbrTangentLocation, gl.Str("vertTangent\x00"))
+	gl.BindAttribLocation(id, pbrBonesLocation, gl.Str("vertBones\x00"))
+	gl.BindAttribLocation(id, pbrWeightsLocation, gl.Str("vertWeights\x00"))
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	bindLights(id)
+	bindShadows(id)
+	gl.ProgramUniform1i(id, gl.GetUniformLocation(id, gl.Str("irradianceMap\x00")), irradianceUnit)
+	gl.ProgramUniform1i(id, gl.GetUniformLocation(id, gl.Str("reflectionMap\x00")), reflectionUnit)
+	gl.ProgramUniform1i(id, gl.GetUniformLocation(id, gl.Str("brdfMap\x00")), brdfUnit)
+
+	p := &PBRProgram{
+		ID:            id,
+		ProjectionID:  gl.GetUniformLocation(id, gl.Str("projection\x00")),
+		CameraID:      gl.GetUniformLocation(id, gl.Str("camera\x00")),
+		ModelID:       gl.GetUniformLocation(id, gl.Str("model\x00")),
+		CamPositionID: gl.GetUniformLocation(id, gl.Str("camPosition\x00")),
+		BonesID:       gl.GetUniformLocation(id, gl.Str("bones\x00")),
+		SkinnedID:     gl.GetUniformLocation(id, gl.Str("skinned\x00")),
+
+		VertexID:      pbrVertexLocation,
+		TextureLocID:  pbrTexCoordLocation,
+		NormalID:      pbrNormalLocation,
+		TangentID:     pbrTangentLocation,
+		VertBonesID:   pbrBonesLocation,
+		VertWeightsID: pbrWeightsLocation,
+
+		hasEnvironmentID:    gl.GetUniformLocation(id, gl.Str("hasEnvironment\x00")),
+		environmentLevelsID: gl.GetUniformLocation(id, gl.Str("environmentLevels\x00")),
+		brdf:                newBRDFTexture(),
+
+		projection: mgl32.Ident4(),
+		view:       mgl32.Ident4(),
+		meshes:     make(map[*Mesh]struct{}),
+
+		materialIDs: newMaterialIDs(id),
+	}
+
+	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
+
+	return p
+}
+
+func (p *PBRProgram) use() {
+	gl.UseProgram(p.ID)
+}
+
+func (p *PBRProgram) setView(view mgl32.Mat4, camPosition mgl32.Vec3) {
+	p.view = view
+	p.camPosition = camPosition
+}
+
+func (p *PBRProgram) setProjection(projection mgl32.Mat4) {
+	p.projection = projection
+}
+
+func (p *PBRProgram) Draw(state *GLState) {
+
+	p.begin(state)
+
+	p.meshesMut.Lock()
+	for mesh := range p.meshes {
+		if state.cull(mesh) || state.deferBlended(mesh) {
+			continue
+		}
+		p.drawMesh(mesh, state)
+	}
+	p.meshesMut.Unlock()
+}
+
+// begin makes p the current program, seen from its view and lit by the environment of state.
+func (p *PBRProgram) begin(state *GLState) {
+	p.use()
+	gl.UniformMatrix4fv(p.CameraID, 1, false, &p.view[0])
+	gl.UniformMatrix4fv(p.ProjectionID, 1, false, &p.projection[0])
+	gl.Uniform3fv(p.CamPositionID, 1, &p.camPosition[0])
+
+	gl.ActiveTexture(gl.TEXTURE0 + brdfUnit)
+	gl.BindTexture(gl.TEXTURE_2D, p.brdf)
+	if e := state.environment; e != nil {
+		gl.Uniform1i(p.hasEnvironmentID, 1)
+		gl.Uniform1f(p.environmentLevelsID, environmentLevels)
+		gl.ActiveTexture(gl.TEXTURE0 + irradianceUnit)
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, e.irradiance)
+		gl.ActiveTexture(gl.TEXTURE0 + reflectionUnit)
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, e.reflection)
+	} else {
+		gl.Uniform1i(p.hasEnvironmentID, 0)
+	}
+}
+
+// drawMesh draws mesh with p, posing it if it has bones. begin must have made p the current program.
+func (p *PBRProgram) drawMesh(mesh *Mesh, state *GLState) {
+	if len(mesh.bones) > 0 {
+		gl.Uniform1i(p.SkinnedID, 1)
+		mesh.bonesMut.Lock()
+		gl.UniformMatrix4fv(p.BonesID, int32(len(mesh.bones)), false, &mesh.bones[0][0])
+		mesh.bonesMut.Unlock()
+	} else {
+		gl.Uniform1i(p.SkinnedID, 0)
+	}
+
+	mesh.Draw(state)
+}
+
+// drawDepth draws the meshes of p that are in view of pass into a shadow map, posing those with bones.
+func (p *PBRProgram) drawDepth(s *shadowMaps, pass depthPass) {
+	p.meshesMut.Lock()
+	defer p.meshesMut.Unlock()
+
+	s.pbr.use(pass)
+	for mesh := range p.meshes {
+		if mesh.hidden || mesh.blended() || len(mesh.bones) > 0 || !pass.frustum.containsMesh(mesh) {
+			continue
+		}
+		mesh.drawDepth(s.pbr.modelID)
+	}
+
+	s.pbrBoned.use(pass)
+	for mesh := range p.meshes {
+		if mesh.hidden || mesh.blended() || len(mesh.bones) == 0 || !pass.frustum.containsMesh(mesh) {
+			continue
+		}
+		mesh.bonesMut.Lock()
+		gl.UniformMatrix4fv(s.pbrBoned.bonesID, int32(len(mesh.bones)), false, &mesh.bones[0][0])
+		mesh.bonesMut.Unlock()
+
+		mesh.drawDepth(s.pbrBoned.modelID)
+	}
+}
+
+func (p *PBRProgram) getMaterialIDs() materialIDs {
+	return p.materialIDs
+}
+
+func (p *PBRProgram) GetModelID() int32 {
+	return p.ModelID
+}
+
+// NewMesh creates a new still mesh drawn by p. tangents may be nil, in which case they are generated.
+func (p *PBRProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, tangents []mgl32.Vec3) *Mesh {
+	mesh := p.newMesh(vertexes, faces, uvCoords, normals, tangents)
+
+	p.meshesMut.Lock()
+	p.meshes[mesh] = struct{}{}
+	p.meshesMut.Unlock()
+
+	return mesh
+}
+
+// NewSkinnedMesh creates a new bone animated mesh drawn by p. tangents may be nil, in which case they are
+// generated.
+func (p *PBRProgram) NewSkinnedMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, tangents []mgl32.Vec3, vertBones []VertBone, boneWeights []mgl32.Vec4, bones []mgl32.Mat4) *Mesh {
+	mesh := p.newMesh(vertexes, faces, uvCoords, normals, tangents)
+	mesh.bones = bones
+	mesh.radius *= boneBoundsPadding
+
+	gl.GenBuffers(1, &mesh.boneIDsVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.boneIDsVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertBones)*4*4, gl.Ptr(vertBones), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.VertBonesID)
+	gl.VertexAttribIPointer(p.VertBonesID, 4, gl.INT, 4*4, gl.PtrOffset(0))
+
+	gl.GenBuffers(1, &mesh.weightsVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.weightsVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(boneWeights)*4*4, gl.Ptr(boneWeights), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.VertWeightsID)
+	gl.VertexAttribPointer(p.VertWeightsID, 4, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
+
+	p.meshesMut.Lock()
+	p.meshes[mesh] = struct{}{}
+	p.meshesMut.Unlock()
+
+	return mesh
+}
+
+// newMesh creates a new mesh with the attributes still and animated meshes share, leaving its vertex
+// array bound.
+func (p *PBRProgram) newMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, tangents []mgl32.Vec3) *Mesh {
+
+	mesh := &Mesh{
+		count:    int32(len(faces) * 3),
+		program:  p,
+		rotation: mgl32.QuatIdent(),
+		material: NewMaterial(""),
+
+		// Save references to data so it won't get garbage-collected prematurely
+		vertexes: vertexes,
+		faces:    faces,
+		uvCoords: uvCoords,
+		normals:  normals,
+		tangents: meshTangents(vertexes, faces, uvCoords, normals, tangents),
+		bounds:   newBounds(vertexes),
+	}
+	mesh.radius = mesh.bounds.Radius()
+
+	gl.GenVertexArrays(1, &mesh.vao)
+	gl.BindVertexArray(mesh.vao)
+
+	gl.GenBuffers(1, &mesh.vertexVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.vertexVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertexes)*3*4, gl.Ptr(vertexes), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.VertexID)
+	// attribute id - data type - transpose - stride - offset
+	gl.VertexAttribPointer(p.VertexID, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
+
+	if len(uvCoords) > 0 {
+		gl.GenBuffers(1, &mesh.uvVBO)
+		gl.BindBuffer(gl.ARRAY_BUFFER, mesh.uvVBO)
+		// buffer type - length in bytes - data pointer - draw type
+		gl.BufferData(gl.ARRAY_BUFFER, len(uvCoords)*2*4, gl.Ptr(uvCoords), gl.STATIC_DRAW)
+
+		gl.EnableVertexAttribArray(p.TextureLocID)
+		// attribute id - data type - transpose - stride - offset
+		gl.VertexAttribPointer(p.TextureLocID, 2, gl.FLOAT, false, 2*4, gl.PtrOffset(0))
+	}
+
+	gl.GenBuffers(1, &mesh.indexBufferID)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, mesh.indexBufferID)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(faces)*3*4, gl.Ptr(faces), gl.STATIC_DRAW)
+
+	gl.GenBuffers(1, &mesh.normalVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.normalVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(normals)*3*4, gl.Ptr(normals), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.NormalID)
+	gl.VertexAttribPointer(p.NormalID, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
+
+	gl.GenBuffers(1, &mesh.tangentVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.tangentVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(mesh.tangents)*4*4, gl.Ptr(mesh.tangents), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.TangentID)
+	gl.VertexAttribPointer(p.TangentID, 4, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
+
+	return mesh
+}
+
+func (p *PBRProgram) RemoveMesh(m *Mesh) {
+	p.meshesMut.Lock()
+	delete(p.meshes, m)
+	p.meshesMut.Unlock()
+}
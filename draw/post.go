@@ -0,0 +1,490 @@
+package draw
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// maxBloomLevels is the most times the scene is halved in size to spread bloom.
+const maxBloomLevels = 8
+
+// PostPass is a post-processing pass run on the scene after it is drawn.
+type PostPass string
+
+const (
+	// PostBloom spreads light brighter than a threshold into a glow around it.
+	PostBloom PostPass = "bloom"
+	// PostToneMap maps the unbounded light of the scene into the range a screen can show.
+	PostToneMap PostPass = "tonemap"
+	// PostFXAA smooths jagged edges.
+	PostFXAA PostPass = "fxaa"
+	// PostVignette darkens the edges of the window.
+	PostVignette PostPass = "vignette"
+	// PostGrade adjusts the scene's contrast, saturation and colour balance.
+	PostGrade PostPass = "grade"
+)
+
+// ToneMapper is the curve a tone mapping pass maps light with.
+type ToneMapper string
+
+const (
+	// ToneMapACES is a filmic curve that rolls bright highlights off to white.
+	ToneMapACES ToneMapper = "aces"
+	// ToneMapReinhard compresses all light evenly, never quite reaching white.
+	ToneMapReinhard ToneMapper = "reinhard"
+	// ToneMapClamp cuts off light brighter than white.
+	ToneMapClamp ToneMapper = "clamp"
+)
+
+// PostSettings sets which post-processing passes run on a window's scene, in order, and how each looks.
+// The scene is drawn in linear light with no upper bound, so bloom belongs before tone mapping, and
+// FXAA after it.
+type PostSettings struct {
+	Passes   []PostPass       `json:"passes"`
+	Bloom    BloomSettings    `json:"bloom"`
+	ToneMap  ToneMapSettings  `json:"toneMap"`
+	Vignette VignetteSettings `json:"vignette"`
+	Grade    GradeSettings    `json:"grade"`
+}
+
+// BloomSettings sets how light blooms.
+type BloomSettings struct {
+	// Threshold is how bright light must be to bloom, fading in over Knee below it.
+	Threshold float32 `json:"threshold"`
+	Knee      float32 `json:"knee"`
+	// Intensity scales the glow added to the scene.
+	Intensity float32 `json:"intensity"`
+	// Levels is the number of times the scene is halved to spread the glow, up to eight. More levels
+	// spread it wider.
+	Levels int `json:"levels"`
+}
+
+// ToneMapSettings sets how light is mapped to the screen.
+type ToneMapSettings struct {
+	Operator ToneMapper `json:"operator"`
+	// Exposure scales light before it is mapped.
+	Exposure float32 `json:"exposure"`
+}
+
+// VignetteSettings sets how the edges of the window are darkened.
+type VignetteSettings struct {
+	// Intensity is how much the corners are darkened, from 0 to 1.
+	Intensity float32 `json:"intensity"`
+	// Radius is the distance from the center, as a fraction of the window's height, darkening ends
+	// at, and Softness the distance before it that darkening starts.
+	Radius   float32 `json:"radius"`
+	Softness float32 `json:"softness"`
+}
+
+// GradeSettings sets the colour grade of the scene. The zero lift and ones elsewhere leave it as it is.
+type GradeSettings struct {
+	Contrast   float32 `json:"contrast"`
+	Saturation float32 `json:"saturation"`
+	// Lift raises the shadows, Gamma bends the midtones and Gain scales the highlights of each channel.
+	Lift  mgl32.Vec3 `json:"lift"`
+	Gamma mgl32.Vec3 `json:"gamma"`
+	Gain  mgl32.Vec3 `json:"gain"`
+}
+
+// DefaultPostSettings are the post-processing settings windows start with.
+var DefaultPostSettings = PostSettings{
+	Passes: []PostPass{PostBloom, PostToneMap, PostFXAA},
+	Bloom: BloomSettings{
+		Threshold: 1,
+		Knee:      0.5,
+		Intensity: 0.6,
+		Levels:    5,
+	},
+	ToneMap: ToneMapSettings{
+		Operator: ToneMapACES,
+		Exposure: 1,
+	},
+	Vignette: VignetteSettings{
+		Intensity: 0.4,
+		Radius:    0.8,
+		Softness:  0.5,
+	},
+	Grade: GradeSettings{
+		Contrast:   1,
+		Saturation: 1,
+		Gamma:      mgl32.Vec3{1, 1, 1},
+		Gain:       mgl32.Vec3{1, 1, 1},
+	},
+}
+
+// LoadPostSettings loads post-processing settings from the JSON file at path. Settings the file leaves
+// out keep their defaults.
+func LoadPostSettings(path string) (PostSettings, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return PostSettings{}, fmt.Errorf("load post settings %s: %v", path, err)
+	}
+	s := DefaultPostSettings
+	s.Passes = nil
+	if err := json.Unmarshal(data, &s); err != nil {
+		return PostSettings{}, fmt.Errorf("load post settings %s: %v", path, err)
+	}
+	if s.Passes == nil {
+		s.Passes = DefaultPostSettings.Passes
+	}
+	for _, p := range s.Passes {
+		switch p {
+		case PostBloom, PostToneMap, PostFXAA, PostVignette, PostGrade:
+		default:
+			return PostSettings{}, fmt.Errorf("load post settings %s: unknown pass %q", path, p)
+		}
+	}
+	switch s.ToneMap.Operator {
+	case ToneMapACES, ToneMapReinhard, ToneMapClamp:
+	default:
+		return PostSettings{}, fmt.Errorf("load post settings %s: unknown tone mapper %q", path, s.ToneMap.Operator)
+	}
+	return s, nil
+}
+
+// clamp returns s with its settings limited to what can be drawn.
+func (s PostSettings) clamp() PostSettings {
+	s.Passes = append([]PostPass(nil), s.Passes...)
+	if s.Bloom.Levels < 1 {
+		s.Bloom.Levels = 1
+	}
+	if s.Bloom.Levels > maxBloomLevels {
+		s.Bloom.Levels = maxBloomLevels
+	}
+	if s.Bloom.Knee < 0 {
+		s.Bloom.Knee = 0
+	}
+	s.Vignette.Intensity = mgl32.Clamp(s.Vignette.Intensity, 0, 1)
+	return s
+}
+
+// PostSettings returns the post-processing settings of w.
+func (w *Window) PostSettings() PostSettings {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.postSettings
+}
+
+// SetPostSettings sets the post-processing settings of w, taking effect from the next frame.
+func (w *Window) SetPostSettings(s PostSettings) {
+	s = s.clamp()
+	w.mut.Lock()
+	w.postSettings = s
+	w.mut.Unlock()
+}
+
+// postTarget is a texture that can be drawn into.
+type postTarget struct {
+	fbo, texture  uint32
+	width, height int
+}
+
+func newPostTarget(width, height int) postTarget {
+	t := postTarget{width: width, height: height}
+	gl.GenTextures(1, &t.texture)
+	gl.BindTexture(gl.TEXTURE_2D, t.texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, int32(width), int32(height), 0, gl.RGBA, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	gl.GenFramebuffers(1, &t.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, t.texture, 0)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	return t
+}
+
+func (t postTarget) delete() {
+	gl.DeleteFramebuffers(1, &t.fbo)
+	gl.DeleteTextures(1, &t.texture)
+}
+
+// bind draws into t.
+func (t postTarget) bind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	gl.Viewport(0, 0, int32(t.width), int32(t.height))
+}
+
+// postShader is the program of one post-processing step, drawn over a whole target.
+type postShader struct {
+	id       uint32
+	uniforms map[string]int32
+}
+
+func newPostShader(vertShaderPath, fragShaderPath string) postShader {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(fmt.Sprintf("compile fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	return postShader{id: id, uniforms: make(map[string]int32)}
+}
+
+// uniform returns the location of the uniform of s called name.
+func (s postShader) uniform(name string) int32 {
+	if id, ok := s.uniforms[name]; ok {
+		return id
+	}
+	id := gl.GetUniformLocation(s.id, gl.Str(name+"\x00"))
+	s.uniforms[name] = id
+	return id
+}
+
+// use makes s the current program, reading the textures in sources from units in order.
+func (s postShader) use(sources ...uint32) {
+	gl.UseProgram(s.id)
+	for i, t := range sources {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, t)
+	}
+}
+
+// postProcessor draws a window's scene into an offscreen HDR target, then runs the post-processing
+// passes on it and shows the result in the window.
+type postProcessor struct {
+	width, height int
+	scene         postTarget
+	depth         uint32
+	// swap holds the results of passes, each reading one and drawing into the other.
+	swap  [2]postTarget
+	bloom []postTarget
+	vao   uint32
+
+	downsample, upsample, bloomShader    postShader
+	tonemap, fxaa, vignette, grade, show postShader
+}
+
+func newPostProcessor() *postProcessor {
+	p := &postProcessor{
+		downsample:  newPostShader("shaders/post.vert", "shaders/post_downsample.frag"),
+		upsample:    newPostShader("shaders/post.vert", "shaders/post_upsample.frag"),
+		bloomShader: newPostShader("shaders/post.vert", "shaders/post_bloom.frag"),
+		tonemap:     newPostShader("shaders/post.vert", "shaders/post_tonemap.frag"),
+		fxaa:        newPostShader("shaders/post.vert", "shaders/post_fxaa.frag"),
+		vignette:    newPostShader("shaders/post.vert", "shaders/post_vignette.frag"),
+		grade:       newPostShader("shaders/post.vert", "shaders/post_grade.frag"),
+		show:        newPostShader("shaders/post.vert", "shaders/post_present.frag"),
+	}
+	gl.ProgramUniform1i(p.bloomShader.id, p.bloomShader.uniform("bloom"), 1)
+
+	// Passes are drawn from a triangle made by the vertex shader alone, but a vertex array must still
+	// be bound to draw it.
+	gl.GenVertexArrays(1, &p.vao)
+	return p
+}
+
+// resize replaces p's targets to fit a window width by height, if they don't already.
+func (p *postProcessor) resize(width, height int) {
+	if width == p.width && height == p.height {
+		return
+	}
+	if p.width > 0 {
+		p.scene.delete()
+		gl.DeleteRenderbuffers(1, &p.depth)
+		for _, t := range p.swap {
+			t.delete()
+		}
+		for _, t := range p.bloom {
+			t.delete()
+		}
+	}
+	p.width, p.height = width, height
+
+	// The scene keeps a float depth buffer, which reversed-Z projections need to be precise.
+	p.scene = newPostTarget(width, height)
+	gl.GenRenderbuffers(1, &p.depth)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, p.depth)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT32F, int32(width), int32(height))
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.scene.fbo)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, p.depth)
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		panic(fmt.Sprintf("create scene framebuffer: status 0x%x", status))
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	for i := range p.swap {
+		p.swap[i] = newPostTarget(width, height)
+	}
+
+	p.bloom = p.bloom[:0]
+	w, h := width, height
+	for i := 0; i < maxBloomLevels; i++ {
+		w, h = (w+1)/2, (h+1)/2
+		p.bloom = append(p.bloom, newPostTarget(w, h))
+		if w == 1 && h == 1 {
+			break
+		}
+	}
+}
+
+// bindScene draws into the scene target.
+func (p *postProcessor) bindScene() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.scene.fbo)
+}
+
+// run runs the passes of s on the scene, then shows the result in the framebuffer output.
+func (p *postProcessor) run(s PostSettings, output uint32) {
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.SCISSOR_TEST)
+	gl.DepthMask(false)
+	gl.BindVertexArray(p.vao)
+
+	source := p.scene
+	next := 0
+	for _, pass := range s.Passes {
+		target := p.swap[next]
+		switch pass {
+		case PostBloom:
+			p.runBloom(s.Bloom, source, target)
+		case PostToneMap:
+			target.bind()
+			p.tonemap.use(source.texture)
+			gl.Uniform1i(p.tonemap.uniform("operator"), toneMapOperator(s.ToneMap.Operator))
+			gl.Uniform1f(p.tonemap.uniform("exposure"), s.ToneMap.Exposure)
+			p.draw()
+		case PostFXAA:
+			target.bind()
+			p.fxaa.use(source.texture)
+			gl.Uniform2f(p.fxaa.uniform("texel"), 1/float32(source.width), 1/float32(source.height))
+			p.draw()
+		case PostVignette:
+			target.bind()
+			p.vignette.use(source.texture)
+			gl.Uniform1f(p.vignette.uniform("aspect"), float32(source.width)/float32(source.height))
+			gl.Uniform1f(p.vignette.uniform("intensity"), s.Vignette.Intensity)
+			gl.Uniform1f(p.vignette.uniform("radius"), s.Vignette.Radius)
+			gl.Uniform1f(p.vignette.uniform("softness"), s.Vignette.Softness)
+			p.draw()
+		case PostGrade:
+			target.bind()
+			p.grade.use(source.texture)
+			gl.Uniform1f(p.grade.uniform("contrast"), s.Grade.Contrast)
+			gl.Uniform1f(p.grade.uniform("saturation"), s.Grade.Saturation)
+			gl.Uniform3fv(p.grade.uniform("lift"), 1, &s.Grade.Lift[0])
+			gl.Uniform3fv(p.grade.uniform("gamma"), 1, &s.Grade.Gamma[0])
+			gl.Uniform3fv(p.grade.uniform("gain"), 1, &s.Grade.Gain[0])
+			p.draw()
+		default:
+			continue
+		}
+		source = target
+		next = 1 - next
+	}
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, output)
+	gl.Viewport(0, 0, int32(p.width), int32(p.height))
+	p.show.use(source.texture)
+	p.draw()
+
+	gl.BindVertexArray(0)
+	gl.DepthMask(true)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// runBloom adds the glow of the light in source brighter than s.Threshold to it, drawing into target.
+// The light is halved in size level by level, then added back up the levels, which spreads it widely
+// for little work.
+func (p *postProcessor) runBloom(s BloomSettings, source, target postTarget) {
+	levels := s.Levels
+	if levels > len(p.bloom) {
+		levels = len(p.bloom)
+	}
+
+	p.downsample.use()
+	gl.Uniform1f(p.downsample.uniform("threshold"), s.Threshold)
+	gl.Uniform1f(p.downsample.uniform("knee"), s.Knee)
+	from := source
+	for i := 0; i < levels; i++ {
+		p.bloom[i].bind()
+		p.downsample.use(from.texture)
+		gl.Uniform1i(p.downsample.uniform("prefilter"), boolInt(i == 0))
+		gl.Uniform2f(p.downsample.uniform("texel"), 1/float32(from.width), 1/float32(from.height))
+		p.draw()
+		from = p.bloom[i]
+	}
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.ONE, gl.ONE)
+	for i := levels - 1; i > 0; i-- {
+		p.bloom[i-1].bind()
+		p.upsample.use(p.bloom[i].texture)
+		gl.Uniform2f(p.upsample.uniform("texel"), 1/float32(p.bloom[i].width), 1/float32(p.bloom[i].height))
+		p.draw()
+	}
+	gl.Disable(gl.BLEND)
+
+	target.bind()
+	p.bloomShader.use(source.texture, p.bloom[0].texture)
+	gl.Uniform2f(p.bloomShader.uniform("bloomTexel"), 1/float32(p.bloom[0].width), 1/float32(p.bloom[0].height))
+	gl.Uniform1f(p.bloomShader.uniform("intensity"), s.Intensity)
+	p.draw()
+}
+
+// draw draws the current post-processing step over the whole of its target.
+func (p *postProcessor) draw() {
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+}
+
+// toneMapOperator returns the number post_tonemap.frag knows op by.
+func toneMapOperator(op ToneMapper) int32 {
+	switch op {
+	case ToneMapReinhard:
+		return 1
+	case ToneMapClamp:
+		return 2
+	}
+	return 0
+}
+
+// boolInt returns 1 for true and 0 for false, as boolean uniforms are set.
+func boolInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -2,7 +2,11 @@ package draw
 
 import (
 	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
@@ -16,9 +20,74 @@ const (
 	ProgramTypeStandard ProgramType = iota
 	// ProgramTypeBoned is a shader program for bone animated models
 	ProgramTypeBoned = iota
+	// ProgramTypePBR is a physically based shader program for still and bone animated models
+	ProgramTypePBR = iota
 )
 
+// GLState is the state of the frame being drawn, shared by every program that draws in it.
 type GLState struct {
+	frustum     frustum
+	stats       RenderStats
+	environment *Environment
+	// blended collects the meshes with blended materials as programs draw, to be drawn after the solid
+	// meshes of every program.
+	blended []*Mesh
+}
+
+// cull reports whether m should be skipped, because it is hidden or out of view, and counts it.
+func (s *GLState) cull(m *Mesh) bool {
+	if m.hidden {
+		return true
+	}
+	if s.frustum != nil && !s.frustum.containsMesh(m) {
+		s.stats.Culled++
+		return true
+	}
+	s.stats.Drawn++
+	return false
+}
+
+// deferBlended reports whether m has a blended material, and if so adds it to the meshes drawn by
+// drawBlended.
+func (s *GLState) deferBlended(m *Mesh) bool {
+	if !m.blended() {
+		return false
+	}
+	s.blended = append(s.blended, m)
+	return true
+}
+
+// drawBlended draws the blended meshes collected while programs drew, furthest from camPosition first so
+// nearer surfaces blend over them, then forgets them. Blended meshes are hidden behind solid ones, but
+// don't hide each other.
+func (s *GLState) drawBlended(camPosition mgl32.Vec3) {
+	if len(s.blended) == 0 {
+		return
+	}
+	distances := make(map[*Mesh]float32, len(s.blended))
+	for _, m := range s.blended {
+		distances[m] = m.center().Sub(camPosition).Len()
+	}
+	sort.SliceStable(s.blended, func(i, j int) bool {
+		return distances[s.blended[i]] > distances[s.blended[j]]
+	})
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.DepthMask(false)
+
+	var current Program
+	for _, m := range s.blended {
+		if m.program != current {
+			current = m.program
+			current.begin(s)
+		}
+		current.drawMesh(m, s)
+	}
+
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+	s.blended = s.blended[:0]
 }
 
 // Program is a shader program.
@@ -27,7 +96,39 @@ type Program interface {
 	setProjection(projection mgl32.Mat4)
 	RemoveMesh(m *Mesh)
 	Draw(state *GLState)
+	begin(state *GLState)
+	drawMesh(m *Mesh, state *GLState)
 	GetModelID() int32
+	getMaterialIDs() materialIDs
+	drawDepth(s *shadowMaps, pass depthPass)
+}
+
+var (
+	shaderRootMut sync.Mutex
+	shaderRoot    string
+)
+
+// ShaderRoot returns the directory the shaders directory is loaded from.
+func ShaderRoot() string {
+	shaderRootMut.Lock()
+	defer shaderRootMut.Unlock()
+	return shaderRoot
+}
+
+// SetShaderRoot sets the directory the shaders directory is loaded from, which is the working directory
+// if dir is empty. It applies to windows created afterwards.
+func SetShaderRoot(dir string) {
+	shaderRootMut.Lock()
+	defer shaderRootMut.Unlock()
+	shaderRoot = dir
+}
+
+// readShader reads the shader source file at path, relative to the shader root.
+func readShader(path string) ([]byte, error) {
+	if root := ShaderRoot(); root != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	return ioutil.ReadFile(path)
 }
 
 func compileShader(source string, shaderType uint32) (uint32, error) {
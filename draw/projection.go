@@ -0,0 +1,87 @@
+package draw
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Projection describes how a viewport projects the scene onto the screen.
+type Projection struct {
+	// FOV is the vertical field of view of a perspective projection, in radians.
+	FOV float32
+	// Near and Far are the distances of the near and far clip planes. Far is ignored when Infinite is set.
+	Near, Far float32
+	// Infinite sets a perspective projection's far plane infinitely far away, so nothing is ever clipped
+	// for being too distant.
+	Infinite bool
+	// ReversedZ maps the near plane to a depth of 1 and the far plane to 0, which spreads depth precision
+	// evenly enough to draw a ship's cockpit and a distant planet without z-fighting. It is ignored on
+	// drivers that don't support glClipControl.
+	ReversedZ bool
+	// Orthographic projects without perspective, showing Height units of the scene from the bottom of
+	// the viewport to the top.
+	Orthographic bool
+	Height       float32
+}
+
+// DefaultProjection is the projection viewports and cameras start with: a 45° perspective with no far
+// plane, using reversed-Z where it is supported.
+var DefaultProjection = Projection{
+	FOV:       mgl32.DegToRad(45),
+	Near:      0.1,
+	Far:       100000,
+	Infinite:  true,
+	ReversedZ: true,
+	Height:    100,
+}
+
+// Matrix returns the projection matrix of p for a viewport with the specified aspect ratio. reversed
+// is whether the depth range is reversed, which should only be true if p.ReversedZ is set and the
+// driver supports it.
+func (p Projection) Matrix(aspect float32, reversed bool) mgl32.Mat4 {
+	// Matrices are column major, so m[c*4+r] is row r of column c.
+	if p.Orthographic {
+		h := p.Height / 2
+		w := h * aspect
+		if !reversed {
+			return mgl32.Ortho(-w, w, -h, h, p.Near, p.Far)
+		}
+		m := mgl32.Ortho(-w, w, -h, h, p.Near, p.Far)
+		m[10] = 1 / (p.Far - p.Near)
+		m[14] = p.Far / (p.Far - p.Near)
+		return m
+	}
+
+	f := float32(1 / math.Tan(float64(p.FOV)/2))
+	m := mgl32.Mat4{}
+	m[0] = f / aspect
+	m[5] = f
+	m[11] = -1
+	switch {
+	case reversed && p.Infinite:
+		m[14] = p.Near
+	case reversed:
+		m[10] = p.Near / (p.Far - p.Near)
+		m[14] = p.Far * p.Near / (p.Far - p.Near)
+	case p.Infinite:
+		m[10] = -1
+		m[14] = -2 * p.Near
+	default:
+		return mgl32.Perspective(p.FOV, aspect, p.Near, p.Far)
+	}
+	return m
+}
+
+// Lerp returns the projection t of the way from p to q. Settings that can't be blended switch halfway.
+func (p Projection) Lerp(q Projection, t float32) Projection {
+	r := q
+	if t < 0.5 {
+		r = p
+	}
+	r.FOV = p.FOV + (q.FOV-p.FOV)*t
+	r.Near = p.Near + (q.Near-p.Near)*t
+	r.Far = p.Far + (q.Far-p.Far)*t
+	r.Height = p.Height + (q.Height-p.Height)*t
+	return r
+}
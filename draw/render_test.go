@@ -0,0 +1,60 @@
+//go:build egl
+// +build egl
+
+package draw_test
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+	"github.com/lsmith130/space/draw/drawtest"
+)
+
+// cube returns the vertexes, faces and normals of a cube 2 units wide around the origin, with each side
+// flat shaded.
+func cube() ([]mgl32.Vec3, []draw.MeshFace, []mgl32.Vec3) {
+	var vertexes, normals []mgl32.Vec3
+	var faces []draw.MeshFace
+	for axis := 0; axis < 3; axis++ {
+		for _, sign := range []float32{-1, 1} {
+			var normal, u, v mgl32.Vec3
+			normal[axis] = sign
+			u[(axis+1)%3] = 1
+			v[(axis+2)%3] = 1
+			if sign < 0 {
+				u, v = v, u
+			}
+			n := uint32(len(vertexes))
+			for _, c := range [][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+				vertexes = append(vertexes, normal.Add(u.Mul(c[0])).Add(v.Mul(c[1])))
+				normals = append(normals, normal)
+			}
+			faces = append(faces, draw.MeshFace{n, n + 1, n + 2}, draw.MeshFace{n, n + 2, n + 3})
+		}
+	}
+	return vertexes, faces, normals
+}
+
+func TestRenderLitCube(t *testing.T) {
+	w := drawtest.Window(t, 128, 96)
+
+	sky := draw.DefaultStarfield
+	sky.Stars = 400
+	sky.Size = 64
+	w.SetSky(draw.NewStarfieldSky(sky))
+	w.AddLight(draw.NewDirectionalLight(mgl32.Vec3{1, 0.95, 0.9}, 0.8, mgl32.Vec3{-1, -2, -1.5}))
+	w.SetAmbient(mgl32.Vec3{0.05, 0.05, 0.08})
+
+	vertexes, faces, normals := cube()
+	mesh := w.GetStandardProgram().NewMesh(vertexes, faces, nil, normals, nil)
+	material := draw.NewMaterial("paint")
+	material.DiffuseColor = mgl32.Vec3{0.8, 0.3, 0.1}
+	mesh.SetMaterial(material)
+	mesh.SetRotation(mgl32.QuatRotate(0.6, mgl32.Vec3{0, 1, 0}))
+
+	eye := mgl32.Vec3{2, 2.5, 5}
+	w.SetView(mgl32.LookAtV(eye, mgl32.Vec3{}, mgl32.Vec3{0, 1, 0}), eye)
+
+	drawtest.AssertGolden(t, w.RenderImage(), "testdata/lit_cube.png", drawtest.DefaultTolerance)
+}
@@ -0,0 +1,549 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// MaxCascades is the most cascades the shadow of a directional light can be split into.
+	MaxCascades = 4
+	// MaxSpotShadows is the most spot lights that can cast shadows at once.
+	MaxSpotShadows = 4
+	// MaxPointShadows is the most point lights that can cast shadows at once.
+	MaxPointShadows = 4
+
+	// shadowsBinding is the uniform buffer binding point of the shadow block of every program.
+	shadowsBinding = 1
+	// The texture units the shadow maps are bound to while drawing. Unit 0 holds mesh textures.
+	cascadeShadowUnit = 1
+	spotShadowUnit    = 2
+	pointShadowUnit   = 3
+
+	// shadowNear is the near plane of spot and point light shadows.
+	shadowNear = 0.1
+	// cascadeSplitLambda blends cascade splits between evenly spaced, at 0, and spaced to keep the same
+	// texel density on screen, at 1.
+	cascadeSplitLambda = 0.75
+	// spotShadowMargin widens the shadow of a spot light past its outer cone, in radians, so filtering
+	// at the edge of the cone stays inside the map.
+	spotShadowMargin = 0.05
+)
+
+// ShadowQuality sets how detailed and how far reaching the shadows of a window are.
+type ShadowQuality struct {
+	// Resolution is the width and height of each directional shadow cascade in texels. Spot shadows are
+	// drawn at half of it and point shadows at a quarter. Zero turns shadows off.
+	Resolution int
+	// Cascades is the number of cascades the shadow of a directional light is split into, up to
+	// MaxCascades. More cascades keep shadows sharp further from the camera.
+	Cascades int
+	// Distance is how far from the camera directional shadows reach, and how far spot and point
+	// shadows reach from lights with no range.
+	Distance float32
+	// PCFRadius is the radius of the filter that softens the edges of shadows, in texels.
+	PCFRadius int
+	// Bias is how far surfaces are pushed out along their normals before looking up their shadows, so
+	// they don't shadow themselves.
+	Bias float32
+}
+
+var (
+	// ShadowsOff draws no shadows.
+	ShadowsOff = ShadowQuality{}
+	// ShadowsLow draws blocky shadows that end close to the camera.
+	ShadowsLow = ShadowQuality{Resolution: 1024, Cascades: 2, Distance: 150, Bias: 0.1}
+	// ShadowsMedium draws filtered shadows that reach across most levels.
+	ShadowsMedium = ShadowQuality{Resolution: 2048, Cascades: 3, Distance: 300, PCFRadius: 1, Bias: 0.05}
+	// ShadowsHigh draws soft, sharp shadows that reach far from the camera.
+	ShadowsHigh = ShadowQuality{Resolution: 4096, Cascades: 4, Distance: 500, PCFRadius: 2, Bias: 0.03}
+	// DefaultShadowQuality is the shadow quality windows start with.
+	DefaultShadowQuality = ShadowsMedium
+)
+
+// clamp returns q with its settings limited to what can be drawn.
+func (q ShadowQuality) clamp() ShadowQuality {
+	if q.Resolution < 0 {
+		q.Resolution = 0
+	}
+	if q.Cascades < 0 {
+		q.Cascades = 0
+	}
+	if q.Cascades > MaxCascades {
+		q.Cascades = MaxCascades
+	}
+	if q.PCFRadius < 0 {
+		q.PCFRadius = 0
+	}
+	return q
+}
+
+// shadowBlock is the Shadows uniform block of the shaders, by std140 rules.
+type shadowBlock struct {
+	cascadeMatrices [MaxCascades]mgl32.Mat4
+	spotMatrices    [MaxSpotShadows]mgl32.Mat4
+	pointFar        [MaxPointShadows]float32
+	// settings holds the bias, the PCF radius and the number of cascades.
+	settings [4]float32
+}
+
+// depthProgram is a shader program that draws meshes into a shadow map.
+type depthProgram struct {
+	id              uint32
+	lightMatrixID   int32
+	modelID         int32
+	bonesID         int32
+	lightPositionID int32
+	farID           int32
+}
+
+// depthPass is the drawing of one shadow map, or one face of a point light's shadow cube.
+type depthPass struct {
+	matrix        mgl32.Mat4
+	frustum       frustum
+	lightPosition mgl32.Vec3
+	// far is the far plane of point light shadows, which store the distance from the light, and zero
+	// for other shadows.
+	far float32
+}
+
+// shadowMaps draws the shadows of a window's lights, and holds them for its programs to use.
+type shadowMaps struct {
+	quality    ShadowQuality
+	fbo        uint32
+	cascadeTex uint32
+	spotTex    uint32
+	pointTex   uint32
+	ubo        uint32
+	standard   depthProgram
+	boned      depthProgram
+	pbr        depthProgram
+	pbrBoned   depthProgram
+	block      shadowBlock
+
+	directional *Light
+	spotLights  []*Light
+	pointLights []*Light
+	index       map[*Light]int
+}
+
+// newShadowMaps creates the shadow maps of a window with standard, boned and pbr programs.
+func newShadowMaps(standard *StandardProgram, boned *BoneProgram, pbr *PBRProgram, quality ShadowQuality) *shadowMaps {
+	s := &shadowMaps{
+		standard: newDepthProgram("shaders/shadow.vert", "shaders/shadow.frag", map[string]uint32{
+			"vert": standard.VertexID,
+		}),
+		boned: newDepthProgram("shaders/shadow_bones.vert", "shaders/shadow.frag", map[string]uint32{
+			"vert":        boned.VertexID,
+			"vertBones":   boned.VertBonesID,
+			"vertWeights": boned.VertWeightsID,
+		}),
+		pbr: newDepthProgram("shaders/shadow.vert", "shaders/shadow.frag", map[string]uint32{
+			"vert": pbr.VertexID,
+		}),
+		pbrBoned: newDepthProgram("shaders/shadow_bones.vert", "shaders/shadow.frag", map[string]uint32{
+			"vert":        pbr.VertexID,
+			"vertBones":   pbr.VertBonesID,
+			"vertWeights": pbr.VertWeightsID,
+		}),
+		index: make(map[*Light]int),
+	}
+
+	gl.GenFramebuffers(1, &s.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, s.fbo)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	gl.GenBuffers(1, &s.ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, s.ubo)
+	gl.BufferData(gl.UNIFORM_BUFFER, int(unsafe.Sizeof(shadowBlock{})), nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, shadowsBinding, s.ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+
+	s.setQuality(quality)
+	return s
+}
+
+func newDepthProgram(vertShaderPath, fragShaderPath string, attribs map[string]uint32) depthProgram {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic("compile fragment shader: " + err.Error())
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+
+	// Meshes are set up with the attribute locations of the programs that draw them, so the depth
+	// programs must read their attributes from the same locations.
+	for name, location := range attribs {
+		gl.BindAttribLocation(id, location, gl.Str(name+"\x00"))
+	}
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	return depthProgram{
+		id:              id,
+		lightMatrixID:   gl.GetUniformLocation(id, gl.Str("lightMatrix\x00")),
+		modelID:         gl.GetUniformLocation(id, gl.Str("model\x00")),
+		bonesID:         gl.GetUniformLocation(id, gl.Str("bones\x00")),
+		lightPositionID: gl.GetUniformLocation(id, gl.Str("lightPosition\x00")),
+		farID:           gl.GetUniformLocation(id, gl.Str("far\x00")),
+	}
+}
+
+// use draws with d for pass.
+func (d depthProgram) use(pass depthPass) {
+	gl.UseProgram(d.id)
+	gl.UniformMatrix4fv(d.lightMatrixID, 1, false, &pass.matrix[0])
+	gl.Uniform3fv(d.lightPositionID, 1, &pass.lightPosition[0])
+	gl.Uniform1f(d.farID, pass.far)
+}
+
+// bindShadows connects the Shadows uniform block and shadow map samplers of program, if it has them,
+// to the shadow maps.
+func bindShadows(program uint32) {
+	index := gl.GetUniformBlockIndex(program, gl.Str("Shadows\x00"))
+	if index != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(program, index, shadowsBinding)
+	}
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("cascadeShadows\x00")), cascadeShadowUnit)
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("spotShadows\x00")), spotShadowUnit)
+	gl.ProgramUniform1i(program, gl.GetUniformLocation(program, gl.Str("pointShadows\x00")), pointShadowUnit)
+}
+
+// setQuality sets the quality of s, replacing its shadow maps.
+func (s *shadowMaps) setQuality(q ShadowQuality) {
+	s.quality = q.clamp()
+
+	textures := []uint32{s.cascadeTex, s.spotTex, s.pointTex}
+	gl.DeleteTextures(int32(len(textures)), &textures[0])
+
+	// Shadow maps are kept even while shadows are off, so the samplers of the programs are always
+	// complete.
+	res := s.quality.Resolution
+	s.cascadeTex = newShadowTexture(gl.TEXTURE_2D_ARRAY, res, MaxCascades)
+	s.spotTex = newShadowTexture(gl.TEXTURE_2D_ARRAY, res/2, MaxSpotShadows)
+	s.pointTex = newShadowTexture(gl.TEXTURE_CUBE_MAP_ARRAY, res/4, 6*MaxPointShadows)
+}
+
+// newShadowTexture creates a depth texture array of target with layers of size texels square, which
+// compares against the depths it is sampled with.
+func newShadowTexture(target uint32, size, layers int) uint32 {
+	if size < 1 {
+		size = 1
+	}
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(target, tex)
+	gl.TexImage3D(target, 0, gl.DEPTH_COMPONENT32F, int32(size), int32(size), int32(layers), 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+
+	// Linear filtering of a comparing texture blends the results of the four nearest texels, which
+	// smooths shadow edges for free.
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(target, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.BindTexture(target, 0)
+	return tex
+}
+
+// assign picks the lights that cast shadows this frame: the first directional light, and the first
+// spot and point lights up to their limits.
+func (s *shadowMaps) assign(lights []*Light) {
+	s.directional = nil
+	s.spotLights = s.spotLights[:0]
+	s.pointLights = s.pointLights[:0]
+	for l := range s.index {
+		delete(s.index, l)
+	}
+	if s.quality.Resolution == 0 {
+		return
+	}
+
+	for _, l := range lights {
+		if !l.CastsShadows() {
+			continue
+		}
+		switch l.Type() {
+		case LightDirectional:
+			if s.directional == nil && s.quality.Cascades > 0 {
+				s.directional = l
+				s.index[l] = 0
+			}
+		case LightSpot:
+			if len(s.spotLights) < MaxSpotShadows {
+				s.index[l] = len(s.spotLights)
+				s.spotLights = append(s.spotLights, l)
+			}
+		case LightPoint:
+			if len(s.pointLights) < MaxPointShadows {
+				s.index[l] = len(s.pointLights)
+				s.pointLights = append(s.pointLights, l)
+			}
+		}
+	}
+}
+
+// shadowIndex returns the shadow map l was assigned, or -1 if it casts no shadows this frame.
+func (s *shadowMaps) shadowIndex(l *Light) int {
+	if i, ok := s.index[l]; ok {
+		return i
+	}
+	return -1
+}
+
+// render draws the shadow maps of the assigned lights with w's programs. Cascades are fitted to the
+// camera with view and proj, drawn at aspect.
+func (s *shadowMaps) render(w *Window, view mgl32.Mat4, proj Projection, aspect float32) {
+	s.block = shadowBlock{
+		settings: [4]float32{s.quality.Bias, float32(s.quality.PCFRadius)},
+	}
+
+	if len(s.index) > 0 {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, s.fbo)
+		gl.Disable(gl.SCISSOR_TEST)
+		w.setDepthMode(false)
+		gl.Enable(gl.POLYGON_OFFSET_FILL)
+		gl.PolygonOffset(2, 4)
+
+		res := int32(s.quality.Resolution)
+		if s.directional != nil {
+			gl.Viewport(0, 0, res, res)
+			matrices := cascadeMatrices(s.directional.Direction(), view, proj, aspect, s.quality)
+			for i, m := range matrices {
+				s.block.cascadeMatrices[i] = m
+				s.pass(w, s.cascadeTex, i, depthPass{matrix: m})
+			}
+			s.block.settings[2] = float32(len(matrices))
+		}
+
+		gl.Viewport(0, 0, res/2, res/2)
+		for i, l := range s.spotLights {
+			m := spotShadowMatrix(l, s.quality.Distance)
+			s.block.spotMatrices[i] = m
+			s.pass(w, s.spotTex, i, depthPass{matrix: m})
+		}
+
+		gl.Viewport(0, 0, res/4, res/4)
+		for i, l := range s.pointLights {
+			loc, far := l.Location(), l.Range()
+			if far == 0 {
+				far = s.quality.Distance
+			}
+			s.block.pointFar[i] = far
+			for face, m := range pointShadowMatrices(loc, far) {
+				s.pass(w, s.pointTex, 6*i+face, depthPass{matrix: m, lightPosition: loc, far: far})
+			}
+		}
+
+		gl.Disable(gl.POLYGON_OFFSET_FILL)
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	}
+
+	// gl.Ptr rejects pointers to structs, so the block is passed as an unsafe.Pointer. go-gl receives it
+	// as one, so cgo checks the whole allocation it points into rather than just the field, and s holds
+	// Go pointers. A copy on its own holds none.
+	block := s.block
+	gl.BindBuffer(gl.UNIFORM_BUFFER, s.ubo)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, int(unsafe.Sizeof(block)), unsafe.Pointer(&block))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+}
+
+// pass draws every mesh of w that casts a shadow into layer of tex.
+func (s *shadowMaps) pass(w *Window, tex uint32, layer int, pass depthPass) {
+	gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, tex, 0, int32(layer))
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+
+	pass.frustum = newFrustum(pass.matrix, false)
+	for _, p := range w.programs {
+		p.drawDepth(s, pass)
+	}
+}
+
+// bind binds the shadow maps to their texture units for drawing.
+func (s *shadowMaps) bind() {
+	gl.ActiveTexture(gl.TEXTURE0 + cascadeShadowUnit)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, s.cascadeTex)
+	gl.ActiveTexture(gl.TEXTURE0 + spotShadowUnit)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, s.spotTex)
+	gl.ActiveTexture(gl.TEXTURE0 + pointShadowUnit)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP_ARRAY, s.pointTex)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// cascadeMatrices returns the light matrices of the cascades of a directional light shining in
+// direction, splitting the view of the camera with view and proj at aspect up to q.Distance.
+func cascadeMatrices(direction mgl32.Vec3, view mgl32.Mat4, proj Projection, aspect float32, q ShadowQuality) []mgl32.Mat4 {
+	near, far := proj.Near, q.Distance
+	if !proj.Infinite && proj.Far < far {
+		far = proj.Far
+	}
+	if near <= 0 || far <= near {
+		return nil
+	}
+
+	dir := direction.Normalize()
+	up := shadowUp(dir)
+	inverse := view.Inv()
+	res := float32(q.Resolution)
+
+	matrices := make([]mgl32.Mat4, 0, q.Cascades)
+	start := near
+	for i := 1; i <= q.Cascades; i++ {
+		f := float64(i) / float64(q.Cascades)
+		logSplit := float64(near) * math.Pow(float64(far/near), f)
+		evenSplit := float64(near) + float64(far-near)*f
+		end := float32(cascadeSplitLambda*logSplit + (1-cascadeSplitLambda)*evenSplit)
+
+		// Fit a sphere rather than a box around the slice of the view, so the cascade keeps its size, and
+		// its shadows don't shimmer, as the camera turns.
+		corners := sliceCorners(inverse, proj, aspect, start, end)
+		var center mgl32.Vec3
+		for _, c := range corners {
+			center = center.Add(c)
+		}
+		center = center.Mul(1 / float32(len(corners)))
+		var radius float32
+		for _, c := range corners {
+			radius = float32(math.Max(float64(radius), float64(c.Sub(center).Len())))
+		}
+		radius = float32(math.Ceil(float64(radius)*16) / 16)
+
+		// Back the light away past the slice so bodies between it and the slice still cast shadows
+		// into it.
+		eye := center.Sub(dir.Mul(radius + q.Distance))
+		m := mgl32.Ortho(-radius, radius, -radius, radius, 0, 2*radius+q.Distance).Mul4(mgl32.LookAtV(eye, center, up))
+
+		// Snap the cascade to whole texels so its shadows don't shimmer as the camera moves.
+		origin := m.Mul4x1(mgl32.Vec4{0, 0, 0, 1}).Mul(res / 2)
+		m[12] += (float32(math.Round(float64(origin[0]))) - origin[0]) * 2 / res
+		m[13] += (float32(math.Round(float64(origin[1]))) - origin[1]) * 2 / res
+
+		matrices = append(matrices, m)
+		start = end
+	}
+	return matrices
+}
+
+// sliceCorners returns the world space corners of the part of the view of a camera, with the inverse
+// of its view matrix and proj at aspect, from near to far in front of it.
+func sliceCorners(inverse mgl32.Mat4, proj Projection, aspect, near, far float32) []mgl32.Vec3 {
+	corners := make([]mgl32.Vec3, 0, 8)
+	for _, d := range []float32{near, far} {
+		halfHeight := proj.Height / 2
+		if !proj.Orthographic {
+			halfHeight = d * float32(math.Tan(float64(proj.FOV/2)))
+		}
+		halfWidth := halfHeight * aspect
+		for _, x := range []float32{-halfWidth, halfWidth} {
+			for _, y := range []float32{-halfHeight, halfHeight} {
+				corners = append(corners, mgl32.TransformCoordinate(mgl32.Vec3{x, y, -d}, inverse))
+			}
+		}
+	}
+	return corners
+}
+
+// spotShadowMatrix returns the light matrix of the shadow of spot light l, reaching distance if l has
+// no range.
+func spotShadowMatrix(l *Light, distance float32) mgl32.Mat4 {
+	far := l.Range()
+	if far == 0 {
+		far = distance
+	}
+	_, outer := l.Cone()
+	fov := float32(math.Min(float64(2*outer+spotShadowMargin), float64(mgl32.DegToRad(170))))
+
+	loc, dir := l.Location(), l.Direction()
+	return mgl32.Perspective(fov, 1, shadowNear, far).Mul4(mgl32.LookAtV(loc, loc.Add(dir), shadowUp(dir)))
+}
+
+// pointShadowMatrices returns the light matrices of the six faces of the shadow cube of a point light
+// at loc reaching far, in the order of the faces of a cube map.
+func pointShadowMatrices(loc mgl32.Vec3, far float32) [6]mgl32.Mat4 {
+	faces := [6][2]mgl32.Vec3{
+		{{1, 0, 0}, {0, -1, 0}},
+		{{-1, 0, 0}, {0, -1, 0}},
+		{{0, 1, 0}, {0, 0, 1}},
+		{{0, -1, 0}, {0, 0, -1}},
+		{{0, 0, 1}, {0, -1, 0}},
+		{{0, 0, -1}, {0, -1, 0}},
+	}
+
+	proj := mgl32.Perspective(math.Pi/2, 1, shadowNear, far)
+	var matrices [6]mgl32.Mat4
+	for i, f := range faces {
+		matrices[i] = proj.Mul4(mgl32.LookAtV(loc, loc.Add(f[0]), f[1]))
+	}
+	return matrices
+}
+
+// shadowUp returns an up direction for a light shining in dir that isn't parallel to it.
+func shadowUp(dir mgl32.Vec3) mgl32.Vec3 {
+	if math.Abs(float64(dir.Normalize().Y())) > 0.99 {
+		return mgl32.Vec3{0, 0, 1}
+	}
+	return mgl32.Vec3{0, 1, 0}
+}
+
+// ShadowQuality returns the quality of the shadows of w.
+func (w *Window) ShadowQuality() ShadowQuality {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.shadowQuality
+}
+
+// SetShadowQuality sets the quality of the shadows of w. The shadow maps are replaced before the next
+// frame.
+func (w *Window) SetShadowQuality(q ShadowQuality) {
+	q = q.clamp()
+	w.mut.Lock()
+	w.shadowQuality = q
+	w.mut.Unlock()
+
+	w.Do(func() {
+		w.shadows.setQuality(q)
+	})
+}
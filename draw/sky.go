@@ -0,0 +1,395 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Sky is the background of a window, drawn behind everything else as if infinitely far away.
+type Sky struct {
+	texture uint32
+	// small is the sky at the size environments are filtered from.
+	small cubeImage
+}
+
+// Starfield describes a procedurally generated sky of stars and nebulae. The same seed always generates
+// the same sky.
+type Starfield struct {
+	Seed int64
+	// Stars is the number of stars, and StarBrightness scales how bright they are.
+	Stars          int
+	StarBrightness float32
+	// Nebula is how bright the clouds of gas between the stars are, or zero for none. Their color fades
+	// between NebulaColors.
+	Nebula       float32
+	NebulaColors [2]mgl32.Vec3
+	// Size is the size of the faces of the sky's cube map.
+	Size int
+}
+
+// DefaultStarfield is a sky of faint purple and blue nebulae behind thousands of stars.
+var DefaultStarfield = Starfield{
+	Seed:           1,
+	Stars:          6000,
+	StarBrightness: 1,
+	Nebula:         0.15,
+	NebulaColors:   [2]mgl32.Vec3{{0.35, 0.1, 0.5}, {0.05, 0.25, 0.5}},
+	Size:           512,
+}
+
+// NewSkybox creates a new sky from the six images at faces of a cube map, in the order +x, -x, +y, -y,
+// +z, -z, oriented as OpenGL cube maps are. Faces must be square and the same size.
+//
+// Like textures, skies must be created on the thread running the window's loop.
+func NewSkybox(faces [6]string) (*Sky, error) {
+	var cube cubeImage
+	size := 0
+	for i, path := range faces {
+		img, err := loadImage(path)
+		if err != nil {
+			return nil, fmt.Errorf("load skybox: %v", err)
+		}
+		s := img.Bounds().Size()
+		if s.X != s.Y || (size != 0 && s.X != size) {
+			return nil, fmt.Errorf("load skybox: face %s is %dx%d, not square and the size of the others", path, s.X, s.Y)
+		}
+		size = s.X
+		cube[i] = resampleFace(img, size)
+	}
+	return newSky(cube), nil
+}
+
+// NewEquirectSky creates a new sky from the equirectangular image at path, which wraps around the sky
+// horizontally with up at the top, as panoramas are usually stored.
+//
+// Like textures, skies must be created on the thread running the window's loop.
+func NewEquirectSky(path string) (*Sky, error) {
+	img, err := loadImage(path)
+	if err != nil {
+		return nil, fmt.Errorf("load sky: %v", err)
+	}
+	b := img.Bounds()
+	pano := cubeFace{size: b.Dx(), pix: make([]mgl32.Vec3, b.Dx()*b.Dy())}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			pano.pix[y*b.Dx()+x] = linearColor(img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	height := b.Dy()
+
+	// A quarter of the panorama's width covers each face.
+	size := b.Dx() / 4
+	if size < 1 {
+		size = 1
+	}
+	var cube cubeImage
+	for face := range cube {
+		cube[face] = cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				d := cubeDirection(face, x, y, size)
+				u := 0.5 + math.Atan2(float64(d[0]), float64(-d[2]))/(2*math.Pi)
+				v := math.Acos(float64(mgl32.Clamp(d[1], -1, 1))) / math.Pi
+				cube[face].pix[y*size+x] = equirectAt(pano, height, float32(u), float32(v))
+			}
+		}
+	}
+	return newSky(cube), nil
+}
+
+// NewStarfieldSky creates a new procedurally generated sky of stars and nebulae.
+//
+// Like textures, skies must be created on the thread running the window's loop.
+func NewStarfieldSky(s Starfield) *Sky {
+	size := s.Size
+	if size <= 0 {
+		size = DefaultStarfield.Size
+	}
+	r := rand.New(rand.NewSource(s.Seed))
+
+	// Nebulae are smooth, so generate them small and let filtering spread them over the sky.
+	nebulaSize := size / 4
+	if nebulaSize < 1 {
+		nebulaSize = 1
+	}
+	density := newNoise(r)
+	tint := newNoise(r)
+	var nebula cubeImage
+	for face := range nebula {
+		nebula[face] = cubeFace{size: nebulaSize, pix: make([]mgl32.Vec3, nebulaSize*nebulaSize)}
+		if s.Nebula <= 0 {
+			continue
+		}
+		for y := 0; y < nebulaSize; y++ {
+			for x := 0; x < nebulaSize; x++ {
+				d := cubeDirection(face, x, y, nebulaSize)
+				clouds := smoothstep(0.45, 0.85, density.fbm(d.Mul(2), 5))
+				mix := smoothstep(0.3, 0.7, tint.fbm(d.Mul(3), 3))
+				c := s.NebulaColors[0].Mul(1 - mix).Add(s.NebulaColors[1].Mul(mix))
+				nebula[face].pix[y*nebulaSize+x] = c.Mul(clouds * s.Nebula)
+			}
+		}
+	}
+
+	var cube cubeImage
+	for face := range cube {
+		cube[face] = cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				cube[face].pix[y*size+x] = nebula.sample(cubeDirection(face, x, y, size))
+			}
+		}
+	}
+
+	for i := 0; i < s.Stars; i++ {
+		dir := mgl32.Vec3{float32(r.NormFloat64()), float32(r.NormFloat64()), float32(r.NormFloat64())}
+		if dir.Len() < 1e-6 {
+			continue
+		}
+		// Most stars are faint, and a few are very bright.
+		brightness := s.StarBrightness * (0.05 + 4*float32(math.Pow(r.Float64(), 12)))
+		warm := float32(r.Float64())
+		color := mgl32.Vec3{0.7, 0.8, 1}.Mul(1 - warm).Add(mgl32.Vec3{1, 0.85, 0.6}.Mul(warm))
+		cube.splat(dir.Normalize(), color.Mul(brightness))
+	}
+
+	return newSky(cube)
+}
+
+// newSky uploads cube as a new sky.
+func newSky(cube cubeImage) *Sky {
+	small := cube
+	for small.size() > environmentSize {
+		small = small.downsample()
+	}
+	return &Sky{
+		texture: newCubeTexture([]cubeImage{cube}),
+		small:   small,
+	}
+}
+
+// Environment creates a new environment from s, so the PBR program lights meshes with the light of the
+// sky.
+func (s *Sky) Environment() *Environment {
+	small := s.small
+	if small.size() < environmentSize {
+		small = resampleCube(small, environmentSize)
+	}
+	return newEnvironment(small)
+}
+
+// Sky returns the sky drawn behind w's scene, or nil if it is cleared to a plain color.
+func (w *Window) Sky() *Sky {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.sky
+}
+
+// SetSky sets the sky drawn behind w's scene. With no sky, w is cleared to a plain color.
+func (w *Window) SetSky(s *Sky) {
+	w.mut.Lock()
+	w.sky = s
+	w.mut.Unlock()
+}
+
+// skyProgram draws skies.
+type skyProgram struct {
+	id                      uint32
+	vao                     uint32
+	inverseViewProjectionID int32
+}
+
+func newSkyProgram(vertShaderPath, fragShaderPath string) skyProgram {
+
+	vertSource, err := readShader(vertShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
+	}
+
+	fragSource, err := readShader(fragShaderPath)
+	if err != nil {
+		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
+	}
+
+	vertexShader, err := compileShader(string(vertSource)+"\x00", gl.VERTEX_SHADER)
+	if err != nil {
+		panic("compile vertex shader: " + err.Error())
+	}
+
+	fragmentShader, err := compileShader(string(fragSource)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic("compile fragment shader: " + err.Error())
+	}
+
+	id := gl.CreateProgram()
+
+	gl.AttachShader(id, vertexShader)
+	gl.AttachShader(id, fragmentShader)
+	gl.LinkProgram(id)
+
+	var status int32
+	gl.GetProgramiv(id, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(id, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(id, logLength, nil, gl.Str(log))
+
+		panic(fmt.Sprintf("link program: %v", log))
+	}
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
+
+	// The sky is drawn from a triangle made by the vertex shader alone, but a vertex array must still be
+	// bound to draw it.
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+
+	return skyProgram{
+		id:                      id,
+		vao:                     vao,
+		inverseViewProjectionID: gl.GetUniformLocation(id, gl.Str("inverseViewProjection\x00")),
+	}
+}
+
+// draw draws s behind everything in the current viewport, seen with view and proj. The sky isn't
+// clipped by the projection's near and far planes, and ignores the camera's location.
+func (p skyProgram) draw(s *Sky, view mgl32.Mat4, proj Projection, aspect float32) {
+	fov := proj.FOV
+	if proj.Orthographic || fov <= 0 {
+		// Orthographic views have no field of view, so show the sky as a wide perspective would.
+		fov = math.Pi / 2
+	}
+	rotation := view.Mat3().Mat4()
+	inverse := mgl32.Perspective(fov, aspect, 1, 2).Mul4(rotation).Inv()
+
+	gl.Disable(gl.DEPTH_TEST)
+	gl.DepthMask(false)
+
+	gl.UseProgram(p.id)
+	gl.UniformMatrix4fv(p.inverseViewProjectionID, 1, false, &inverse[0])
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, s.texture)
+	gl.BindVertexArray(p.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+
+	gl.DepthMask(true)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// splat adds color to c as a point in direction dir, spread over the texels around it so it keeps its
+// position between them.
+func (c cubeImage) splat(dir mgl32.Vec3, color mgl32.Vec3) {
+	face, s, t := cubeCoords(dir)
+	f := c[face]
+	x := s*float32(f.size) - 0.5
+	y := t*float32(f.size) - 0.5
+	x0, y0 := int(math.Floor(float64(x))), int(math.Floor(float64(y)))
+	fx, fy := x-float32(x0), y-float32(y0)
+
+	add := func(x, y int, weight float32) {
+		if x < 0 || y < 0 || x >= f.size || y >= f.size {
+			return
+		}
+		f.pix[y*f.size+x] = f.pix[y*f.size+x].Add(color.Mul(weight))
+	}
+	add(x0, y0, (1-fx)*(1-fy))
+	add(x0+1, y0, fx*(1-fy))
+	add(x0, y0+1, (1-fx)*fy)
+	add(x0+1, y0+1, fx*fy)
+}
+
+// resampleCube returns c at size, filtering bilinearly.
+func resampleCube(c cubeImage, size int) cubeImage {
+	var out cubeImage
+	for face := range out {
+		out[face] = cubeFace{size: size, pix: make([]mgl32.Vec3, size*size)}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				out[face].pix[y*size+x] = c.sample(cubeDirection(face, x, y, size))
+			}
+		}
+	}
+	return out
+}
+
+// equirectAt returns the color of the equirectangular image pano, height pixels high, at u, v from zero
+// to one, filtering bilinearly and wrapping around horizontally.
+func equirectAt(pano cubeFace, height int, u, v float32) mgl32.Vec3 {
+	width := pano.size
+	x := u*float32(width) - 0.5
+	y := v*float32(height) - 0.5
+	x0, y0 := int(math.Floor(float64(x))), int(math.Floor(float64(y)))
+	fx, fy := x-float32(x0), y-float32(y0)
+
+	at := func(x, y int) mgl32.Vec3 {
+		x = ((x % width) + width) % width
+		y = clampInt(y, 0, height-1)
+		return pano.pix[y*width+x]
+	}
+	top := at(x0, y0).Mul(1 - fx).Add(at(x0+1, y0).Mul(fx))
+	bottom := at(x0, y0+1).Mul(1 - fx).Add(at(x0+1, y0+1).Mul(fx))
+	return top.Mul(1 - fy).Add(bottom.Mul(fy))
+}
+
+// noise is seeded three dimensional value noise.
+type noise struct {
+	perm [512]int
+}
+
+// newNoise creates new noise seeded from r.
+func newNoise(r *rand.Rand) *noise {
+	n := &noise{}
+	for i, p := range r.Perm(256) {
+		n.perm[i] = p
+		n.perm[i+256] = p
+	}
+	return n
+}
+
+// at returns the noise at p, from zero to one.
+func (n *noise) at(p mgl32.Vec3) float32 {
+	fx, fy, fz := math.Floor(float64(p[0])), math.Floor(float64(p[1])), math.Floor(float64(p[2]))
+	x, y, z := int(fx)&255, int(fy)&255, int(fz)&255
+	tx, ty, tz := p[0]-float32(fx), p[1]-float32(fy), p[2]-float32(fz)
+	tx, ty, tz = tx*tx*(3-2*tx), ty*ty*(3-2*ty), tz*tz*(3-2*tz)
+
+	corner := func(dx, dy, dz int) float32 {
+		return float32(n.perm[n.perm[n.perm[x+dx]+y+dy]+z+dz]) / 255
+	}
+	lerp := func(a, b, t float32) float32 {
+		return a + (b-a)*t
+	}
+	return lerp(
+		lerp(lerp(corner(0, 0, 0), corner(1, 0, 0), tx), lerp(corner(0, 1, 0), corner(1, 1, 0), tx), ty),
+		lerp(lerp(corner(0, 0, 1), corner(1, 0, 1), tx), lerp(corner(0, 1, 1), corner(1, 1, 1), tx), ty),
+		tz)
+}
+
+// fbm returns octaves of n at p added together, each at twice the detail and half the strength of the
+// last, from zero to one.
+func (n *noise) fbm(p mgl32.Vec3, octaves int) float32 {
+	var sum, total float32
+	amplitude := float32(1)
+	for i := 0; i < octaves; i++ {
+		sum += n.at(p) * amplitude
+		total += amplitude
+		amplitude /= 2
+		p = p.Mul(2)
+	}
+	return sum / total
+}
+
+func smoothstep(edge0, edge1, x float32) float32 {
+	t := mgl32.Clamp((x-edge0)/(edge1-edge0), 0, 1)
+	return t * t * (3 - 2*t)
+}
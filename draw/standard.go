@@ -2,7 +2,6 @@ package draw
 
 import (
 	"fmt"
-	"io/ioutil"
 	"strings"
 	"sync"
 
@@ -21,24 +20,26 @@ type StandardProgram struct {
 	TextureLocID uint32
 	VertexID     uint32
 	NormalID     uint32
+	TangentID    uint32
 
 	viewMut       sync.Mutex
 	view          mgl32.Mat4
 	camPosition   mgl32.Vec3
 	projectionMut sync.Mutex
 	projection    mgl32.Mat4
+	materialIDs   materialIDs
 	meshesMut     sync.Mutex
 	meshes        map[*Mesh]struct{}
 }
 
 func newStandardProgram(vertShaderPath, fragShaderPath string) *StandardProgram {
 
-	vertSource, err := ioutil.ReadFile(vertShaderPath)
+	vertSource, err := readShader(vertShaderPath)
 	if err != nil {
 		panic(fmt.Sprintf("open vertex shader %s: %v", vertShaderPath, err))
 	}
 
-	fragSource, err := ioutil.ReadFile(fragShaderPath)
+	fragSource, err := readShader(fragShaderPath)
 	if err != nil {
 
 		panic(fmt.Sprintf("open fragment shader %s: %v", fragShaderPath, err))
@@ -75,6 +76,9 @@ func newStandardProgram(vertShaderPath, fragShaderPath string) *StandardProgram
 	gl.DeleteShader(vertexShader)
 	gl.DeleteShader(fragmentShader)
 
+	bindLights(id)
+	bindShadows(id)
+
 	p := &StandardProgram{
 		ID:            id,
 		ProjectionID:  gl.GetUniformLocation(id, gl.Str("projection\x00")),
@@ -85,13 +89,15 @@ func newStandardProgram(vertShaderPath, fragShaderPath string) *StandardProgram
 		VertexID:      uint32(gl.GetAttribLocation(id, gl.Str("vert\x00"))),
 		TextureLocID:  uint32(gl.GetAttribLocation(id, gl.Str("vertTexCoord\x00"))),
 		NormalID:      uint32(gl.GetAttribLocation(id, gl.Str("vertNormal\x00"))),
+		TangentID:     uint32(gl.GetAttribLocation(id, gl.Str("vertTangent\x00"))),
 
 		projection: mgl32.Ident4(),
 		view:       mgl32.Ident4(),
 		meshes:     make(map[*Mesh]struct{}),
+
+		materialIDs: newMaterialIDs(id),
 	}
 
-	gl.Uniform1i(p.TextureID, 0)
 	gl.BindFragDataLocation(id, 0, gl.Str("outputColor\x00"))
 
 	return p
@@ -112,35 +118,71 @@ func (p *StandardProgram) setProjection(projection mgl32.Mat4) {
 
 func (p *StandardProgram) Draw(state *GLState) {
 
+	p.begin(state)
+
+	p.meshesMut.Lock()
+	for mesh := range p.meshes {
+		if state.cull(mesh) || state.deferBlended(mesh) {
+			continue
+		}
+		p.drawMesh(mesh, state)
+	}
+	p.meshesMut.Unlock()
+}
+
+// begin makes p the current program, seen from its view.
+func (p *StandardProgram) begin(state *GLState) {
 	p.use()
 
 	gl.UniformMatrix4fv(p.CameraID, 1, false, &p.view[0])
 	gl.UniformMatrix4fv(p.ProjectionID, 1, false, &p.projection[0])
 	gl.Uniform3fv(p.CamPositionID, 1, &p.camPosition[0])
+}
+
+// drawMesh draws mesh with p, which begin must have made the current program.
+func (p *StandardProgram) drawMesh(mesh *Mesh, state *GLState) {
+	mesh.Draw(state)
+}
+
+// drawDepth draws the meshes of p that are in view of pass into a shadow map.
+func (p *StandardProgram) drawDepth(s *shadowMaps, pass depthPass) {
+	s.standard.use(pass)
 
 	p.meshesMut.Lock()
 	for mesh := range p.meshes {
-		mesh.Draw(state)
+		if mesh.hidden || mesh.blended() || !pass.frustum.containsMesh(mesh) {
+			continue
+		}
+		mesh.drawDepth(s.standard.modelID)
 	}
 	p.meshesMut.Unlock()
 }
 
+func (p *StandardProgram) getMaterialIDs() materialIDs {
+	return p.materialIDs
+}
+
 func (p *StandardProgram) GetModelID() int32 {
 	return p.ModelID
 }
 
-func (p *StandardProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3) *Mesh {
+func (p *StandardProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, tangents []mgl32.Vec3) *Mesh {
 
 	mesh := &Mesh{
 		count:    int32(len(faces) * 3),
 		program:  p,
 		rotation: mgl32.QuatIdent(),
+		material: NewMaterial(""),
 
 		// Save references to data so it won't get garbage-collected prematurely
 		vertexes: vertexes,
+		faces:    faces,
 		uvCoords: uvCoords,
 		normals:  normals,
+		tangents: meshTangents(vertexes, faces, uvCoords, normals, tangents),
+		bounds:   newBounds(vertexes),
 	}
+	mesh.radius = mesh.bounds.Radius()
 
 	gl.GenVertexArrays(1, &mesh.vao)
 	gl.BindVertexArray(mesh.vao)
@@ -178,6 +220,14 @@ func (p *StandardProgram) NewMesh(vertexes []mgl32.Vec3, faces []MeshFace, uvCoo
 	gl.EnableVertexAttribArray(p.NormalID)
 	gl.VertexAttribPointer(p.NormalID, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
 
+	gl.GenBuffers(1, &mesh.tangentVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.tangentVBO)
+	// buffer type - length in bytes - data pointer - draw type
+	gl.BufferData(gl.ARRAY_BUFFER, len(mesh.tangents)*4*4, gl.Ptr(mesh.tangents), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(p.TangentID)
+	gl.VertexAttribPointer(p.TangentID, 4, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
+
 	p.meshesMut.Lock()
 	p.meshes[mesh] = struct{}{}
 	p.meshesMut.Unlock()
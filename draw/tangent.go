@@ -0,0 +1,72 @@
+package draw
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// meshTangents returns the tangent of each vertex of a mesh, which with its normal and bitangent makes
+// the tangent space normal maps are drawn in. The tangent points along the u axis of the mesh's texture
+// coordinates, and its w holds the handedness of the bitangent along the v axis, which shaders rebuild
+// as cross(normal, tangent.xyz) * tangent.w.
+//
+// Tangents are generated from the texture coordinates of faces, unless loaded holds a tangent for each
+// vertex, as model loaders often provide. Either way they are made perpendicular to the normals.
+func meshTangents(vertexes []mgl32.Vec3, faces []MeshFace, uvCoords []mgl32.Vec2, normals []mgl32.Vec3, loaded []mgl32.Vec3) []mgl32.Vec4 {
+	tangents := make([]mgl32.Vec3, len(vertexes))
+	bitangents := make([]mgl32.Vec3, len(vertexes))
+
+	if len(uvCoords) >= len(vertexes) {
+		for _, f := range faces {
+			p0, p1, p2 := vertexes[f[0]], vertexes[f[1]], vertexes[f[2]]
+			uv0, uv1, uv2 := uvCoords[f[0]], uvCoords[f[1]], uvCoords[f[2]]
+
+			e1, e2 := p1.Sub(p0), p2.Sub(p0)
+			d1, d2 := uv1.Sub(uv0), uv2.Sub(uv0)
+			r := d1.X()*d2.Y() - d2.X()*d1.Y()
+			if math.Abs(float64(r)) < 1e-12 {
+				// The face has no area in texture space, so its texture has no direction on it.
+				continue
+			}
+			t := e1.Mul(d2.Y()).Sub(e2.Mul(d1.Y())).Mul(1 / r)
+			b := e2.Mul(d1.X()).Sub(e1.Mul(d2.X())).Mul(1 / r)
+			for _, i := range f {
+				tangents[i] = tangents[i].Add(t)
+				bitangents[i] = bitangents[i].Add(b)
+			}
+		}
+	}
+	if len(loaded) == len(vertexes) {
+		copy(tangents, loaded)
+	}
+
+	out := make([]mgl32.Vec4, len(vertexes))
+	for i, t := range tangents {
+		n := mgl32.Vec3{0, 0, 1}
+		if i < len(normals) && normals[i].Len() > 1e-6 {
+			n = normals[i].Normalize()
+		}
+
+		t = t.Sub(n.Mul(n.Dot(t)))
+		if t.Len() < 1e-6 {
+			t = perpendicular(n)
+		}
+		t = t.Normalize()
+
+		w := float32(1)
+		if n.Cross(t).Dot(bitangents[i]) < 0 {
+			w = -1
+		}
+		out[i] = t.Vec4(w)
+	}
+	return out
+}
+
+// perpendicular returns a unit vector perpendicular to the unit vector n.
+func perpendicular(n mgl32.Vec3) mgl32.Vec3 {
+	if math.Abs(float64(n.X())) < 0.9 {
+		return n.Cross(mgl32.Vec3{1, 0, 0}).Normalize()
+	}
+	return n.Cross(mgl32.Vec3{0, 1, 0}).Normalize()
+}
@@ -0,0 +1,84 @@
+package draw
+
+import (
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Viewport is a rectangle of a window that the scene is drawn into from its own point of view. Each
+// window starts with a main viewport covering all of it, and more can be added with Window.AddViewport
+// for split-screen, picture-in-picture or editor panes.
+//
+// All Viewport functions are safe to use concurrently.
+type Viewport struct {
+	mut                 sync.Mutex
+	x, y, width, height float32
+	view                mgl32.Mat4
+	camPosition         mgl32.Vec3
+	projection          Projection
+}
+
+// NewViewport creates a new Viewport covering the rectangle with its bottom left corner at x, y and the
+// specified width and height, all as fractions of the size of the window it is added to.
+func NewViewport(x, y, width, height float32) *Viewport {
+	return &Viewport{
+		x:          x,
+		y:          y,
+		width:      width,
+		height:     height,
+		view:       mgl32.Ident4(),
+		projection: DefaultProjection,
+	}
+}
+
+// Rect returns the bottom left corner and size of v as fractions of the size of its window.
+func (v *Viewport) Rect() (x, y, width, height float32) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	return v.x, v.y, v.width, v.height
+}
+
+// SetRect sets the bottom left corner and size of v as fractions of the size of its window.
+func (v *Viewport) SetRect(x, y, width, height float32) {
+	v.mut.Lock()
+	v.x, v.y, v.width, v.height = x, y, width, height
+	v.mut.Unlock()
+}
+
+// SetView sets the view matrix and camera position v is drawn with.
+func (v *Viewport) SetView(view mgl32.Mat4, camPosition mgl32.Vec3) {
+	v.mut.Lock()
+	v.view = view
+	v.camPosition = camPosition
+	v.mut.Unlock()
+}
+
+// Projection returns the projection v is drawn with.
+func (v *Viewport) Projection() Projection {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	return v.projection
+}
+
+// SetProjection sets the projection v is drawn with.
+func (v *Viewport) SetProjection(p Projection) {
+	v.mut.Lock()
+	v.projection = p
+	v.mut.Unlock()
+}
+
+// pixels returns the rectangle of v in a window of the specified size, in pixels.
+func (v *Viewport) pixels(width, height int) (x, y, w, h int32) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	return int32(v.x * float32(width)), int32(v.y * float32(height)),
+		int32(v.width * float32(width)), int32(v.height * float32(height))
+}
+
+// state returns the view, camera position and projection of v.
+func (v *Viewport) state() (mgl32.Mat4, mgl32.Vec3, Projection) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	return v.view, v.camPosition, v.projection
+}
@@ -1,60 +1,79 @@
 package draw
 
 import (
-	"fmt"
-	"log"
+	"image"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
 type Window struct {
 	pause         chan bool
 	close         chan struct{}
+	sizeMut       sync.Mutex
 	width, height int
-	window        *glfw.Window
+	clipControl   bool
+	platform
+	output        uint32
 	programs      map[ProgramType]Program
 	mut           sync.Mutex
-	view          mgl32.Mat4
-	camPosition   mgl32.Vec3
+	viewports     []*Viewport
+	sky           *Sky
+	skyProgram    skyProgram
+	emittersMut   sync.Mutex
+	emitters      []*Emitter
+	particles     particleProgram
+	post          *postProcessor
+	postSettings  PostSettings
+	overlay       *Overlay
+	debug         *DebugDraw
+	start         time.Time
+	stats         RenderStats
+	lightsMut     sync.Mutex
+	lights        []*Light
+	ambient       mgl32.Vec3
+	environment   *Environment
+	lightUBO      uint32
+	shadows       *shadowMaps
+	shadowQuality ShadowQuality
+	tasksMut      sync.Mutex
+	tasks         []func()
 }
 
-func NewWindow(width, height int) *Window {
-
-	if err := glfw.Init(); err != nil {
-		log.Fatalf("failed to initialize glfw: %v", err)
-	}
-
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-
-	window, err := glfw.CreateWindow(width, height, "Cube", nil, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	window.MakeContextCurrent()
-
-	if err := gl.Init(); err != nil {
-		log.Fatal(err)
-	}
-
+// newWindow sets up drawing in the current context, presenting frames to the framebuffer output.
+func newWindow(output uint32) *Window {
 	w := &Window{
-		pause:  make(chan bool),
-		close:  make(chan struct{}),
-		width:  width,
-		height: height,
-		window: window,
+		pause:       make(chan bool),
+		close:       make(chan struct{}),
+		output:      output,
+		clipControl: hasClipControl(),
+		ambient:     DefaultAmbient,
+		lightUBO:    newLightBuffer(),
+		start:       time.Now(),
 	}
+	w.viewports = []*Viewport{NewViewport(0, 0, 1, 1)}
 
 	w.programs = map[ProgramType]Program{
 		ProgramTypeStandard: newStandardProgram("shaders/shader.vert", "shaders/shader.frag"),
 		ProgramTypeBoned:    newBoneProgram("shaders/bones.vert", "shaders/bones.frag"),
+		ProgramTypePBR:      newPBRProgram("shaders/pbr.vert", "shaders/pbr.frag"),
 	}
+	w.skyProgram = newSkyProgram("shaders/sky.vert", "shaders/sky.frag")
+	w.particles = newParticleProgram("shaders/particle.vert", "shaders/particle.frag")
+	w.post = newPostProcessor()
+	w.postSettings = DefaultPostSettings.clamp()
+	w.overlay = newOverlay()
+	w.debug = newDebugDraw()
+	w.shadowQuality = DefaultShadowQuality.clamp()
+	w.shadows = newShadowMaps(w.GetStandardProgram(), w.GetBoneProgram(), w.GetPBRProgram(), w.shadowQuality)
+
+	// Configure global settings
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+	gl.ClearColor(.5, .5, .5, .5)
 
 	return w
 }
@@ -82,55 +101,207 @@ func (w *Window) GetStandardProgram() *StandardProgram {
 	return w.programs[ProgramTypeStandard].(*StandardProgram)
 }
 
+// GetPBRProgram returns the PBR program of w
+func (w *Window) GetPBRProgram() *PBRProgram {
+	return w.programs[ProgramTypePBR].(*PBRProgram)
+}
+
+// GetHeight returns the height of w's framebuffer in pixels.
 func (w *Window) GetHeight() int {
+	w.sizeMut.Lock()
+	defer w.sizeMut.Unlock()
 	return w.height
 }
 
+// GetWidth returns the width of w's framebuffer in pixels.
 func (w *Window) GetWidth() int {
+	w.sizeMut.Lock()
+	defer w.sizeMut.Unlock()
 	return w.width
 }
 
+// SetView sets the view matrix and camera position of w's main viewport.
 func (w *Window) SetView(view mgl32.Mat4, camPosition mgl32.Vec3) {
+	w.MainViewport().SetView(view, camPosition)
+}
+
+// SetProjection sets the projection of w's main viewport.
+func (w *Window) SetProjection(p Projection) {
+	w.MainViewport().SetProjection(p)
+}
+
+// hasClipControl reports whether the current context supports glClipControl, which reversed-Z
+// projections need.
+func hasClipControl() bool {
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	if major > 4 || (major == 4 && minor >= 5) {
+		return true
+	}
+
+	var count int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+	for i := uint32(0); i < uint32(count); i++ {
+		if strings.TrimSpace(gl.GoStr(gl.GetStringi(gl.EXTENSIONS, i))) == "GL_ARB_clip_control" {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the number of meshes drawn and culled in the last frame.
+func (w *Window) Stats() RenderStats {
 	w.mut.Lock()
-	w.view = view
-	w.camPosition = camPosition
-	w.mut.Unlock()
+	defer w.mut.Unlock()
+	return w.stats
+}
+
+// MainViewport returns the viewport w starts with, which covers all of w unless it is resized.
+func (w *Window) MainViewport() *Viewport {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.viewports[0]
+}
+
+// AddViewport adds v to the viewports w is drawn into. Viewports are drawn in the order they are added,
+// so later viewports cover earlier ones where they overlap. If v has already been added, AddViewport
+// has no effect.
+func (w *Window) AddViewport(v *Viewport) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	for _, existing := range w.viewports {
+		if existing == v {
+			return
+		}
+	}
+	w.viewports = append(w.viewports, v)
 }
 
-func (w *Window) Loop(keyCallback glfw.KeyCallback, mouseButtonCallback glfw.MouseButtonCallback, cursorPosCallback glfw.CursorPosCallback) {
+// RemoveViewport removes v from the viewports w is drawn into. The main viewport can't be removed.
+func (w *Window) RemoveViewport(v *Viewport) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	for i, existing := range w.viewports[1:] {
+		if existing == v {
+			w.viewports = append(w.viewports[:i+1], w.viewports[i+2:]...)
+			return
+		}
+	}
+}
 
-	defer glfw.Terminate()
+// Do queues f to run on the thread running w's loop before the next frame is drawn. Anything that calls
+// OpenGL from another goroutine, such as creating meshes or textures, must be run with Do.
+func (w *Window) Do(f func()) {
+	w.tasksMut.Lock()
+	w.tasks = append(w.tasks, f)
+	w.tasksMut.Unlock()
+}
 
-	w.window.SetKeyCallback(keyCallback)
-	w.window.SetMouseButtonCallback(mouseButtonCallback)
-	w.window.SetCursorPosCallback(cursorPosCallback)
+func (w *Window) runTasks() {
+	w.tasksMut.Lock()
+	tasks := w.tasks
+	w.tasks = nil
+	w.tasksMut.Unlock()
 
-	version := gl.GoStr(gl.GetString(gl.VERSION))
-	fmt.Println("OpenGL version", version)
+	for _, f := range tasks {
+		f()
+	}
+}
 
-	// Configure global settings
-	gl.Enable(gl.DEPTH_TEST)
-	gl.DepthFunc(gl.LESS)
-	gl.ClearColor(.5, .5, .5, .5)
+// RenderImage runs the functions queued with Do, then draws a frame of w and returns it. It's meant for
+// headless windows, which have no loop, and must be called from the thread that created w.
+func (w *Window) RenderImage() *image.RGBA {
+	w.runTasks()
+	w.drawFrame()
 
-	var glState GLState
+	width, height := w.GetWidth(), w.GetHeight()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 {
+		return img
+	}
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, w.output)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	pixels := make([]uint8, len(img.Pix))
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(pixels))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 4)
+
+	// OpenGL's rows start at the bottom. The overlay blends alpha into the framebuffer, but the frame
+	// is shown opaque.
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*img.Stride:(y+1)*img.Stride], pixels[(height-1-y)*img.Stride:])
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
 
-	projection := mgl32.Perspective(mgl32.DegToRad(45.0), float32(w.GetWidth())/float32(w.GetHeight()), 0.1, 500.0)
+// drawFrame draws a frame of w into its output framebuffer.
+func (w *Window) drawFrame() {
+	w.mut.Lock()
+	viewports := append([]*Viewport(nil), w.viewports...)
+	sky := w.sky
+	post := w.postSettings
+	w.mut.Unlock()
 
-	for !w.window.ShouldClose() {
+	glState := GLState{environment: w.Environment()}
+	winWidth, winHeight := w.GetWidth(), w.GetHeight()
+	if winWidth <= 0 || winHeight <= 0 {
+		// Minimized windows have no framebuffer to draw into.
+		return
+	}
 
-		w.waitIfPaused()
-		if w.shouldClose() {
-			break
+	// Draw shadows before anything else, fitting the cascades of directional shadows to the main
+	// viewport. Other viewports use the same shadows.
+	lights := w.frameLights()
+	w.shadows.assign(lights)
+	w.uploadLights(lights)
+	if _, _, width, height := viewports[0].pixels(winWidth, winHeight); width > 0 && height > 0 {
+		view, _, proj := viewports[0].state()
+		w.shadows.render(w, view, proj, float32(width)/float32(height))
+	}
+	w.shadows.bind()
+
+	// Particles are emitted once a frame and drawn the same in every viewport.
+	now := float32(time.Since(w.start).Seconds())
+	emitters := w.frameEmitters(now)
+	debug := w.debug.frame()
+
+	// Draw the scene offscreen in linear light, to be post-processed into the window.
+	w.post.resize(winWidth, winHeight)
+	w.post.bindScene()
+
+	// Clear buffer
+	gl.Disable(gl.SCISSOR_TEST)
+	gl.Viewport(0, 0, int32(winWidth), int32(winHeight))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	for i, v := range viewports {
+		x, y, width, height := v.pixels(winWidth, winHeight)
+		if width <= 0 || height <= 0 {
+			continue
+		}
+		view, camPosition, proj := v.state()
+		reversed := proj.ReversedZ && w.clipControl
+		w.setDepthMode(reversed)
+
+		// Clear only this viewport so it covers any viewports beneath it.
+		gl.Viewport(x, y, width, height)
+		gl.Enable(gl.SCISSOR_TEST)
+		gl.Scissor(x, y, width, height)
+		if i > 0 {
+			gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		} else {
+			gl.Clear(gl.DEPTH_BUFFER_BIT)
 		}
 
-		w.mut.Lock()
-		view := w.view
-		camPosition := w.camPosition
-		w.mut.Unlock()
+		if sky != nil {
+			w.skyProgram.draw(sky, view, proj, float32(width)/float32(height))
+		}
 
-		// Clear buffer
-		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		projection := proj.Matrix(float32(width)/float32(height), reversed)
+		glState.frustum = newFrustum(projection.Mul4(view), reversed)
 
 		for _, p := range w.programs {
 			p.setProjection(projection)
@@ -138,11 +309,37 @@ func (w *Window) Loop(keyCallback glfw.KeyCallback, mouseButtonCallback glfw.Mou
 
 			p.Draw(&glState)
 		}
+		glState.drawBlended(camPosition)
+		w.particles.draw(emitters, view, projection, now)
+	}
 
-		// Maintenance
-		w.window.SwapBuffers()
-		glfw.PollEvents()
+	w.post.run(post, w.output)
+	if debug != nil {
+		w.setDepthMode(false)
+		w.debug.draw(debug, viewports, winWidth, winHeight)
 	}
+	w.overlay.draw(winWidth, winHeight)
+
+	w.mut.Lock()
+	w.stats = glState.stats
+	w.mut.Unlock()
+}
+
+// setDepthMode sets up the depth buffer for a standard or reversed-Z projection. Reversed-Z stores
+// depth from 1 at the near plane to 0 at the far plane with glClipControl's zero to one range, which
+// w.clipControl must allow.
+func (w *Window) setDepthMode(reversed bool) {
+	if reversed {
+		gl.ClipControl(gl.LOWER_LEFT, gl.ZERO_TO_ONE)
+		gl.DepthFunc(gl.GREATER)
+		gl.ClearDepth(0)
+		return
+	}
+	if w.clipControl {
+		gl.ClipControl(gl.LOWER_LEFT, gl.NEGATIVE_ONE_TO_ONE)
+	}
+	gl.DepthFunc(gl.LESS)
+	gl.ClearDepth(1)
 }
 
 func (w *Window) waitIfPaused() {
@@ -0,0 +1,100 @@
+//go:build !egl
+// +build !egl
+
+package draw
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// platform is the GLFW window a Window draws into. Builds with the egl tag have headless windows
+// instead, so they don't need GLFW or a window system to build.
+type platform struct {
+	window *glfw.Window
+}
+
+func NewWindow(width, height int) *Window {
+
+	if err := glfw.Init(); err != nil {
+		log.Fatalf("failed to initialize glfw: %v", err)
+	}
+
+	glfw.WindowHint(glfw.Resizable, glfw.True)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window, err := glfw.CreateWindow(width, height, "Cube", nil, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	w := newWindow(0)
+	w.window = window
+
+	// Draw at the size of the framebuffer, which is larger than the window on high DPI screens, and keep
+	// following it as the window is resized.
+	w.width, w.height = window.GetFramebufferSize()
+	window.SetFramebufferSizeCallback(func(_ *glfw.Window, width, height int) {
+		w.sizeMut.Lock()
+		w.width, w.height = width, height
+		w.sizeMut.Unlock()
+	})
+
+	return w
+}
+
+// SetScrollCallback sets the function called when the mouse wheel or a touchpad is scrolled over w.
+// It must be called from the same thread as Loop.
+func (w *Window) SetScrollCallback(cb glfw.ScrollCallback) {
+	w.window.SetScrollCallback(cb)
+}
+
+// SetCursorCaptured sets whether the cursor is hidden and locked to w, so that it reports unbounded
+// movement such as for mouse-look.
+func (w *Window) SetCursorCaptured(captured bool) {
+	mode := glfw.CursorNormal
+	if captured {
+		mode = glfw.CursorDisabled
+	}
+	w.Do(func() {
+		w.window.SetInputMode(glfw.CursorMode, mode)
+	})
+}
+
+func (w *Window) Loop(keyCallback glfw.KeyCallback, mouseButtonCallback glfw.MouseButtonCallback, cursorPosCallback glfw.CursorPosCallback) {
+
+	defer glfw.Terminate()
+
+	w.window.SetKeyCallback(keyCallback)
+	w.window.SetMouseButtonCallback(mouseButtonCallback)
+	w.window.SetCursorPosCallback(cursorPosCallback)
+
+	version := gl.GoStr(gl.GetString(gl.VERSION))
+	fmt.Println("OpenGL version", version)
+
+	for !w.window.ShouldClose() {
+
+		w.waitIfPaused()
+		if w.shouldClose() {
+			break
+		}
+		w.runTasks()
+
+		w.drawFrame()
+
+		// Maintenance
+		w.window.SwapBuffers()
+		glfw.PollEvents()
+	}
+}
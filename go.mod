@@ -10,6 +10,7 @@ require (
 	github.com/pkg/errors v0.8.0 // indirect
 	github.com/tbogdala/assimp-go v0.0.0-20160907223021-d10e2135f9fe
 	github.com/tbogdala/gombz v0.0.0-20160813021445-aee583525334
-	golang.org/x/image v0.0.0-20181116024801-cd38e8056d9b // indirect
+	golang.org/x/image v0.0.0-20181116024801-cd38e8056d9b
+	golang.org/x/text v0.3.0 // indirect
 	gopkg.in/mgo.v2 v2.0.0-20180705113604-9856a29383ce // indirect
 )
@@ -23,6 +23,7 @@ func init() {
 
 var bot *models.Robot
 var cam *univ.ChaseCam
+var debugCam *models.DebugCam
 
 func main() {
 	window := draw.NewWindow(1000, 1000)
@@ -36,8 +37,18 @@ func main() {
 	bot = models.NewRobot(u)
 	defer bot.Remove()
 
-	cam = univ.NewChaseCam(bot.Body, u.Window)
+	window.SetSky(draw.NewStarfieldSky(draw.DefaultStarfield))
+
+	sun := draw.NewPointLight(mgl32.Vec3{1, 1, 1}, 1, 0)
+	sun.SetLocation(mgl32.Vec3{0, 100, 0})
+	u.AddLight(sun)
+
+	cam = univ.NewChaseCam(bot.Body)
 	cam.SetLocation(mgl32.Vec3{5, 5, 5})
+	u.Cameras.Add("chase", cam)
+
+	debugCam = models.NewDebugCam(u)
+	defer debugCam.Destroy()
 
 	// force := univ.NewLinearForce(bot2.Body, mgl32.Vec3{0.5, 0.5, 0.5})
 	// defer force.Destroy()
@@ -47,10 +58,21 @@ func main() {
 	// defer torque.Destroy()
 	// bot.Rotate(mgl32.QuatRotate(1, mgl32.Vec3{0, 1, 0}))
 
+	window.SetScrollCallback(HandleScroll)
 	window.Loop(HandleKey, HandleMouseButton, HandleCursor)
 }
 
 func HandleKey(w *glfw.Window, key glfw.Key, scanCode int, action glfw.Action, modifier glfw.ModifierKey) {
+	if key == glfw.KeyF {
+		if action == glfw.Press {
+			debugCam.Toggle()
+		}
+		return
+	}
+	if debugCam.HandleKey(key, action) {
+		return
+	}
+
 	switch key {
 	case glfw.KeyLeft:
 		cam.Translate(mgl32.Vec3{0.1, 0, 0})
@@ -75,5 +97,8 @@ func HandleMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Acti
 	log.Println("Handle mouse button")
 }
 func HandleCursor(w *glfw.Window, xpos float64, ypos float64) {
-	// cam.Translate(mgl32.)
+	debugCam.HandleCursor(xpos, ypos)
+}
+func HandleScroll(w *glfw.Window, xoff float64, yoff float64) {
+	debugCam.HandleScroll(xoff, yoff)
 }
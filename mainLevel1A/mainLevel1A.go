@@ -11,6 +11,7 @@ import (
 	"github.com/faiface/beep/wav"
 	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/cinema"
 	"github.com/lsmith130/space/draw"
 	"github.com/lsmith130/space/models"
 	"github.com/lsmith130/space/univ"
@@ -19,6 +20,17 @@ import (
 const windowWidth = 800
 const windowHeight = 600
 
+// cameraBlend is how long switching between cameras takes.
+const cameraBlend = time.Second
+
+// cameraCycle is the order the scene's cameras are switched through.
+var cameraCycle = []string{"chase", "overview", "inspect"}
+
+// dragging is whether the mouse is being dragged to orbit the inspection camera, and lastCursor is
+// where the cursor was last seen.
+var dragging bool
+var lastCursor [2]float64
+
 var u *univ.Universe
 
 func init() {
@@ -30,6 +42,15 @@ var cam *univ.ChaseCam
 var man *models.Astronaut
 var goal1 *models.Goal
 var goal2 *models.Goal
+var ship *models.Ship
+var pilot *models.Pilot
+var level1 *models.Level1A
+var debugCam *models.DebugCam
+var intro *cinema.Player
+var rearView *draw.Viewport
+var rearCam *univ.ChaseCam
+var rearShown bool
+var hud *models.HUD
 
 func main() {
 	window := draw.NewWindow(1000, 1000)
@@ -44,13 +65,35 @@ func main() {
 	man.SetLocation(mgl32.Vec3{0, 2, 0})
 	defer man.Remove()
 
-	level1 := models.NewLevel1A(u)
+	level1 = models.NewLevel1A(u)
 	defer level1.Remove()
 
-	ship := models.NewShip(u)
-	ship.SetLocation(mgl32.Vec3{-10, 5, 0})
+	ship = models.NewShip(u)
+	// The intro cutscene flies the ship in to (-10, 5, 0).
+	ship.SetLocation(mgl32.Vec3{-40, 30, 60})
 	defer ship.Remove()
 
+	sparks := models.NewSparks(u)
+	defer sparks.Remove()
+
+	scene, err := univ.LoadScene("scenes/level1a.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	hud = models.NewHUD(u)
+	hud.Track(man.Body)
+	hud.AddShip(ship)
+	hud.SetObjective(dockObjective)
+	defer hud.Remove()
+
+	for _, p := range scene.DockingPorts("level1a", level1.Body) {
+		u.Docking.AddPort(p)
+	}
+	for _, p := range scene.DockingPorts("ship", ship.Body) {
+		u.Docking.AddPort(p)
+	}
+	u.Docking.AddObserver(stationObjective{station: level1.Body, ship: ship, hud: hud})
+
 	goal1 = models.NewGoal(u)
 	goal1.SetLocation(mgl32.Vec3{95, 7, 337})
 	defer goal1.Remove()
@@ -58,16 +101,151 @@ func main() {
 	goal2 = models.NewGoal(u)
 	goal2.SetLocation(mgl32.Vec3{254, -6, 13})
 	defer goal2.Remove()
+	hud.AddPrompter(goal1)
+	hud.AddPrompter(goal2)
 
-	cam = univ.NewChaseCam(man.Body, u.Window)
-	cam.SetLocation(mgl32.Vec3{0, 2, -10})
+	sceneBodies := map[string]*univ.Body{
+		"astronaut": man.Body,
+		"ship":      ship.Body,
+		"level1a":   level1.Body,
+	}
+	if err := scene.SetupCameras(u, sceneBodies); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := scene.SetupLights(u, sceneBodies); err != nil {
+		log.Fatal(err)
+	}
+	if err := scene.SetupMaterials(u, sceneBodies); err != nil {
+		log.Fatal(err)
+	}
+	if err := scene.SetupSky(window); err != nil {
+		log.Fatal(err)
+	}
+	loadPostSettings()
+	cam = u.Cameras.Get("chase").(*univ.ChaseCam)
 	defer cam.Remove()
 
+	pilot = models.NewPilot(man, cam)
+
+	debugCam = models.NewDebugCam(u)
+	defer debugCam.Destroy()
+
+	// The debug view is drawn while the window's debug drawing is toggled on with F3.
+	debugView := univ.NewDebugView(u)
+	defer debugView.Remove()
+
+	// The rear view mirror sits in the top corner of the window and is hidden until toggled.
+	rearCam = univ.NewChaseCam(man.Body)
+	rearCam.SetLocation(mgl32.Vec3{0, 2.5, 0.5})
+	rearCam.SetFocus(mgl32.Vec3{0, 2, -10})
+	rearCam.SetPositionSpring(univ.Spring{})
+	rearCam.SetRotationSpring(univ.Spring{})
+	defer rearCam.Remove()
+	u.Cameras.Add("rear", rearCam)
+	rearView = draw.NewViewport(0.7, 0.75, 0.28, 0.22)
+
+	timeline, err := cinema.Load("cutscenes/level1a_intro.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	intro, err = cinema.NewPlayer(timeline, u, map[string]*univ.Body{"ship": ship.Body}, cameraBlend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer intro.Destroy()
+	intro.AddObserver(subtitleLog{})
+	intro.Start()
+
+	window.SetScrollCallback(HandleScroll)
 	window.Loop(HandleKey, HandleMouseButton, HandleCursor)
 }
 
+// postSettingsPath is the settings file for the window's post-processing, reloaded with F5.
+const postSettingsPath = "settings/post.json"
+
+// loadPostSettings sets the window's post-processing from its settings file, keeping the settings it
+// has if the file can't be loaded.
+func loadPostSettings() {
+	s, err := draw.LoadPostSettings(postSettingsPath)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	u.Window.SetPostSettings(s)
+}
+
 func HandleKey(w *glfw.Window, key glfw.Key, scanCode int, action glfw.Action, modifier glfw.ModifierKey) {
 
+	if key == glfw.KeyEnter {
+		if action == glfw.Press {
+			if pilot.ToggleBoard(ship) {
+				hud.Track(pilot.Controlled())
+				// The mirror looks back from whatever is controlled, even while it's hidden.
+				rearCam.SetTarget(pilot.Controlled())
+				rearCam.Snap()
+			}
+		}
+		return
+	}
+
+	if !intro.Finished() {
+		if key == glfw.KeyEscape && action == glfw.Press {
+			intro.Skip()
+		}
+		return
+	}
+
+	if key == glfw.KeyF {
+		if action == glfw.Press {
+			debugCam.Toggle()
+		}
+		return
+	}
+	if key == glfw.KeyF3 {
+		if action == glfw.Press {
+			u.Window.Debug().Toggle()
+		}
+		return
+	}
+	if debugCam.Active() {
+		debugCam.HandleKey(key, action)
+		return
+	}
+
+	if key == glfw.KeyR {
+		if action == glfw.Press {
+			toggleRearView()
+		}
+		return
+	}
+
+	if key == glfw.KeyF5 {
+		if action == glfw.Press {
+			loadPostSettings()
+		}
+		return
+	}
+
+	if key == glfw.KeyTab {
+		if action == glfw.Press {
+			next := cameraCycle[0]
+			for i, name := range cameraCycle[:len(cameraCycle)-1] {
+				if u.Cameras.Active() == name {
+					next = cameraCycle[i+1]
+				}
+			}
+			if err := u.Cameras.Activate(next, cameraBlend); err != nil {
+				log.Println(err)
+			}
+		}
+		return
+	}
+
+	if s := pilot.Ship(); s != nil {
+		handleShipKey(s, key, action)
+		return
+	}
+
 	switch key {
 	case glfw.KeyLeft:
 		if action == glfw.Release {
@@ -105,6 +283,8 @@ func HandleKey(w *glfw.Window, key glfw.Key, scanCode int, action glfw.Action, m
 		man.SetUp(action != glfw.Release)
 	case glfw.KeyLeftAlt:
 		man.SetDown(action != glfw.Release)
+	case glfw.KeyX:
+		man.SetStabilize(action != glfw.Release)
 
 	case glfw.KeySpace:
 		if action == glfw.Release {
@@ -115,9 +295,137 @@ func HandleKey(w *glfw.Window, key glfw.Key, scanCode int, action glfw.Action, m
 	}
 
 }
+
+// toggleRearView shows or hides the rear view mirror, which looks back from whatever the player is
+// controlling.
+func toggleRearView() {
+	if rearShown {
+		u.Cameras.Unbind(rearView)
+		rearShown = false
+		return
+	}
+	rearCam.SetTarget(pilot.Controlled())
+	rearCam.Snap()
+	if err := u.Cameras.Bind(rearView, "rear"); err != nil {
+		log.Println(err)
+		return
+	}
+	rearShown = true
+}
+
+func handleShipKey(s *models.Ship, key glfw.Key, action glfw.Action) {
+	enable := action != glfw.Release
+
+	switch key {
+	case glfw.KeyU:
+		if action == glfw.Press {
+			u.Docking.UndockBody(s.Body)
+		}
+	case glfw.Key1:
+		if action == glfw.Press {
+			s.Autopilot().FlyTo(level1.Body, 30)
+		}
+	case glfw.Key2:
+		if action == glfw.Press {
+			s.Autopilot().MatchVelocity(level1.Body)
+		}
+	case glfw.Key3:
+		if action == glfw.Press {
+			s.Autopilot().HoldStation(level1.Body)
+		}
+	case glfw.Key0:
+		if action == glfw.Press {
+			s.Autopilot().Disengage()
+		}
+	case glfw.KeyW:
+		s.SetControl(models.ShipPitchDown, enable)
+	case glfw.KeyS:
+		s.SetControl(models.ShipPitchUp, enable)
+	case glfw.KeyA:
+		s.SetControl(models.ShipYawLeft, enable)
+	case glfw.KeyD:
+		s.SetControl(models.ShipYawRight, enable)
+	case glfw.KeyQ:
+		s.SetControl(models.ShipRollLeft, enable)
+	case glfw.KeyE:
+		s.SetControl(models.ShipRollRight, enable)
+	case glfw.KeyLeft:
+		s.SetControl(models.ShipStrafeLeft, enable)
+	case glfw.KeyRight:
+		s.SetControl(models.ShipStrafeRight, enable)
+	case glfw.KeyUp:
+		s.SetControl(models.ShipStrafeUp, enable)
+	case glfw.KeyDown:
+		s.SetControl(models.ShipStrafeDown, enable)
+	case glfw.KeyLeftShift:
+		if enable {
+			s.AddThrottle(0.1)
+		}
+	case glfw.KeyLeftControl:
+		if enable {
+			s.AddThrottle(-0.1)
+		}
+	case glfw.KeyZ:
+		s.SetThrottle(0)
+	case glfw.KeyV:
+		if action == glfw.Press {
+			s.SetFlightAssist(!s.FlightAssist())
+		}
+	}
+}
+
+// dockObjective is the objective of the level until the ship docks with the station.
+const dockObjective = "Dock the ship with the station"
+
+// stationObjective reports when a ship docks with the station, and refuels the ship when it does.
+type stationObjective struct {
+	station *univ.Body
+	ship    *models.Ship
+	hud     *models.HUD
+}
+
+func (o stationObjective) Docked(a, b *univ.DockingPort) {
+	if a.Body == o.station || b.Body == o.station {
+		log.Println("Docked with station")
+		o.hud.SetObjective("Docked with the station")
+		if a.Body == o.ship.Body || b.Body == o.ship.Body {
+			o.ship.Refuel()
+		}
+	}
+}
+
+func (o stationObjective) Undocked(a, b *univ.DockingPort) {
+	if a.Body == o.station || b.Body == o.station {
+		log.Println("Undocked from station")
+		o.hud.SetObjective(dockObjective)
+	}
+}
+
+// subtitleLog prints the subtitles of cutscenes.
+type subtitleLog struct{}
+
+func (subtitleLog) Subtitle(text string, duration time.Duration) {
+	log.Println(text)
+}
+
+func (subtitleLog) Finished(skipped bool) {}
+
 func HandleMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, modifier glfw.ModifierKey) {
-	log.Println("Handle mouse button")
+	if button == glfw.MouseButtonLeft {
+		dragging = action != glfw.Release
+	}
 }
 func HandleCursor(w *glfw.Window, xpos float64, ypos float64) {
-	// cam.Translate(mgl32.)
+	debugCam.HandleCursor(xpos, ypos)
+	if orbit, ok := u.Cameras.Get(u.Cameras.Active()).(*univ.OrbitCam); ok && dragging {
+		orbit.Drag(float32(xpos-lastCursor[0]), float32(ypos-lastCursor[1]))
+	}
+	lastCursor = [2]float64{xpos, ypos}
+}
+func HandleScroll(w *glfw.Window, xoff float64, yoff float64) {
+	if orbit, ok := u.Cameras.Get(u.Cameras.Active()).(*univ.OrbitCam); ok {
+		orbit.Zoom(float32(yoff))
+		return
+	}
+	debugCam.HandleScroll(xoff, yoff)
 }
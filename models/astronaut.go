@@ -3,6 +3,7 @@ package models
 import (
 	"log"
 	"os"
+	"sync"
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/speaker"
@@ -12,6 +13,20 @@ import (
 	"github.com/lsmith130/space/univ"
 )
 
+const (
+	// suitOxygen is the number of seconds of oxygen a full suit holds.
+	suitOxygen = 300
+	// suitBreachDamage is the smallest hit that breaches the suit.
+	suitBreachDamage = 15
+	// suitLeakRate is the extra oxygen lost each second through each breach.
+	suitLeakRate = 5
+	// suffocationDamage is the damage done each second once the suit runs out of oxygen.
+	suffocationDamage = 10
+)
+
+// jetpackOffset is where the suit's thrusters vent, relative to the astronaut.
+var jetpackOffset = mgl32.Vec3{0, 0, -0.5}
+
 type Astronaut struct {
 	*univ.Body
 	u            *univ.Universe
@@ -19,16 +34,29 @@ type Astronaut struct {
 
 	forward, back, left, right, up, down *univ.Acceleration
 	rightroll, leftroll                  *univ.Acceleration
+	stabilizer                           *univ.ControlLoop
+	// exhaust is the jetpack exhaust of each linear acceleration, vented while it is active.
+	exhaust map[*univ.Acceleration]*draw.Emitter
+
+	health   *univ.Health
+	ticker   *draw.Ticker
+	suitMut  sync.Mutex
+	oxygen   float32
+	breaches int
+	// boarded is whether m is inside a ship, which supplies its air.
+	boarded bool
 }
 
 func NewAstronaut(u *univ.Universe) *Astronaut {
 
-	tex, err := draw.NewTexture("models/astronaut.png")
+	tex, err := u.Texture("models/astronaut.png")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	b, err := u.NewBody("models/astronaut3.dae", u.Window.GetBoneProgram(), []*draw.Texture{tex})
+	b, err := u.NewBody("models/astronaut3.dae", u.Window.GetBoneProgram(), univ.Materials{
+		univ.AllMaterials: draw.NewTextureMaterial("astronaut", tex),
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -44,7 +72,25 @@ func NewAstronaut(u *univ.Universe) *Astronaut {
 		down:      univ.NewLinearAcceleration(b, mgl32.Vec3{0, -20, 0}),
 		rightroll: univ.NewAngularAcceleration(b, mgl32.Vec3{0, -1.5, 0}),
 		leftroll:  univ.NewAngularAcceleration(b, mgl32.Vec3{0, 1.5, 0}),
+		exhaust:   make(map[*univ.Acceleration]*draw.Emitter),
 	}
+	// Each thruster vents exhaust opposite the way it pushes.
+	for _, acc := range []*univ.Acceleration{a.forward, a.back, a.left, a.right, a.up, a.down} {
+		e := draw.NewEmitter(JetpackExhaust)
+		b.AttachEmitter(e, jetpackOffset, acc.LinearVector().Mul(-1))
+		u.AddEmitter(e)
+		a.exhaust[acc] = e
+	}
+	a.stabilizer = univ.NewControlLoop(b, univ.NewSpinController(mgl32.Vec3{}, 3))
+
+	a.oxygen = suitOxygen
+	a.health = univ.NewHealth(b, 100)
+	a.health.ImpactThreshold = 8
+	a.health.ImpactDamage = 4
+	a.health.AddObserver(a)
+	u.Damage.AddHealth(a.health)
+
+	a.ticker = draw.NewTicker(univ.DefaultRefreshRate, a.tick)
 
 	a.forward.Pause()
 	a.back.Pause()
@@ -59,58 +105,132 @@ func NewAstronaut(u *univ.Universe) *Astronaut {
 }
 
 func (m *Astronaut) Remove() {
+	m.ticker.Close()
+	m.u.Damage.RemoveHealth(m.health)
 	m.u.RemoveBody(m.Body)
 }
 
+// Health returns the health of m.
+func (m *Astronaut) Health() *univ.Health {
+	return m.health
+}
+
+// Oxygen returns the number of seconds of oxygen left in m's suit, without accounting for leaks.
+func (m *Astronaut) Oxygen() float32 {
+	m.suitMut.Lock()
+	defer m.suitMut.Unlock()
+	return m.oxygen
+}
+
+// Breaches returns the number of holes in m's suit.
+func (m *Astronaut) Breaches() int {
+	m.suitMut.Lock()
+	defer m.suitMut.Unlock()
+	return m.breaches
+}
+
+// RepairSuit seals every breach in m's suit.
+func (m *Astronaut) RepairSuit() {
+	m.suitMut.Lock()
+	m.breaches = 0
+	m.suitMut.Unlock()
+}
+
+// RefillOxygen fills m's suit with oxygen.
+func (m *Astronaut) RefillOxygen() {
+	m.suitMut.Lock()
+	m.oxygen = suitOxygen
+	m.suitMut.Unlock()
+}
+
+// Damaged conforms to univ.DamageObserver.Damaged and should not be called directly
+func (m *Astronaut) Damaged(h *univ.Health, d univ.Damage) {
+	if d.Type == univ.DamageSuffocation || d.Amount < suitBreachDamage {
+		return
+	}
+	m.suitMut.Lock()
+	m.breaches++
+	m.suitMut.Unlock()
+}
+
+// Destroyed conforms to univ.DamageObserver.Destroyed and should not be called directly
+func (m *Astronaut) Destroyed(h *univ.Health) {
+	log.Println("Astronaut died")
+}
+
+// setBoarded sets whether m is inside a ship, where its suit doesn't use oxygen.
+func (m *Astronaut) setBoarded(boarded bool) {
+	m.suitMut.Lock()
+	m.boarded = boarded
+	m.suitMut.Unlock()
+}
+
+func (m *Astronaut) tick(elapsed float32) {
+	m.suitMut.Lock()
+	if m.boarded {
+		m.suitMut.Unlock()
+		return
+	}
+	m.oxygen -= (1 + float32(m.breaches)*suitLeakRate) * elapsed
+	empty := m.oxygen <= 0
+	if empty {
+		m.oxygen = 0
+	}
+	m.suitMut.Unlock()
+
+	if empty {
+		m.health.Apply(univ.Damage{Type: univ.DamageSuffocation, Amount: suffocationDamage * elapsed})
+	}
+}
+
+// halt stops all of m's accelerations and brings it to rest.
+func (m *Astronaut) halt() {
+	for _, a := range []*univ.Acceleration{m.forward, m.back, m.left, m.right, m.up, m.down, m.rightroll, m.leftroll} {
+		m.thrust(a, false)
+	}
+	m.stabilizer.Pause()
+	m.SetVelocity(mgl32.Vec3{})
+	m.SetAngularV(mgl32.Vec3{})
+}
+
+// thrust starts or pauses a, along with the jetpack exhaust of linear accelerations.
+func (m *Astronaut) thrust(a *univ.Acceleration, enable bool) {
+	e := m.exhaust[a]
+	if enable {
+		a.Start()
+	} else {
+		a.Pause()
+	}
+	if e != nil {
+		e.SetOn(enable)
+	}
+}
+
 func (m *Astronaut) SetForward(enable bool) {
 	f1, _ := os.Open("audio/walking.wav")
 	s, _, _ := wav.Decode(f1)
 	speaker.Play(s)
-	if enable {
-		m.forward.Start()
-	} else {
-		m.forward.Pause()
-	}
+	m.thrust(m.forward, enable)
 }
 
 func (m *Astronaut) SetBack(enable bool) {
-	if enable {
-		m.back.Start()
-	} else {
-		m.back.Pause()
-	}
+	m.thrust(m.back, enable)
 }
 
 func (m *Astronaut) SetLeft(enable bool) {
-	if enable {
-		m.left.Start()
-	} else {
-		m.left.Pause()
-	}
+	m.thrust(m.left, enable)
 }
 
 func (m *Astronaut) SetRight(enable bool) {
-	if enable {
-		m.right.Start()
-	} else {
-		m.right.Pause()
-	}
+	m.thrust(m.right, enable)
 }
 
 func (m *Astronaut) SetDown(enable bool) {
-	if enable {
-		m.down.Start()
-	} else {
-		m.down.Pause()
-	}
+	m.thrust(m.down, enable)
 }
 
 func (m *Astronaut) SetUp(enable bool) {
-	if enable {
-		m.up.Start()
-	} else {
-		m.up.Pause()
-	}
+	m.thrust(m.up, enable)
 }
 
 func (m *Astronaut) SetRollRight(enable bool) {
@@ -121,6 +241,15 @@ func (m *Astronaut) SetRollRight(enable bool) {
 	}
 }
 
+// SetStabilize sets whether m's suit thrusters cancel its spin.
+func (m *Astronaut) SetStabilize(enable bool) {
+	if enable {
+		m.stabilizer.Start()
+	} else {
+		m.stabilizer.Pause()
+	}
+}
+
 func (m *Astronaut) SetRollLeft(enable bool) {
 	if enable {
 		m.leftroll.Start()
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/univ"
+)
+
+// AutopilotMode is the manoeuvre an Autopilot is flying.
+type AutopilotMode int
+
+const (
+	// AutopilotOff leaves the ship to its pilot.
+	AutopilotOff AutopilotMode = iota
+	// AutopilotFlyTo flies to the target and stops alongside it.
+	AutopilotFlyTo
+	// AutopilotMatchVelocity matches the target's velocity without closing the distance to it.
+	AutopilotMatchVelocity
+	// AutopilotHoldStation holds the ship's current position relative to the target.
+	AutopilotHoldStation
+)
+
+// autopilotMaxSpeed is the fastest the autopilot will approach its target.
+const autopilotMaxSpeed = 30
+
+// Autopilot flies a Ship relative to a target body using its thrusters.
+type Autopilot struct {
+	mut    sync.Mutex
+	ship   *Ship
+	loop   *univ.ControlLoop
+	mode   AutopilotMode
+	target *univ.Body
+	// offset is where the ship is held relative to the target, in the target's local space.
+	offset mgl32.Vec3
+
+	position *univ.PositionController
+	velocity *univ.VelocityController
+	attitude *univ.AttitudeController
+	spin     *univ.SpinController
+}
+
+// NewAutopilot creates an Autopilot for ship. The autopilot is initially off.
+func NewAutopilot(ship *Ship) *Autopilot {
+	a := &Autopilot{
+		ship:     ship,
+		position: univ.NewPositionController(mgl32.Vec3{}, shipMainThrust, autopilotMaxSpeed),
+		velocity: univ.NewVelocityController(mgl32.Vec3{}, shipMainThrust),
+		attitude: univ.NewAttitudeController(mgl32.QuatIdent(), shipRotationThrust),
+		spin:     univ.NewSpinController(mgl32.Vec3{}, shipRotationThrust),
+	}
+	a.loop = univ.NewControlLoop(ship.Body, a)
+	return a
+}
+
+// Destroy stops a and cleans up its resources. a should not be used after it is destroyed.
+func (a *Autopilot) Destroy() {
+	a.loop.Destroy()
+}
+
+// Mode returns the manoeuvre a is flying.
+func (a *Autopilot) Mode() AutopilotMode {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.mode
+}
+
+// FlyTo flies the ship to standoff units away from target, on the side it is approaching from, and
+// keeps it there.
+func (a *Autopilot) FlyTo(target *univ.Body, standoff float32) {
+	dir := a.ship.Location().Sub(target.Location())
+	if dir.Len() == 0 {
+		dir = mgl32.Vec3{0, 0, -1}
+	}
+	a.engage(AutopilotFlyTo, target, dir.Normalize().Mul(standoff))
+}
+
+// MatchVelocity matches the ship's velocity to target's.
+func (a *Autopilot) MatchVelocity(target *univ.Body) {
+	a.engage(AutopilotMatchVelocity, target, mgl32.Vec3{})
+}
+
+// HoldStation keeps the ship where it is relative to target.
+func (a *Autopilot) HoldStation(target *univ.Body) {
+	a.engage(AutopilotHoldStation, target, a.ship.Location().Sub(target.Location()))
+}
+
+// Disengage turns a off.
+func (a *Autopilot) Disengage() {
+	a.mut.Lock()
+	a.mode = AutopilotOff
+	a.target = nil
+	a.mut.Unlock()
+	a.loop.Pause()
+}
+
+// engage starts flying mode relative to target, with offset given in world space.
+func (a *Autopilot) engage(mode AutopilotMode, target *univ.Body, offset mgl32.Vec3) {
+	a.mut.Lock()
+	wasOff := a.mode == AutopilotOff
+	a.mode = mode
+	a.target = target
+	a.offset = target.Rotation().Normalize().Inverse().Rotate(offset)
+	a.position.PID.Reset()
+	a.velocity.PID.Reset()
+	a.attitude.PID.Reset()
+	a.spin.PID.Reset()
+	a.mut.Unlock()
+
+	if wasOff {
+		a.loop.Start()
+	}
+}
+
+// Control conforms to univ.Controller.Control, and should not be called directly.
+func (a *Autopilot) Control(b *univ.Body, elapsed float32) (linear, angular mgl32.Vec3) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.mode == AutopilotOff {
+		return mgl32.Vec3{}, mgl32.Vec3{}
+	}
+
+	targetRot := a.target.Rotation().Normalize()
+	hold := a.target.Location().Add(targetRot.Rotate(a.offset))
+
+	switch a.mode {
+	case AutopilotFlyTo, AutopilotHoldStation:
+		a.position.Target = hold
+		a.position.TargetVelocity = a.target.Velocity()
+		linear, _ = a.position.Control(b, elapsed)
+	case AutopilotMatchVelocity:
+		a.velocity.Target = a.target.Velocity()
+		linear, _ = a.velocity.Control(b, elapsed)
+	}
+
+	// Point the nose at the target while flying to it, and otherwise hold the current heading steady.
+	if a.mode == AutopilotFlyTo && a.target.Location().Sub(b.Location()).Len() > 1 {
+		a.attitude.Target = univ.LookRotation(a.target.Location().Sub(b.Location()), targetRot.Rotate(mgl32.Vec3{0, 1, 0}))
+		_, angular = a.attitude.Control(b, elapsed)
+	} else {
+		a.spin.Target = a.target.AngularV()
+		_, angular = a.spin.Control(b, elapsed)
+	}
+
+	return linear, angular
+}
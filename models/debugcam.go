@@ -0,0 +1,152 @@
+package models
+
+import (
+	"log"
+	"sync"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/lsmith130/space/univ"
+)
+
+const (
+	// debugCamName is the name the debug camera is added to the camera manager with.
+	debugCamName = "debug"
+	// debugCamScrollFactor is how much each step of the scroll wheel changes the debug camera's speed by.
+	debugCamScrollFactor = 1.2
+)
+
+// DebugCam is a fly camera that can be toggled on to leave the current view and inspect the level, then
+// toggled off to return to it. While it is on, it takes the mouse and the WASD, Q and E keys, with left
+// shift to fly faster, left control to fly slower and the scroll wheel to change speed.
+type DebugCam struct {
+	u   *univ.Universe
+	cam *univ.FreeCam
+
+	mut      sync.Mutex
+	previous string
+	active   bool
+	cursor   [2]float64
+	tracking bool
+}
+
+// NewDebugCam creates a new DebugCam for u, which is off until it is toggled on.
+func NewDebugCam(u *univ.Universe) *DebugCam {
+	d := &DebugCam{
+		u:   u,
+		cam: univ.NewFreeCam(),
+	}
+	u.Cameras.Add(debugCamName, d.cam)
+	return d
+}
+
+// Destroy removes d from its universe and cleans up its resources.
+func (d *DebugCam) Destroy() {
+	d.u.Cameras.Remove(debugCamName)
+	d.cam.Destroy()
+}
+
+// Camera returns the camera flown by d.
+func (d *DebugCam) Camera() *univ.FreeCam {
+	return d.cam
+}
+
+// Active returns whether d is on.
+func (d *DebugCam) Active() bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.active
+}
+
+// Toggle switches d on, starting from the current view, or off, returning to the camera that was
+// active before.
+func (d *DebugCam) Toggle() {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	d.cam.ClearControls()
+	d.tracking = false
+
+	if d.active {
+		d.active = false
+		d.u.Window.SetCursorCaptured(false)
+		if err := d.u.Cameras.Activate(d.previous, 0); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	view := d.u.Cameras.View()
+	d.cam.SetLocation(view.Eye)
+	d.cam.SetRotation(view.Rotation)
+	d.cam.SetProjection(view.Projection)
+
+	d.previous = d.u.Cameras.Active()
+	if err := d.u.Cameras.Activate(debugCamName, 0); err != nil {
+		log.Println(err)
+		return
+	}
+	d.active = true
+	d.u.Window.SetCursorCaptured(true)
+}
+
+// HandleKey flies d with key, and reports whether d used it. Keys are never used while d is off.
+func (d *DebugCam) HandleKey(key glfw.Key, action glfw.Action) bool {
+	if !d.Active() {
+		return false
+	}
+
+	enable := action != glfw.Release
+	switch key {
+	case glfw.KeyW:
+		d.cam.SetControl(univ.FreeCamForward, enable)
+	case glfw.KeyS:
+		d.cam.SetControl(univ.FreeCamBack, enable)
+	case glfw.KeyA:
+		d.cam.SetControl(univ.FreeCamLeft, enable)
+	case glfw.KeyD:
+		d.cam.SetControl(univ.FreeCamRight, enable)
+	case glfw.KeyE:
+		d.cam.SetControl(univ.FreeCamUp, enable)
+	case glfw.KeyQ:
+		d.cam.SetControl(univ.FreeCamDown, enable)
+	case glfw.KeyLeftShift:
+		d.cam.SetControl(univ.FreeCamFast, enable)
+	case glfw.KeyLeftControl:
+		d.cam.SetControl(univ.FreeCamSlow, enable)
+	default:
+		return false
+	}
+	return true
+}
+
+// HandleCursor turns d by how far the cursor moved to x, y. The cursor is ignored while d is off.
+func (d *DebugCam) HandleCursor(x, y float64) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if !d.active {
+		return
+	}
+
+	// The first position after switching on only gives a starting point, or the view would jump.
+	if d.tracking {
+		d.cam.Look(float32(x-d.cursor[0]), float32(y-d.cursor[1]))
+	}
+	d.cursor = [2]float64{x, y}
+	d.tracking = true
+}
+
+// HandleScroll speeds d up when scrolled up and slows it down when scrolled down. Scrolling is ignored
+// while d is off.
+func (d *DebugCam) HandleScroll(xoff, yoff float64) {
+	if !d.Active() {
+		return
+	}
+	factor := float32(debugCamScrollFactor)
+	if yoff < 0 {
+		factor = 1 / factor
+		yoff = -yoff
+	}
+	for i := 0; i < int(yoff+0.5); i++ {
+		d.cam.ScaleSpeed(factor)
+	}
+}
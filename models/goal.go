@@ -1,11 +1,9 @@
 package models
 
 import (
-	// "fmt"
-
-	"fmt"
 	"log"
 	"os"
+	"sync"
 
 	"github.com/faiface/beep/speaker"
 	"github.com/faiface/beep/wav"
@@ -14,21 +12,27 @@ import (
 	"github.com/lsmith130/space/univ"
 )
 
+// goalReach is how close a body must be to a goal to pick it up.
+const goalReach = 10
+
 type Goal struct {
 	*univ.Body
 	u      *univ.Universe
+	mut    sync.Mutex
 	target *univ.Body
 	ticker *draw.Ticker
 }
 
 func NewGoal(u *univ.Universe) *Goal {
 
-	goal, err := draw.NewTexture("models/goal.png")
+	goal, err := u.Texture("models/goal.png")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	b, err := u.NewBody("models/goal.dae", u.Window.GetStandardProgram(), []*draw.Texture{goal})
+	b, err := u.NewBody("models/goal.dae", u.Window.GetStandardProgram(), univ.Materials{
+		univ.AllMaterials: draw.NewTextureMaterial("goal", goal),
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -41,14 +45,15 @@ func NewGoal(u *univ.Universe) *Goal {
 }
 
 func (goal *Goal) Pickup(t *univ.Body) {
+	goal.mut.Lock()
+	defer goal.mut.Unlock()
 
-	if t.Location().Sub(goal.Body.Location()).Len() < 10 {
+	if goal.inReach(t) {
 		if goal.target == nil {
 			f1, _ := os.Open("audio/pickup.wav")
 			s, _, _ := wav.Decode(f1)
 			speaker.Play(s)
 
-			fmt.Println("Pick up")
 			goal.target = t
 			t.AddObserver(goal)
 			goal.update()
@@ -57,30 +62,54 @@ func (goal *Goal) Pickup(t *univ.Body) {
 			s, _, _ := wav.Decode(f1)
 			speaker.Play(s)
 
-			fmt.Println("Set down")
 			goal.target.RemoveObserver(goal)
 			goal.target = nil
 		}
-	} else {
-		fmt.Println("Can't pick up")
 	}
 }
 
+// Prompt offers t to pick up goal when it is in reach, or to set it down when t is carrying it. It
+// conforms to Prompter.Prompt.
+func (goal *Goal) Prompt(t *univ.Body) string {
+	goal.mut.Lock()
+	defer goal.mut.Unlock()
+
+	switch {
+	case goal.target == t:
+		return "Press Space to set down"
+	case goal.target == nil && goal.inReach(t):
+		return "Press Space to pick up"
+	}
+	return ""
+}
+
+func (goal *Goal) inReach(t *univ.Body) bool {
+	return t.Location().Sub(goal.Body.Location()).Len() < goalReach
+}
+
 func (r *Goal) Remove() {
 	r.u.RemoveBody(r.Body)
 }
 
 // BodyTranslated conforms to Observer.BodyTranslated and should not be called directly
 func (goal *Goal) BodyTranslated(b *univ.Body) {
+	goal.mut.Lock()
+	defer goal.mut.Unlock()
 	goal.update()
 }
 
 // BodyRotated conforms to Observer.BodyRotated and should not be called directly
 func (goal *Goal) BodyRotated(b *univ.Body) {
+	goal.mut.Lock()
+	defer goal.mut.Unlock()
 	goal.update()
 }
 
+// update moves goal in front of the body carrying it. goal.mut must be held.
 func (goal *Goal) update() {
+	if goal.target == nil {
+		return
+	}
 	rot := goal.target.Rotation()
 	posMat := mgl32.Translate3D(goal.target.Location().Elem())
 	posRot := posMat.Mul4(rot.Normalize().Mat4())
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+	"github.com/lsmith130/space/univ"
+)
+
+const (
+	// hudRefreshRate is how often the HUD's readouts are updated.
+	hudRefreshRate = 100 * time.Millisecond
+	// hudMargin is the space between the HUD and the edges of the window, in pixels.
+	hudMargin = 20
+	// hudTextSize and hudPromptSize are the sizes of the HUD's readouts and prompts, in pixels per em.
+	hudTextSize   = 18
+	hudPromptSize = 26
+)
+
+// Prompter offers bodies an interaction, such as picking something up.
+type Prompter interface {
+	// Prompt returns the prompt for the interaction b can take, or "" if it can't take any.
+	Prompt(b *univ.Body) string
+}
+
+// HUD shows the player the speed of the body they control, its fuel, their objective and what they can
+// interact with, over a universe's window.
+//
+// All HUD functions are safe to use concurrently.
+type HUD struct {
+	overlay   *draw.Overlay
+	speed     *draw.Text
+	fuelLabel *draw.Text
+	fuel      *draw.Bar
+	objective *draw.Text
+	prompt    *draw.Text
+	ticker    *draw.Ticker
+
+	mut       sync.Mutex
+	tracked   *univ.Body
+	prompters []Prompter
+	ships     []*Ship
+}
+
+// NewHUD creates a HUD over the window of u, with nothing tracked and no objective.
+func NewHUD(u *univ.Universe) *HUD {
+	text := draw.DefaultFont(hudTextSize)
+
+	h := &HUD{
+		overlay:   u.Window.Overlay(),
+		speed:     draw.NewText(text, ""),
+		fuelLabel: draw.NewText(text, "Fuel"),
+		fuel:      draw.NewBar(160, 10),
+		objective: draw.NewText(text, ""),
+		prompt:    draw.NewText(draw.DefaultFont(hudPromptSize), ""),
+	}
+
+	h.speed.Place(draw.AnchorBottomLeft, mgl32.Vec2{hudMargin, -hudMargin})
+	h.fuelLabel.Place(draw.AnchorBottomLeft, mgl32.Vec2{hudMargin, -hudMargin - 30})
+	h.fuel.Place(draw.AnchorBottomLeft, mgl32.Vec2{hudMargin + 50, -hudMargin - 36})
+	h.fuel.SetColor(mgl32.Vec4{1, 0.75, 0.2, 1})
+	h.fuelLabel.SetVisible(false)
+	h.fuel.SetVisible(false)
+	h.objective.Place(draw.AnchorTopLeft, mgl32.Vec2{hudMargin, hudMargin})
+	h.prompt.Place(draw.AnchorBottom, mgl32.Vec2{0, -120})
+
+	for _, e := range []draw.Element{h.speed, h.fuelLabel, h.fuel, h.objective, h.prompt} {
+		h.overlay.Add(e)
+	}
+
+	h.ticker = draw.NewTicker(hudRefreshRate, h.tick)
+	h.ticker.Start()
+	return h
+}
+
+// Remove takes h off its window.
+func (h *HUD) Remove() {
+	h.ticker.Close()
+	for _, e := range []draw.Element{h.speed, h.fuelLabel, h.fuel, h.objective, h.prompt} {
+		h.overlay.Remove(e)
+	}
+}
+
+// Track shows the speed of b, the prompts offered to it, and its fuel if it's a ship added with AddShip.
+// A nil b tracks nothing.
+func (h *HUD) Track(b *univ.Body) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.tracked = b
+}
+
+// AddShip shows the fuel gauge of ship while its body is tracked.
+func (h *HUD) AddShip(ship *Ship) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.ships = append(h.ships, ship)
+}
+
+// SetObjective shows text as the player's objective. Empty text hides it.
+func (h *HUD) SetObjective(text string) {
+	h.objective.SetText(text)
+}
+
+// AddPrompter shows the prompts p offers the tracked body. Prompters added first take precedence.
+func (h *HUD) AddPrompter(p Prompter) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.prompters = append(h.prompters, p)
+}
+
+// RemovePrompter stops showing the prompts of p.
+func (h *HUD) RemovePrompter(p Prompter) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	for i, other := range h.prompters {
+		if other == p {
+			h.prompters = append(h.prompters[:i], h.prompters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (h *HUD) tick(elapsed float32) {
+	h.mut.Lock()
+	b := h.tracked
+	prompters := append([]Prompter(nil), h.prompters...)
+	var tank *Ship
+	for _, ship := range h.ships {
+		if b != nil && ship.Body == b {
+			tank = ship
+			break
+		}
+	}
+	h.mut.Unlock()
+
+	if tank != nil {
+		h.fuel.SetValue(tank.Fuel())
+	}
+	h.fuel.SetVisible(tank != nil)
+	h.fuelLabel.SetVisible(tank != nil)
+
+	if b == nil {
+		h.speed.SetText("")
+		h.prompt.SetText("")
+		return
+	}
+	h.speed.SetText(fmt.Sprintf("Speed %.1f", b.Velocity().Len()))
+
+	prompt := ""
+	for _, p := range prompters {
+		if prompt = p.Prompt(b); prompt != "" {
+			break
+		}
+	}
+	h.prompt.SetText(prompt)
+}
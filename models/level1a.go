@@ -4,29 +4,43 @@ import (
 	"log"
 
 	"github.com/lsmith130/space/draw"
+	"github.com/lsmith130/space/nav"
 	"github.com/lsmith130/space/univ"
 )
 
 type Level1A struct {
 	Body *univ.Body
 	u    *univ.Universe
+
+	navMesh *nav.Mesh
+	voxels  *nav.VoxelGraph
 }
 
 func NewLevel1A(u *univ.Universe) *Level1A {
-	tex, err := draw.NewTexture("models/cement.jpg")
+	tex, err := u.Texture("models/level1a.png")
 	if err != nil {
 		log.Fatal(err)
 	}
+	metal := draw.NewTextureMaterial("metal", tex)
 
-	metal, err := draw.NewTexture("models/level1a.png")
+	// The cement floors come with the model, but its metal texture is missing.
+	b, err := u.NewBody("models/game.dae", u.Window.GetStandardProgram(), univ.Materials{
+		"lambert1":               metal,
+		"bay_lambert2":           metal,
+		"introlevel1v3_lambert3": metal,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	b, err := u.NewBody("models/game.dae", u.Window.GetStandardProgram(), []*draw.Texture{tex, metal, metal, tex, metal})
+	ports, err := univ.LoadDockingPorts(b)
 	if err != nil {
 		log.Fatal(err)
 	}
+	for _, p := range ports {
+		p.Passive = true
+		u.Docking.AddPort(p)
+	}
 
 	return &Level1A{
 		Body: b,
@@ -37,3 +51,27 @@ func NewLevel1A(u *univ.Universe) *Level1A {
 func (l *Level1A) Remove() {
 	l.u.RemoveBody(l.Body)
 }
+
+// NavMesh returns the navigation mesh of the level's walkable floors, baking it on first use.
+func (l *Level1A) NavMesh() (*nav.Mesh, error) {
+	if l.navMesh == nil {
+		m, err := nav.LoadOrBake(l.Body, nav.DefaultConfig)
+		if err != nil {
+			return nil, err
+		}
+		l.navMesh = m
+	}
+	return l.navMesh, nil
+}
+
+// Voxels returns the voxel graph of the level's open space for flying agents, baking it on first use.
+func (l *Level1A) Voxels() (*nav.VoxelGraph, error) {
+	if l.voxels == nil {
+		g, err := nav.LoadOrBakeVoxels(l.Body, nav.DefaultVoxelConfig)
+		if err != nil {
+			return nil, err
+		}
+		l.voxels = g
+	}
+	return l.voxels, nil
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+	"github.com/lsmith130/space/univ"
+)
+
+// JetpackExhaust is the exhaust of an astronaut's suit thrusters.
+var JetpackExhaust = draw.ParticleEffect{
+	Rate:        60,
+	Lifetime:    600 * time.Millisecond,
+	Speed:       6,
+	SpeedSpread: 1.5,
+	Cone:        0.15,
+	Drag:        1.5,
+	Colors: []draw.ColorKey{
+		{Age: 0, Color: mgl32.Vec4{0.8, 0.9, 1, 0.8}},
+		{Age: 0.3, Color: mgl32.Vec4{0.5, 0.6, 0.8, 0.4}},
+		{Age: 1, Color: mgl32.Vec4{0.4, 0.4, 0.5, 0}},
+	},
+	Sizes: []draw.SizeKey{
+		{Age: 0, Size: 0.1},
+		{Age: 1, Size: 0.6},
+	},
+	Blend: draw.ParticleAdditive,
+}
+
+// BoosterPlume is the plume of a ship's main engine at full throttle.
+var BoosterPlume = draw.ParticleEffect{
+	Rate:        240,
+	Lifetime:    time.Second,
+	Speed:       25,
+	SpeedSpread: 4,
+	Cone:        0.08,
+	Radius:      0.3,
+	Drag:        0.8,
+	Colors: []draw.ColorKey{
+		{Age: 0, Color: mgl32.Vec4{0.6, 0.8, 1, 1}},
+		{Age: 0.15, Color: mgl32.Vec4{1, 0.7, 0.3, 0.8}},
+		{Age: 0.5, Color: mgl32.Vec4{0.8, 0.3, 0.1, 0.3}},
+		{Age: 1, Color: mgl32.Vec4{0.2, 0.1, 0.1, 0}},
+	},
+	Sizes: []draw.SizeKey{
+		{Age: 0, Size: 0.8},
+		{Age: 1, Size: 3},
+	},
+	Blend: draw.ParticleAdditive,
+}
+
+// CollisionSparks are the sparks thrown when bodies collide.
+var CollisionSparks = draw.ParticleEffect{
+	Lifetime:     700 * time.Millisecond,
+	Speed:        8,
+	SpeedSpread:  6,
+	Cone:         math.Pi,
+	Drag:         0.5,
+	MaxParticles: 512,
+	Colors: []draw.ColorKey{
+		{Age: 0, Color: mgl32.Vec4{1, 1, 0.8, 1}},
+		{Age: 0.4, Color: mgl32.Vec4{1, 0.6, 0.2, 1}},
+		{Age: 1, Color: mgl32.Vec4{0.6, 0.1, 0, 0}},
+	},
+	Sizes: []draw.SizeKey{
+		{Age: 0, Size: 0.15},
+		{Age: 1, Size: 0.05},
+	},
+	Blend: draw.ParticleAdditive,
+}
+
+const (
+	// sparkSpeed is the slowest collision that throws sparks.
+	sparkSpeed = 2
+	// sparksPerSpeed is the number of sparks thrown for each unit of speed of a collision.
+	sparksPerSpeed = 4
+	// maxSparks is the most sparks thrown by one collision.
+	maxSparks = 80
+)
+
+// Sparks throws sparks from where the bodies of a universe collide.
+type Sparks struct {
+	u       *univ.Universe
+	emitter *draw.Emitter
+}
+
+// NewSparks starts throwing sparks from the collisions of u's bodies.
+func NewSparks(u *univ.Universe) *Sparks {
+	s := &Sparks{
+		u:       u,
+		emitter: draw.NewEmitter(CollisionSparks),
+	}
+	u.AddEmitter(s.emitter)
+	u.Damage.AddCollisionObserver(s)
+	return s
+}
+
+// Remove stops s throwing sparks.
+func (s *Sparks) Remove() {
+	s.u.Damage.RemoveCollisionObserver(s)
+	s.u.RemoveEmitter(s.emitter)
+}
+
+// Collided conforms to univ.CollisionObserver.Collided and should not be called directly.
+func (s *Sparks) Collided(c univ.Collision) {
+	if c.Speed < sparkSpeed {
+		return
+	}
+	n := int(c.Speed * sparksPerSpeed)
+	if n > maxSparks {
+		n = maxSparks
+	}
+	s.emitter.SetLocation(c.Point)
+	s.emitter.SetVelocity(c.Velocity)
+	s.emitter.Burst(n)
+}
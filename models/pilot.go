@@ -0,0 +1,73 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/univ"
+)
+
+// shipCameraOffset is the location of the chase camera relative to a ship being flown.
+var shipCameraOffset = mgl32.Vec3{0, 4, -18}
+
+// Pilot tracks whether the player is controlling their astronaut or a ship the astronaut has boarded,
+// and keeps a chase camera on whichever of them is being controlled.
+type Pilot struct {
+	mut        sync.Mutex
+	astronaut  *Astronaut
+	ship       *Ship
+	cam        *univ.ChaseCam
+	footOffset mgl32.Vec3
+}
+
+// NewPilot creates a new Pilot controlling a, with cam chasing it.
+func NewPilot(a *Astronaut, cam *univ.ChaseCam) *Pilot {
+	cam.SetTarget(a.Body)
+	return &Pilot{
+		astronaut: a,
+		cam:       cam,
+	}
+}
+
+// Ship returns the ship being flown, or nil if the player is controlling the astronaut.
+func (p *Pilot) Ship() *Ship {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return p.ship
+}
+
+// Controlled returns the body the player is currently controlling.
+func (p *Pilot) Controlled() *univ.Body {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.ship != nil {
+		return p.ship.Body
+	}
+	return p.astronaut.Body
+}
+
+// ToggleBoard exits the ship being flown, or boards the first of ships whose hatch is in range of the
+// astronaut. ToggleBoard reports whether control changed.
+func (p *Pilot) ToggleBoard(ships ...*Ship) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.ship != nil {
+		p.ship.Exit()
+		p.ship = nil
+		p.cam.SetTarget(p.astronaut.Body)
+		p.cam.SetLocation(p.footOffset)
+		return true
+	}
+
+	for _, ship := range ships {
+		if ship.Board(p.astronaut) {
+			p.ship = ship
+			p.footOffset = p.cam.Location()
+			p.cam.SetTarget(ship.Body)
+			p.cam.SetLocation(shipCameraOffset)
+			return true
+		}
+	}
+	return false
+}
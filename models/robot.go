@@ -3,7 +3,6 @@ package models
 import (
 	"log"
 
-	"github.com/lsmith130/space/draw"
 	"github.com/lsmith130/space/univ"
 )
 
@@ -14,24 +13,7 @@ type Robot struct {
 
 func NewRobot(u *univ.Universe) *Robot {
 
-	head, err := draw.NewTexture("models/Material Diffuse Color.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-	body, err := draw.NewTexture("models/Material.001 Diffuse Color.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-	leftfoot, err := draw.NewTexture("models/Material.002 Diffuse Color.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-	rightfoot, err := draw.NewTexture("models/Material.003 Diffuse Color.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	b, err := u.NewBody("models/robot.dae", u.Window.GetStandardProgram(), []*draw.Texture{head, head, leftfoot, rightfoot, body})
+	b, err := u.NewBody("models/robot.dae", u.Window.GetStandardProgram(), nil)
 	if err != nil {
 		log.Fatal(err)
 	}
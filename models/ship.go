@@ -4,6 +4,7 @@ import (
 	// "fmt"
 
 	"log"
+	"sync"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
@@ -11,15 +12,68 @@ import (
 	"github.com/lsmith130/space/univ"
 )
 
+// ShipControl is a thruster control of a Ship that can be switched on and off.
+type ShipControl int
+
+const (
+	ShipPitchUp ShipControl = iota
+	ShipPitchDown
+	ShipYawLeft
+	ShipYawRight
+	ShipRollLeft
+	ShipRollRight
+	ShipStrafeLeft
+	ShipStrafeRight
+	ShipStrafeUp
+	ShipStrafeDown
+	ShipStrafeForward
+	ShipStrafeBack
+	shipControlCount
+)
+
+const (
+	// shipMainThrust is the forward acceleration of the main engine at full throttle.
+	shipMainThrust = 40
+	// shipStrafeThrust is the acceleration of the maneuvering thrusters along each axis.
+	shipStrafeThrust = 10
+	// shipRotationThrust is the angular acceleration of the maneuvering thrusters around each axis.
+	shipRotationThrust = 1.5
+	// shipAssistDamping is the fraction of drift and spin flight assist removes per second.
+	shipAssistDamping = 1.5
+	// shipHatchRange is how close an astronaut must be to the hatch to board.
+	shipHatchRange = 5
+	// shipBurnTime is how many seconds a full tank lasts the main engine at full throttle.
+	shipBurnTime = 120
+	// shipThrusterBurn is how much fuel each maneuvering control burns, as a fraction of the main engine
+	// at full throttle.
+	shipThrusterBurn = 0.1
+)
+
+// shipHatch is the location of the hatch relative to the ship.
+var shipHatch = mgl32.Vec3{-4, 0, 0}
+
+// shipBoosters are the locations of the main engine's nozzles relative to the ship.
+var shipBoosters = []mgl32.Vec3{{2.5, 0, -7.2}, {-2.5, 0, -7.2}}
+
 type Ship struct {
 	*univ.Body
-	u      *univ.Universe
-	ticker *draw.Ticker
+	u         *univ.Universe
+	ticker    *draw.Ticker
+	autopilot *Autopilot
+	health    *univ.Health
+	plumes    []*draw.Emitter
+
+	mut          sync.Mutex
+	pilot        *Astronaut
+	controls     [shipControlCount]bool
+	throttle     float32
+	flightAssist bool
+	fuel         float32
 }
 
 func NewShip(u *univ.Universe) *Ship {
 
-	body, err := draw.NewTexture("models/ship_body.png")
+	body, err := u.Texture("models/ship_body.png")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -27,21 +81,60 @@ func NewShip(u *univ.Universe) *Ship {
 	// if err != nil {
 	// 	log.Fatal(err)
 	// }
-	booster, err := draw.NewTexture("models/ship_boosters.png")
+	booster, err := u.Texture("models/ship_boosters.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b, err := u.NewBody("models/ship.dae", u.Window.GetStandardProgram(), univ.Materials{
+		"lambert1": draw.NewTextureMaterial("booster", booster),
+		"phong1":   draw.NewTextureMaterial("body", body),
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	b, err := u.NewBody("models/ship.dae", u.Window.GetStandardProgram(), []*draw.Texture{booster, body})
+	ports, err := univ.LoadDockingPorts(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range ports {
+		u.Docking.AddPort(p)
+	}
+
+	goal, err := u.Texture("models/goal.png")
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	ship := &Ship{
-		Body: b,
-		u:    u,
+		Body:         b,
+		u:            u,
+		flightAssist: true,
+		fuel:         1,
+		health:       univ.NewHealth(b, 250),
+	}
+	ship.health.Armor = 2
+	ship.health.Resistances[univ.DamageHeat] = 0.5
+	ship.health.Debris = &univ.Debris{
+		Model: "models/goal.dae",
+		Materials: univ.Materials{
+			univ.AllMaterials: draw.NewTextureMaterial("goal", goal),
+		},
+		Count:    8,
+		Speed:    6,
+		Lifetime: 20 * time.Second,
 	}
+	u.Damage.AddHealth(ship.health)
 
+	for _, nozzle := range shipBoosters {
+		e := draw.NewEmitter(BoosterPlume)
+		b.AttachEmitter(e, nozzle, mgl32.Vec3{0, 0, -1})
+		u.AddEmitter(e)
+		ship.plumes = append(ship.plumes, e)
+	}
+
+	ship.autopilot = NewAutopilot(ship)
 	ship.ticker = draw.NewTicker(univ.DefaultRefreshRate, ship.tick)
 	ship.ticker.Start()
 
@@ -49,11 +142,220 @@ func NewShip(u *univ.Universe) *Ship {
 }
 
 func (ship *Ship) tick(elapsed float32) {
-	offset := (float32((time.Now().UnixNano()/1000%3)-1) / 50.0) + 5
-	loc := mgl32.Vec3{ship.Body.Location().X(), float32(offset), ship.Body.Location().Z()}
-	ship.Body.SetLocation(loc)
+	ship.mut.Lock()
+	controls := ship.controls
+	throttle := ship.throttle
+	assist := ship.flightAssist
+	pilot := ship.pilot
+	empty := ship.fuel <= 0
+	ship.mut.Unlock()
+
+	// The thrusters don't fire without fuel.
+	if empty {
+		controls = [shipControlCount]bool{}
+		throttle = 0
+	}
+	burn := throttle
+	for _, on := range controls {
+		if on {
+			burn += shipThrusterBurn
+		}
+	}
+	ship.mut.Lock()
+	ship.fuel = mgl32.Clamp(ship.fuel-burn*elapsed/shipBurnTime, 0, 1)
+	ship.mut.Unlock()
+
+	axis := func(positive, negative ShipControl) float32 {
+		var v float32
+		if controls[positive] {
+			v++
+		}
+		if controls[negative] {
+			v--
+		}
+		return v
+	}
+
+	// Inputs are in the ship's local space, where +z is forward, +x is left and +y is up.
+	linear := mgl32.Vec3{
+		axis(ShipStrafeLeft, ShipStrafeRight) * shipStrafeThrust,
+		axis(ShipStrafeUp, ShipStrafeDown) * shipStrafeThrust,
+		axis(ShipStrafeForward, ShipStrafeBack)*shipStrafeThrust + throttle*shipMainThrust,
+	}
+	angular := mgl32.Vec3{
+		axis(ShipPitchDown, ShipPitchUp),
+		axis(ShipYawLeft, ShipYawRight),
+		axis(ShipRollRight, ShipRollLeft),
+	}.Mul(shipRotationThrust)
+
+	// The plumes grow with the throttle.
+	for _, e := range ship.plumes {
+		e.SetRate(throttle * BoosterPlume.Rate)
+		e.SetOn(throttle > 0)
+	}
+
+	rot := ship.Rotation()
+	ship.AddVelocity(rot.Rotate(linear.Mul(elapsed)))
+	ship.AddAngularV(rot.Rotate(angular.Mul(elapsed)))
+
+	// The autopilot steers with thrusters of its own, which assist would fight against.
+	if assist && ship.autopilot.Mode() == AutopilotOff {
+		// Damp spin around each axis that isn't being turned, and drift along each axis that isn't being
+		// thrust along, leaving forward motion to the throttle.
+		damping := 1 - mgl32.Clamp(shipAssistDamping*elapsed, 0, 1)
+		inverse := rot.Inverse()
+
+		localAngularV := inverse.Rotate(ship.AngularV())
+		localV := inverse.Rotate(ship.Velocity())
+		for i := 0; i < 3; i++ {
+			if angular[i] == 0 {
+				localAngularV[i] *= damping
+			}
+			if i < 2 && linear[i] == 0 {
+				localV[i] *= damping
+			}
+		}
+		ship.SetAngularV(rot.Rotate(localAngularV))
+		ship.SetVelocity(rot.Rotate(localV))
+	}
+
+	if pilot != nil {
+		pilot.SetLocation(ship.Location())
+		pilot.SetRotation(ship.Rotation())
+	}
+}
+
+// SetControl switches a thruster control of ship on or off.
+func (ship *Ship) SetControl(control ShipControl, enable bool) {
+	ship.mut.Lock()
+	ship.controls[control] = enable
+	ship.mut.Unlock()
+}
+
+// Throttle returns the main engine throttle of ship, between 0 and 1.
+func (ship *Ship) Throttle() float32 {
+	ship.mut.Lock()
+	defer ship.mut.Unlock()
+	return ship.throttle
+}
+
+// SetThrottle sets the main engine throttle of ship, clamped between 0 and 1.
+func (ship *Ship) SetThrottle(throttle float32) {
+	ship.mut.Lock()
+	ship.throttle = mgl32.Clamp(throttle, 0, 1)
+	ship.mut.Unlock()
+}
+
+// AddThrottle adjusts the main engine throttle of ship by delta, clamped between 0 and 1.
+func (ship *Ship) AddThrottle(delta float32) {
+	ship.mut.Lock()
+	ship.throttle = mgl32.Clamp(ship.throttle+delta, 0, 1)
+	ship.mut.Unlock()
+}
+
+// FlightAssist returns whether flight assist is damping ship's rotation and drift. Assist is suspended
+// while the autopilot is flying.
+func (ship *Ship) FlightAssist() bool {
+	ship.mut.Lock()
+	defer ship.mut.Unlock()
+	return ship.flightAssist
+}
+
+// SetFlightAssist sets whether flight assist damps ship's rotation and drift.
+func (ship *Ship) SetFlightAssist(enable bool) {
+	ship.mut.Lock()
+	ship.flightAssist = enable
+	ship.mut.Unlock()
+}
+
+// Fuel returns how full ship's tank is, from 0 to 1. The thrusters don't fire once it's empty.
+func (ship *Ship) Fuel() float32 {
+	ship.mut.Lock()
+	defer ship.mut.Unlock()
+	return ship.fuel
+}
+
+// Refuel fills ship's tank.
+func (ship *Ship) Refuel() {
+	ship.mut.Lock()
+	ship.fuel = 1
+	ship.mut.Unlock()
+}
+
+// Health returns the health of ship.
+func (ship *Ship) Health() *univ.Health {
+	return ship.health
+}
+
+// Autopilot returns the autopilot of ship.
+func (ship *Ship) Autopilot() *Autopilot {
+	return ship.autopilot
+}
+
+// HatchLocation returns the current location of ship's hatch.
+func (ship *Ship) HatchLocation() mgl32.Vec3 {
+	return ship.Location().Add(ship.Rotation().Rotate(shipHatch))
+}
+
+// Pilot returns the astronaut flying ship, or nil if it is empty.
+func (ship *Ship) Pilot() *Astronaut {
+	ship.mut.Lock()
+	defer ship.mut.Unlock()
+	return ship.pilot
+}
+
+// Board puts a inside ship as its pilot if a is close enough to the hatch and ship is empty, and
+// reports whether a boarded. Boarding repairs a's suit and refills its oxygen.
+func (ship *Ship) Board(a *Astronaut) bool {
+	if a.Location().Sub(ship.HatchLocation()).Len() > shipHatchRange {
+		return false
+	}
+
+	ship.mut.Lock()
+	defer ship.mut.Unlock()
+	if ship.pilot != nil {
+		return false
+	}
+	ship.pilot = a
+
+	a.halt()
+	a.SetHidden(true)
+	// The ship repairs and refills the pilot's suit, and supplies its air while it's inside.
+	a.RepairSuit()
+	a.RefillOxygen()
+	a.setBoarded(true)
+	// The pilot is protected by the ship while inside it.
+	ship.u.Damage.RemoveHealth(a.health)
+	return true
+}
+
+// Exit puts ship's pilot back outside at the hatch, moving with the ship, and returns it. Exit returns
+// nil if ship is empty.
+func (ship *Ship) Exit() *Astronaut {
+	ship.mut.Lock()
+	a := ship.pilot
+	ship.pilot = nil
+	ship.controls = [shipControlCount]bool{}
+	ship.throttle = 0
+	ship.mut.Unlock()
+
+	if a == nil {
+		return nil
+	}
+
+	a.SetLocation(ship.HatchLocation())
+	a.SetRotation(ship.Rotation())
+	a.SetVelocity(ship.Velocity())
+	a.SetHidden(false)
+	a.setBoarded(false)
+	// A pilot already destroyed while inside must not have its body removed again as it steps out.
+	if !a.health.Destroyed() {
+		ship.u.Damage.AddHealth(a.health)
+	}
+	return a
 }
 
 func (r *Ship) Remove() {
+	r.u.Damage.RemoveHealth(r.health)
 	r.u.RemoveBody(r.Body)
 }
@@ -0,0 +1,66 @@
+package nav
+
+import "container/heap"
+
+// astar finds the cheapest route between the start and goal nodes of a graph. neighbors calls visit for
+// each node adjacent to a node along with the cost of moving to it, and heuristic estimates the remaining
+// cost from a node to goal without overestimating. astar returns nil if goal can't be reached.
+func astar(start, goal int, neighbors func(node int, visit func(next int, cost float32)), heuristic func(node int) float32) []int {
+	cost := map[int]float32{start: 0}
+	parent := map[int]int{}
+	closed := map[int]struct{}{}
+
+	open := &openSet{{node: start, priority: heuristic(start)}}
+	for open.Len() > 0 {
+		current := heap.Pop(open).(openNode).node
+		if current == goal {
+			path := []int{goal}
+			for current != start {
+				current = parent[current]
+				path = append(path, current)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		if _, ok := closed[current]; ok {
+			continue
+		}
+		closed[current] = struct{}{}
+
+		neighbors(current, func(next int, stepCost float32) {
+			if _, ok := closed[next]; ok {
+				return
+			}
+			nextCost := cost[current] + stepCost
+			if c, ok := cost[next]; ok && c <= nextCost {
+				return
+			}
+			cost[next] = nextCost
+			parent[next] = current
+			heap.Push(open, openNode{node: next, priority: nextCost + heuristic(next)})
+		})
+	}
+	return nil
+}
+
+type openNode struct {
+	node     int
+	priority float32
+}
+
+// openSet is a min-heap of nodes ordered by priority. Nodes may appear more than once, in which case
+// the stale entries are skipped once the node is closed.
+type openSet []openNode
+
+func (s openSet) Len() int            { return len(s) }
+func (s openSet) Less(i, j int) bool  { return s[i].priority < s[j].priority }
+func (s openSet) Swap(i, j int)       { s[i], s[j] = s[j], s[i] }
+func (s *openSet) Push(x interface{}) { *s = append(*s, x.(openNode)) }
+func (s *openSet) Pop() interface{} {
+	old := *s
+	n := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,41 @@
+package nav
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAstar(t *testing.T) {
+	// A graph of weighted edges, where the route from 0 to 3 through 1 has fewer steps but costs more
+	// than the route through 2 and 4. Node 5 is unconnected.
+	edges := map[int]map[int]float32{
+		0: {1: 1, 2: 1},
+		1: {0: 1, 3: 10},
+		2: {0: 1, 4: 1},
+		3: {1: 10, 4: 1},
+		4: {2: 1, 3: 1},
+	}
+	neighbors := func(node int, visit func(int, float32)) {
+		for next, cost := range edges[node] {
+			visit(next, cost)
+		}
+	}
+	noHeuristic := func(int) float32 { return 0 }
+
+	tests := []struct {
+		name        string
+		start, goal int
+		want        []int
+	}{
+		{"start is goal", 0, 0, []int{0}},
+		{"one step", 0, 1, []int{0, 1}},
+		{"cheapest", 0, 3, []int{0, 2, 4, 3}},
+		{"backwards", 3, 0, []int{3, 4, 2, 0}},
+		{"unreachable", 0, 5, nil},
+	}
+	for _, test := range tests {
+		if got := astar(test.start, test.goal, neighbors, noHeuristic); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
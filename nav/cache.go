@@ -0,0 +1,114 @@
+package nav
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+
+	"github.com/lsmith130/space/univ"
+)
+
+// cacheVersion is written to cache files and must be incremented whenever the baked formats change.
+const cacheVersion = 1
+
+type cacheHeader struct {
+	Version int
+	Hash    uint64
+}
+
+// LoadOrBake returns the navigation mesh for b. The mesh is loaded from a cache file next to b's model
+// when one exists for the same geometry and cfg, and is otherwise baked and written to the cache.
+func LoadOrBake(b *univ.Body, cfg Config) (*Mesh, error) {
+	tris := b.Triangles()
+	path := b.ModelPath() + ".navmesh"
+	hash := hashTriangles(tris, cfg)
+
+	var m Mesh
+	if loadCache(path, hash, &m) {
+		return &m, nil
+	}
+
+	baked := Bake(tris, cfg)
+	if err := saveCache(path, hash, baked); err != nil {
+		return nil, fmt.Errorf("cache navmesh %s: %v", path, err)
+	}
+	return baked, nil
+}
+
+// LoadOrBakeVoxels returns the voxel graph for b, caching it next to b's model like LoadOrBake.
+func LoadOrBakeVoxels(b *univ.Body, cfg VoxelConfig) (*VoxelGraph, error) {
+	tris := b.Triangles()
+	path := b.ModelPath() + ".navvoxels"
+	hash := hashTriangles(tris, cfg)
+
+	var g VoxelGraph
+	if loadCache(path, hash, &g) {
+		return &g, nil
+	}
+
+	baked := BakeVoxels(tris, cfg)
+	if err := saveCache(path, hash, baked); err != nil {
+		return nil, fmt.Errorf("cache voxel graph %s: %v", path, err)
+	}
+	return baked, nil
+}
+
+// loadCache decodes the cache file at path into v, returning false if the file is missing, unreadable
+// or was baked from different input.
+func loadCache(path string, hash uint64, v interface{}) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	var header cacheHeader
+	if err := dec.Decode(&header); err != nil || header.Version != cacheVersion || header.Hash != hash {
+		return false
+	}
+	if err := dec.Decode(v); err != nil {
+		log.Printf("read %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+func saveCache(path string, hash uint64, v interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	enc := gob.NewEncoder(f)
+	if err := enc.Encode(cacheHeader{Version: cacheVersion, Hash: hash}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := enc.Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// hashTriangles hashes tris along with the config they will be baked with, so that changes to either
+// invalidate the cache.
+func hashTriangles(tris []univ.Triangle, cfg interface{}) uint64 {
+	h := fnv.New64a()
+	var buf [4]byte
+	for _, tri := range tris {
+		for _, v := range tri {
+			for _, f := range v {
+				binary.LittleEndian.PutUint32(buf[:], math.Float32bits(f))
+				h.Write(buf[:])
+			}
+		}
+	}
+	fmt.Fprintf(h, "%+v", cfg)
+	return h.Sum64()
+}
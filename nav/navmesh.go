@@ -0,0 +1,236 @@
+// Package nav generates navigation data from level geometry and finds paths through it.
+package nav
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/univ"
+)
+
+// Config controls how a Mesh is generated from level geometry.
+type Config struct {
+	// Up is the direction agents stand upright in.
+	Up mgl32.Vec3
+	// MaxSlope is the steepest angle away from Up, in radians, that an agent can walk on.
+	MaxSlope float32
+	// WeldDistance is the distance within which the corners of neighboring triangles are joined.
+	WeldDistance float32
+	// MaxStepHeight is the greatest distance a point can be above or below the mesh and still be on it.
+	MaxStepHeight float32
+}
+
+// DefaultConfig is a Config suited to an astronaut walking on station floors.
+var DefaultConfig = Config{
+	Up:            mgl32.Vec3{0, 1, 0},
+	MaxSlope:      mgl32.DegToRad(45),
+	WeldDistance:  0.01,
+	MaxStepHeight: 2,
+}
+
+// Mesh is a navigation mesh made of the walkable triangles of a level.
+type Mesh struct {
+	Config   Config
+	Vertices []mgl32.Vec3
+	Polys    []Poly
+}
+
+// Poly is a walkable triangle of a Mesh.
+type Poly struct {
+	// Verts are indexes into the Vertices of the mesh, wound counter-clockwise around Config.Up.
+	Verts [3]int32
+	// Neighbors[i] is the index of the poly sharing the edge from Verts[i] to Verts[(i+1)%3], or -1 if the
+	// edge is on the boundary of the mesh.
+	Neighbors [3]int32
+	Center    mgl32.Vec3
+}
+
+// Bake builds a Mesh from the triangles in tris that are flat enough to walk on.
+//
+// Triangles are expected to be wound counter-clockwise when viewed from above, so ceilings aren't
+// mistaken for floors.
+func Bake(tris []univ.Triangle, cfg Config) *Mesh {
+	up := cfg.Up.Normalize()
+	minCos := float32(math.Cos(float64(cfg.MaxSlope)))
+	m := &Mesh{Config: cfg}
+
+	welded := make(map[[3]int64]int32)
+	weld := func(v mgl32.Vec3) int32 {
+		var key [3]int64
+		for i := range key {
+			key[i] = int64(math.Floor(float64(v[i] / cfg.WeldDistance)))
+		}
+		if index, ok := welded[key]; ok {
+			return index
+		}
+		index := int32(len(m.Vertices))
+		m.Vertices = append(m.Vertices, v)
+		welded[key] = index
+		return index
+	}
+
+	for _, tri := range tris {
+		normal := tri[1].Sub(tri[0]).Cross(tri[2].Sub(tri[0]))
+		if normal.Len() == 0 || normal.Normalize().Dot(up) < minCos {
+			continue
+		}
+
+		poly := Poly{
+			Verts:     [3]int32{weld(tri[0]), weld(tri[1]), weld(tri[2])},
+			Neighbors: [3]int32{-1, -1, -1},
+			Center:    tri.Center(),
+		}
+		if poly.Verts[0] == poly.Verts[1] || poly.Verts[1] == poly.Verts[2] || poly.Verts[2] == poly.Verts[0] {
+			continue
+		}
+		m.Polys = append(m.Polys, poly)
+	}
+
+	// Link polys that share an edge. Edges shared by more than two polys only link the first pair.
+	type edgeRef struct{ poly, edge int }
+	edges := make(map[[2]int32]edgeRef)
+	for p := range m.Polys {
+		for e := 0; e < 3; e++ {
+			a, b := m.Polys[p].Verts[e], m.Polys[p].Verts[(e+1)%3]
+			key := [2]int32{a, b}
+			if a > b {
+				key = [2]int32{b, a}
+			}
+			other, ok := edges[key]
+			if !ok {
+				edges[key] = edgeRef{p, e}
+				continue
+			}
+			if m.Polys[other.poly].Neighbors[other.edge] == -1 {
+				m.Polys[other.poly].Neighbors[other.edge] = int32(p)
+				m.Polys[p].Neighbors[e] = int32(other.poly)
+			}
+		}
+	}
+
+	return m
+}
+
+// FindPath returns a smoothed path across m from start to end, including both points. ok is false if
+// either point isn't on m or no path connects them.
+func (m *Mesh) FindPath(start, end mgl32.Vec3) (path []mgl32.Vec3, ok bool) {
+	startPoly, endPoly := m.Locate(start), m.Locate(end)
+	if startPoly < 0 || endPoly < 0 {
+		return nil, false
+	}
+	if startPoly == endPoly {
+		return []mgl32.Vec3{start, end}, true
+	}
+
+	polys := astar(startPoly, endPoly, func(node int, visit func(int, float32)) {
+		for _, n := range m.Polys[node].Neighbors {
+			if n >= 0 {
+				visit(int(n), m.Polys[node].Center.Sub(m.Polys[n].Center).Len())
+			}
+		}
+	}, func(node int) float32 {
+		return m.Polys[node].Center.Sub(end).Len()
+	})
+	if polys == nil {
+		return nil, false
+	}
+
+	return m.smooth(start, end, polys), true
+}
+
+// Locate returns the index of the poly that p is standing on, or -1 if p isn't on m.
+func (m *Mesh) Locate(p mgl32.Vec3) int {
+	up := m.Config.Up.Normalize()
+	best := -1
+	bestHeight := m.Config.MaxStepHeight
+
+	for i, poly := range m.Polys {
+		a, b, c := m.Vertices[poly.Verts[0]], m.Vertices[poly.Verts[1]], m.Vertices[poly.Verts[2]]
+		if side(up, a, b, p) < 0 || side(up, b, c, p) < 0 || side(up, c, a, p) < 0 {
+			continue
+		}
+
+		// Distance along up from p to the plane of the poly.
+		normal := b.Sub(a).Cross(c.Sub(a))
+		height := float32(math.Abs(float64(normal.Dot(p.Sub(a)) / normal.Dot(up))))
+		if height <= bestHeight {
+			best = i
+			bestHeight = height
+		}
+	}
+
+	return best
+}
+
+// smooth pulls the path through the centers of polys taut around corners using the simple stupid
+// funnel algorithm.
+func (m *Mesh) smooth(start, end mgl32.Vec3, polys []int) []mgl32.Vec3 {
+	up := m.Config.Up.Normalize()
+
+	// Build the portals crossed between each pair of polys, as left and right points relative to the
+	// direction of travel.
+	lefts := []mgl32.Vec3{start}
+	rights := []mgl32.Vec3{start}
+	for i := 0; i < len(polys)-1; i++ {
+		poly := m.Polys[polys[i]]
+		for e, n := range poly.Neighbors {
+			if int(n) != polys[i+1] {
+				continue
+			}
+			// Polys are counter-clockwise, so leaving through an edge has its end on the left.
+			lefts = append(lefts, m.Vertices[poly.Verts[(e+1)%3]])
+			rights = append(rights, m.Vertices[poly.Verts[e]])
+			break
+		}
+	}
+	lefts = append(lefts, end)
+	rights = append(rights, end)
+
+	path := []mgl32.Vec3{start}
+	apex, left, right := start, start, start
+	apexIndex, leftIndex, rightIndex := 0, 0, 0
+
+	for i := 1; i < len(lefts); i++ {
+		// Try to narrow the right side of the funnel.
+		if side(up, apex, right, rights[i]) >= 0 {
+			if apex == right || side(up, apex, left, rights[i]) <= 0 {
+				right = rights[i]
+				rightIndex = i
+			} else {
+				// The right side crossed the left, so the left point is a corner of the path.
+				path = append(path, left)
+				apex, apexIndex = left, leftIndex
+				left, right = apex, apex
+				leftIndex, rightIndex = apexIndex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+
+		// Try to narrow the left side of the funnel.
+		if side(up, apex, left, lefts[i]) <= 0 {
+			if apex == left || side(up, apex, right, lefts[i]) >= 0 {
+				left = lefts[i]
+				leftIndex = i
+			} else {
+				path = append(path, right)
+				apex, apexIndex = right, rightIndex
+				left, right = apex, apex
+				leftIndex, rightIndex = apexIndex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+	}
+
+	if path[len(path)-1] != end {
+		path = append(path, end)
+	}
+	return path
+}
+
+// side returns a positive value if c is to the left of the line from a to b when viewed from above,
+// a negative value if it is to the right, and zero if it is on the line.
+func side(up, a, b, c mgl32.Vec3) float32 {
+	return b.Sub(a).Cross(c.Sub(a)).Dot(up)
+}
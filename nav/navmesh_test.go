@@ -0,0 +1,187 @@
+package nav
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/univ"
+)
+
+// floor returns the triangles of a floor of 1 unit squares in the plane y = 0, one for each '#' in rows,
+// where rows run along z and their characters along x. Each square is split into two triangles wound
+// counter-clockwise from above.
+func floor(rows ...string) []univ.Triangle {
+	var tris []univ.Triangle
+	for z, row := range rows {
+		for x, c := range row {
+			if c != '#' {
+				continue
+			}
+			x0, z0, x1, z1 := float32(x), float32(z), float32(x+1), float32(z+1)
+			tris = append(tris,
+				univ.Triangle{{x0, 0, z0}, {x0, 0, z1}, {x1, 0, z0}},
+				univ.Triangle{{x1, 0, z0}, {x0, 0, z1}, {x1, 0, z1}},
+			)
+		}
+	}
+	return tris
+}
+
+func TestBake(t *testing.T) {
+	square := floor("#")
+	tests := []struct {
+		name       string
+		tris       []univ.Triangle
+		polys      int
+		vertices   int
+		neighbored int
+	}{
+		{"square", square, 2, 4, 2},
+		{"welded", append(floor("#"), univ.Triangle{{1.004, 0, 0}, {1, 0, 1.004}, {2, 0, 0}}), 3, 5, 4},
+		{"ceiling", []univ.Triangle{{{0, 2, 0}, {1, 2, 0}, {0, 2, 1}}}, 0, 0, 0},
+		{"wall", []univ.Triangle{{{0, 0, 0}, {0, 1, 0}, {0, 0, 1}}}, 0, 0, 0},
+		{"gentle ramp", []univ.Triangle{{{0, 0, 0}, {0, 0, 1}, {1, 0.5, 0}}}, 1, 3, 0},
+		{"steep ramp", []univ.Triangle{{{0, 0, 0}, {0, 0, 1}, {1, 2, 0}}}, 0, 0, 0},
+		{"degenerate", []univ.Triangle{{{0, 0, 0}, {0, 0, 1}, {0.001, 0, 0}}}, 0, 2, 0},
+	}
+	for _, test := range tests {
+		m := Bake(test.tris, DefaultConfig)
+		if len(m.Polys) != test.polys || len(m.Vertices) != test.vertices {
+			t.Errorf("%s: got %v polys and %v vertices, want %v and %v", test.name, len(m.Polys), len(m.Vertices), test.polys, test.vertices)
+			continue
+		}
+
+		// Neighbors are linked both ways across the edge they share.
+		neighbored := 0
+		for p, poly := range m.Polys {
+			for e, n := range poly.Neighbors {
+				if n < 0 {
+					continue
+				}
+				neighbored++
+				other := m.Polys[n]
+				a, b := poly.Verts[e], poly.Verts[(e+1)%3]
+				linked := false
+				for oe, on := range other.Neighbors {
+					if int(on) == p && other.Verts[oe] == b && other.Verts[(oe+1)%3] == a {
+						linked = true
+					}
+				}
+				if !linked {
+					t.Errorf("%s: poly %v links to %v across edge %v, which doesn't link back", test.name, p, n, e)
+				}
+			}
+		}
+		if neighbored != test.neighbored {
+			t.Errorf("%s: got %v linked edges, want %v", test.name, neighbored, test.neighbored)
+		}
+	}
+}
+
+func TestMeshFindPath(t *testing.T) {
+	open := Bake(floor(
+		"####",
+		"####",
+		"####",
+		"####",
+	), DefaultConfig)
+	// A wall across the middle of the floor leaves a gap on either side, the left one closer.
+	walled := Bake(floor(
+		"#####",
+		"#####",
+		"#...#",
+		"#####",
+		"#####",
+	), DefaultConfig)
+	// A corridor turning a corner at (3, 1).
+	corner := Bake(floor(
+		"####",
+		"...#",
+		"...#",
+		"...#",
+	), DefaultConfig)
+	islands := Bake(floor(
+		"##.##",
+	), DefaultConfig)
+
+	diagonal := float32(math.Sqrt2)
+	tests := []struct {
+		name       string
+		m          *Mesh
+		start, end mgl32.Vec3
+		ok         bool
+		// corners are the points the path turns at, between start and end.
+		corners []mgl32.Vec3
+		length  float32
+	}{
+		{"same point", open, mgl32.Vec3{1.2, 0, 1.7}, mgl32.Vec3{1.2, 0, 1.7}, true, nil, 0},
+		{"same poly", open, mgl32.Vec3{1.1, 0, 1.2}, mgl32.Vec3{1.3, 0, 1.1}, true, nil, float32(math.Hypot(0.2, 0.1))},
+		{"adjacent polys", open, mgl32.Vec3{1.1, 0, 1.1}, mgl32.Vec3{1.9, 0, 1.9}, true, nil, 0.8 * diagonal},
+		{"straight", open, mgl32.Vec3{0.5, 0, 0.5}, mgl32.Vec3{3.5, 0, 0.5}, true, nil, 3},
+		// The diagonal passes exactly through the corners of polys it crosses.
+		{"straight through vertices", open, mgl32.Vec3{0.5, 0, 0.5}, mgl32.Vec3{3.5, 0, 3.5}, true, nil, 3 * diagonal},
+		{
+			"around the wall", walled, mgl32.Vec3{1.5, 0, 0.5}, mgl32.Vec3{1.5, 0, 4.5}, true,
+			[]mgl32.Vec3{{1, 0, 2}, {1, 0, 3}}, 2*float32(math.Hypot(0.5, 1.5)) + 1,
+		},
+		{"left turn", corner, mgl32.Vec3{0.5, 0, 0.5}, mgl32.Vec3{3.5, 0, 3.5}, true, []mgl32.Vec3{{3, 0, 1}}, float32(math.Hypot(2.5, 0.5)) + float32(math.Hypot(0.5, 2.5))},
+		{"right turn", corner, mgl32.Vec3{3.5, 0, 3.5}, mgl32.Vec3{0.5, 0, 0.5}, true, []mgl32.Vec3{{3, 0, 1}}, float32(math.Hypot(2.5, 0.5)) + float32(math.Hypot(0.5, 2.5))},
+		{"off the mesh", open, mgl32.Vec3{0.5, 0, 0.5}, mgl32.Vec3{5.5, 0, 0.5}, false, nil, 0},
+		{"too far above", open, mgl32.Vec3{0.5, 0, 0.5}, mgl32.Vec3{1.5, 3, 0.5}, false, nil, 0},
+		{"disconnected", islands, mgl32.Vec3{0.5, 0, 0.5}, mgl32.Vec3{4.5, 0, 0.5}, false, nil, 0},
+	}
+	for _, test := range tests {
+		path, ok := test.m.FindPath(test.start, test.end)
+		if ok != test.ok {
+			t.Errorf("%s: got ok %v, want %v", test.name, ok, test.ok)
+			continue
+		}
+		if !ok {
+			if path != nil {
+				t.Errorf("%s: got path %v with no route", test.name, path)
+			}
+			continue
+		}
+		if len(path) < 2 || path[0] != test.start || path[len(path)-1] != test.end {
+			t.Errorf("%s: got path %v, want it from %v to %v", test.name, path, test.start, test.end)
+			continue
+		}
+		if corners := path[1 : len(path)-1]; !pointsEqual(corners, test.corners) {
+			t.Errorf("%s: got path %v, want it to turn at %v", test.name, path, test.corners)
+		}
+		if length := pathLength(path); math.Abs(float64(length-test.length)) > 1e-4 {
+			t.Errorf("%s: got path %v of length %v, want %v", test.name, path, length, test.length)
+		}
+
+		// The path stays on the mesh.
+		for i := 1; i < len(path); i++ {
+			for s := float32(0); s <= 1; s += 0.05 {
+				if p := path[i-1].Add(path[i].Sub(path[i-1]).Mul(s)); test.m.Locate(p) < 0 {
+					t.Errorf("%s: path %v leaves the mesh at %v", test.name, path, p)
+					break
+				}
+			}
+		}
+	}
+}
+
+func pointsEqual(a, b []mgl32.Vec3) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].ApproxEqualThreshold(b[i], 1e-5) {
+			return false
+		}
+	}
+	return true
+}
+
+func pathLength(path []mgl32.Vec3) float32 {
+	var length float32
+	for i := 1; i < len(path); i++ {
+		length += path[i].Sub(path[i-1]).Len()
+	}
+	return length
+}
@@ -0,0 +1,234 @@
+package nav
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/univ"
+)
+
+// VoxelConfig controls how a VoxelGraph is generated from level geometry.
+type VoxelConfig struct {
+	// CellSize is the width of each cubic cell of the graph.
+	CellSize float32
+	// AgentRadius is the distance agents keep from geometry.
+	AgentRadius float32
+	// Padding is the amount of open space included around the bounds of the geometry.
+	Padding float32
+}
+
+// DefaultVoxelConfig is a VoxelConfig suited to small drones flying around a station.
+var DefaultVoxelConfig = VoxelConfig{
+	CellSize:    2,
+	AgentRadius: 1,
+	Padding:     10,
+}
+
+// VoxelGraph divides space into a 3D grid of cells that are either open or blocked by geometry. It is
+// used to find paths for agents that can fly in any direction, such as drones in zero-g areas.
+type VoxelGraph struct {
+	Config  VoxelConfig
+	Origin  mgl32.Vec3
+	Dims    [3]int
+	Blocked []bool
+}
+
+// BakeVoxels builds a VoxelGraph around tris, blocking every cell within cfg.AgentRadius of a triangle.
+func BakeVoxels(tris []univ.Triangle, cfg VoxelConfig) *VoxelGraph {
+	g := &VoxelGraph{Config: cfg}
+	if len(tris) == 0 {
+		return g
+	}
+
+	min, max := tris[0][0], tris[0][0]
+	for _, tri := range tris {
+		for _, v := range tri {
+			for i := range v {
+				if v[i] < min[i] {
+					min[i] = v[i]
+				}
+				if v[i] > max[i] {
+					max[i] = v[i]
+				}
+			}
+		}
+	}
+	pad := mgl32.Vec3{cfg.Padding, cfg.Padding, cfg.Padding}
+	g.Origin = min.Sub(pad)
+	size := max.Add(pad).Sub(g.Origin)
+	for i := range g.Dims {
+		g.Dims[i] = int(math.Ceil(float64(size[i]/cfg.CellSize))) + 1
+	}
+	solid := make([]bool, g.Dims[0]*g.Dims[1]*g.Dims[2])
+
+	half := cfg.CellSize / 2
+	for _, tri := range tris {
+		lo, hi := g.cell(tri[0]), g.cell(tri[0])
+		for _, v := range tri[1:] {
+			c := g.cell(v)
+			for i := range c {
+				if c[i] < lo[i] {
+					lo[i] = c[i]
+				}
+				if c[i] > hi[i] {
+					hi[i] = c[i]
+				}
+			}
+		}
+		for x := lo[0]; x <= hi[0]; x++ {
+			for y := lo[1]; y <= hi[1]; y++ {
+				for z := lo[2]; z <= hi[2]; z++ {
+					c := [3]int{x, y, z}
+					if triangleOverlapsBox(tri, g.center(c), half) {
+						solid[g.index(c)] = true
+					}
+				}
+			}
+		}
+	}
+
+	// Grow the solid cells by the agent radius so paths keep their distance from walls.
+	g.Blocked = make([]bool, len(solid))
+	r := int(math.Ceil(float64(cfg.AgentRadius / cfg.CellSize)))
+	for i, s := range solid {
+		if !s {
+			continue
+		}
+		c := g.coords(i)
+		for x := c[0] - r; x <= c[0]+r; x++ {
+			for y := c[1] - r; y <= c[1]+r; y++ {
+				for z := c[2] - r; z <= c[2]+r; z++ {
+					if n := [3]int{x, y, z}; g.inBounds(n) {
+						g.Blocked[g.index(n)] = true
+					}
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// FindPath returns a smoothed path through the open cells of g from start to end, including both
+// points. ok is false if either point is outside g or blocked, or no path connects them.
+func (g *VoxelGraph) FindPath(start, end mgl32.Vec3) (path []mgl32.Vec3, ok bool) {
+	startCell, endCell := g.cell(start), g.cell(end)
+	if !g.open(startCell) || !g.open(endCell) {
+		return nil, false
+	}
+
+	cells := astar(g.index(startCell), g.index(endCell), func(node int, visit func(int, float32)) {
+		c := g.coords(node)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for dz := -1; dz <= 1; dz++ {
+					n := [3]int{c[0] + dx, c[1] + dy, c[2] + dz}
+					if n != c && g.open(n) {
+						visit(g.index(n), g.Config.CellSize*float32(math.Sqrt(float64(dx*dx+dy*dy+dz*dz))))
+					}
+				}
+			}
+		}
+	}, func(node int) float32 {
+		return g.center(g.coords(node)).Sub(end).Len()
+	})
+	if cells == nil {
+		return nil, false
+	}
+	if len(cells) == 1 {
+		return []mgl32.Vec3{start, end}, true
+	}
+
+	points := make([]mgl32.Vec3, len(cells))
+	points[0] = start
+	for i, c := range cells[1 : len(cells)-1] {
+		points[i+1] = g.center(g.coords(c))
+	}
+	points[len(points)-1] = end
+
+	// Skip any waypoints that can be bypassed in a straight line.
+	path = []mgl32.Vec3{start}
+	for i := 0; i < len(points)-1; {
+		next := i + 1
+		for j := len(points) - 1; j > next; j-- {
+			if g.lineOfSight(points[i], points[j]) {
+				next = j
+				break
+			}
+		}
+		path = append(path, points[next])
+		i = next
+	}
+
+	return path, true
+}
+
+// lineOfSight reports whether the straight line from a to b only passes through open cells.
+func (g *VoxelGraph) lineOfSight(a, b mgl32.Vec3) bool {
+	dist := b.Sub(a).Len()
+	steps := int(math.Ceil(float64(dist/(g.Config.CellSize/2)))) + 1
+	for i := 0; i <= steps; i++ {
+		p := a.Add(b.Sub(a).Mul(float32(i) / float32(steps)))
+		if !g.open(g.cell(p)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *VoxelGraph) cell(p mgl32.Vec3) [3]int {
+	var c [3]int
+	for i := range c {
+		c[i] = int(math.Floor(float64((p[i] - g.Origin[i]) / g.Config.CellSize)))
+	}
+	return c
+}
+
+func (g *VoxelGraph) center(c [3]int) mgl32.Vec3 {
+	return g.Origin.Add(mgl32.Vec3{float32(c[0]) + 0.5, float32(c[1]) + 0.5, float32(c[2]) + 0.5}.Mul(g.Config.CellSize))
+}
+
+func (g *VoxelGraph) inBounds(c [3]int) bool {
+	return c[0] >= 0 && c[1] >= 0 && c[2] >= 0 && c[0] < g.Dims[0] && c[1] < g.Dims[1] && c[2] < g.Dims[2]
+}
+
+func (g *VoxelGraph) open(c [3]int) bool {
+	return g.inBounds(c) && !g.Blocked[g.index(c)]
+}
+
+func (g *VoxelGraph) index(c [3]int) int {
+	return (c[2]*g.Dims[1]+c[1])*g.Dims[0] + c[0]
+}
+
+func (g *VoxelGraph) coords(index int) [3]int {
+	return [3]int{index % g.Dims[0], index / g.Dims[0] % g.Dims[1], index / (g.Dims[0] * g.Dims[1])}
+}
+
+// triangleOverlapsBox reports whether tri intersects the axis aligned cube at center with half width
+// half, using the separating axis theorem.
+func triangleOverlapsBox(tri univ.Triangle, center mgl32.Vec3, half float32) bool {
+	v := [3]mgl32.Vec3{tri[0].Sub(center), tri[1].Sub(center), tri[2].Sub(center)}
+	edges := [3]mgl32.Vec3{v[1].Sub(v[0]), v[2].Sub(v[1]), v[0].Sub(v[2])}
+	boxAxes := [3]mgl32.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+	axes := []mgl32.Vec3{boxAxes[0], boxAxes[1], boxAxes[2], edges[0].Cross(edges[1])}
+	for _, e := range edges {
+		for _, b := range boxAxes {
+			axes = append(axes, e.Cross(b))
+		}
+	}
+
+	for _, axis := range axes {
+		if axis.Len() == 0 {
+			continue
+		}
+		p0, p1, p2 := v[0].Dot(axis), v[1].Dot(axis), v[2].Dot(axis)
+		min := float32(math.Min(float64(p0), math.Min(float64(p1), float64(p2))))
+		max := float32(math.Max(float64(p0), math.Max(float64(p1), float64(p2))))
+		r := half * (mgl32.Abs(axis[0]) + mgl32.Abs(axis[1]) + mgl32.Abs(axis[2]))
+		if min > r || max < -r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package nav
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/univ"
+)
+
+// wallGraph returns a graph of 1 unit cells reaching 4 units past a wall in the plane x = 0, which
+// spans y and z from -2 to 2 and blocks the cells next to it.
+func wallGraph() *VoxelGraph {
+	tris := []univ.Triangle{
+		{{0, -2, -2}, {0, 2, -2}, {0, 2, 2}},
+		{{0, -2, -2}, {0, 2, 2}, {0, -2, 2}},
+	}
+	return BakeVoxels(tris, VoxelConfig{CellSize: 1, AgentRadius: 0.5, Padding: 4})
+}
+
+func TestVoxelGraphFindPath(t *testing.T) {
+	g := wallGraph()
+
+	tests := []struct {
+		name       string
+		start, end mgl32.Vec3
+		ok         bool
+	}{
+		{"same cell", mgl32.Vec3{-3.3, 0.2, 0.2}, mgl32.Vec3{-3.2, 0.4, 0.4}, true},
+		{"adjacent cells", mgl32.Vec3{-3.5, 0.5, 0.5}, mgl32.Vec3{-2.5, 0.5, 0.5}, true},
+		{"around the wall", mgl32.Vec3{-3.5, 0.5, 0.5}, mgl32.Vec3{3.5, 0.5, 0.5}, true},
+		{"blocked end", mgl32.Vec3{-3.5, 0.5, 0.5}, mgl32.Vec3{0.1, 0.5, 0.5}, false},
+		{"outside the graph", mgl32.Vec3{-3.5, 0.5, 0.5}, mgl32.Vec3{100, 0, 0}, false},
+	}
+	for _, test := range tests {
+		path, ok := g.FindPath(test.start, test.end)
+		if ok != test.ok {
+			t.Errorf("%s: got ok %v, want %v", test.name, ok, test.ok)
+			continue
+		}
+		if !ok {
+			if path != nil {
+				t.Errorf("%s: got path %v with no route", test.name, path)
+			}
+			continue
+		}
+		if len(path) < 2 || path[0] != test.start || path[len(path)-1] != test.end {
+			t.Errorf("%s: got path %v, want it from %v to %v", test.name, path, test.start, test.end)
+			continue
+		}
+		for i := 1; i < len(path); i++ {
+			if !g.lineOfSight(path[i-1], path[i]) {
+				t.Errorf("%s: path %v passes through blocked cells between %v and %v", test.name, path, path[i-1], path[i])
+			}
+		}
+	}
+}
+
+func TestVoxelGraphFindPathUnreachable(t *testing.T) {
+	// Wall off a pocket of open space in the middle of the graph.
+	g := &VoxelGraph{Config: VoxelConfig{CellSize: 1}, Dims: [3]int{5, 5, 5}}
+	g.Blocked = make([]bool, 5*5*5)
+	for i := range g.Blocked {
+		c := g.coords(i)
+		g.Blocked[i] = c != [3]int{2, 2, 2} && c[0] >= 1 && c[0] <= 3 && c[1] >= 1 && c[1] <= 3 && c[2] >= 1 && c[2] <= 3
+	}
+
+	if path, ok := g.FindPath(mgl32.Vec3{0.5, 0.5, 0.5}, mgl32.Vec3{2.5, 2.5, 2.5}); ok {
+		t.Errorf("got path %v into a walled off cell", path)
+	}
+	if path, ok := g.FindPath(mgl32.Vec3{2.2, 2.2, 2.2}, mgl32.Vec3{2.8, 2.8, 2.8}); !ok || len(path) != 2 {
+		t.Errorf("got path %v, %v within a walled off cell, want a straight line", path, ok)
+	}
+}
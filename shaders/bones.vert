@@ -8,12 +8,14 @@ uniform mat4 bones[30];
 in vec3 vert;
 in vec2 vertTexCoord;
 in vec3 vertNormal;
+in vec4 vertTangent;
 in ivec4 vertBones;
 in vec4 vertWeights;
 
 out vec2 fragTexCoord;
 out vec3 fragNormal;
 out vec3 fragPosition;
+out vec4 fragTangent;
 void main() {
 
   mat4 BoneTransform = bones[vertBones[0]] * vertWeights[0];
@@ -21,16 +23,13 @@ void main() {
   BoneTransform += bones[vertBones[2]] * vertWeights[2];
   BoneTransform += bones[vertBones[3]] * vertWeights[3];
 
-  fragNormal = (BoneTransform * vec4(vertNormal, 0.0)).xyz;
-  // fragNormal = vertNormal;
-
-  fragTexCoord = vertTexCoord;
-
   mat4 modelview = camera * model;
 
   vec4 pos = BoneTransform * vec4(vert, 1);
   gl_Position = projection * modelview * vec4(pos.xyz, 1);
-  fragPosition = vec3(pos);
   fragTexCoord = vertTexCoord;
-  fragNormal = vec3(BoneTransform * vec4(vertNormal, 1));  
+  // Lights are in world space, so light in world space too.
+  fragPosition = vec3(model * vec4(pos.xyz, 1));
+  fragNormal = mat3(model) * vec3(BoneTransform * vec4(vertNormal, 0));
+  fragTangent = vec4(mat3(model) * vec3(BoneTransform * vec4(vertTangent.xyz, 0)), vertTangent.w);
 }
@@ -0,0 +1,9 @@
+#version 410
+
+in vec4 lineColor;
+
+out vec4 outputColor;
+
+void main() {
+  outputColor = lineColor;
+}
@@ -0,0 +1,13 @@
+#version 410
+
+uniform mat4 viewProjection;
+
+in vec3 position;
+in vec4 color;
+
+out vec4 lineColor;
+
+void main() {
+  gl_Position = viewProjection * vec4(position, 1);
+  lineColor = color;
+}
@@ -0,0 +1,25 @@
+#version 410
+
+// Modes of drawing, matching draw/overlay.go.
+const int solid = 0;
+const int sprite = 1;
+const int glyph = 2;
+
+uniform int mode;
+uniform sampler2D tex;
+
+in vec2 uv;
+in vec4 tint;
+
+out vec4 outputColor;
+
+void main() {
+  vec4 c = tint;
+  if (mode == sprite) {
+    c *= texture(tex, uv);
+  } else if (mode == glyph) {
+    // Glyph atlases hold only coverage, in the red channel.
+    c.a *= texture(tex, uv).r;
+  }
+  outputColor = c;
+}
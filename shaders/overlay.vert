@@ -0,0 +1,18 @@
+#version 410
+
+uniform vec2 screen;
+
+in vec2 position;
+in vec2 texCoord;
+in vec4 color;
+
+out vec2 uv;
+out vec4 tint;
+
+void main() {
+  // Positions are in pixels from the top left of the screen.
+  vec2 pos = position / screen * 2.0 - 1.0;
+  gl_Position = vec4(pos.x, -pos.y, 0, 1);
+  uv = texCoord;
+  tint = color;
+}
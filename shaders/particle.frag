@@ -0,0 +1,24 @@
+#version 410
+
+uniform bool hasTexture;
+uniform sampler2D tex;
+
+in vec2 corner;
+in vec4 color;
+
+out vec4 outputColor;
+
+void main() {
+  vec4 c = color;
+  if (hasTexture) {
+    c *= texture(tex, corner * 0.5 + 0.5);
+  } else {
+    // A soft round dot, brightest in the middle.
+    float d = 1 - dot(corner, corner);
+    if (d <= 0) {
+      discard;
+    }
+    c.a *= d * d;
+  }
+  outputColor = c;
+}
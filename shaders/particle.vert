@@ -0,0 +1,76 @@
+#version 410
+
+#define MAX_KEYS 4
+
+uniform mat4 projection;
+uniform mat4 camera;
+uniform float time;
+
+uniform vec3 acceleration;
+uniform float drag;
+
+uniform int colorCount;
+uniform float colorAges[MAX_KEYS];
+uniform vec4 colors[MAX_KEYS];
+uniform int sizeCount;
+uniform float sizeAges[MAX_KEYS];
+uniform float sizes[MAX_KEYS];
+
+// Where and when the particle was emitted, and its velocity and lifetime. Each particle is read once
+// for the four corners of its quad.
+in vec4 spawn;
+in vec4 motion;
+
+out vec2 corner;
+out vec4 color;
+
+vec4 colorAt(float life) {
+  if (colorCount == 0) {
+    return vec4(1);
+  }
+  vec4 c = colors[0];
+  for (int i = 1; i < colorCount; i++) {
+    if (life > colorAges[i - 1]) {
+      float span = max(colorAges[i] - colorAges[i - 1], 1e-5);
+      c = mix(colors[i - 1], colors[i], clamp((life - colorAges[i - 1]) / span, 0, 1));
+    }
+  }
+  return c;
+}
+
+float sizeAt(float life) {
+  if (sizeCount == 0) {
+    return 1.0;
+  }
+  float s = sizes[0];
+  for (int i = 1; i < sizeCount; i++) {
+    if (life > sizeAges[i - 1]) {
+      float span = max(sizeAges[i] - sizeAges[i - 1], 1e-5);
+      s = mix(sizes[i - 1], sizes[i], clamp((life - sizeAges[i - 1]) / span, 0, 1));
+    }
+  }
+  return s;
+}
+
+void main() {
+  corner = vec2(gl_VertexID & 1, gl_VertexID >> 1) * 2.0 - 1.0;
+
+  float age = time - spawn.w;
+  float life = age / motion.w;
+  if (motion.w <= 0 || age < 0 || life >= 1) {
+    // Dead particles are moved outside the clip volume so nothing is drawn for them.
+    gl_Position = vec4(2, 2, 2, 1);
+    color = vec4(0);
+    return;
+  }
+
+  // Drag slows velocity exponentially, which integrates to (1 - e^(-kt)) / k of it.
+  float travel = drag > 0 ? (1 - exp(-drag * age)) / drag : age;
+  vec3 position = spawn.xyz + motion.xyz * travel + 0.5 * acceleration * age * age;
+
+  // Grow the quad in view space so it always faces the camera.
+  vec4 viewPosition = camera * vec4(position, 1);
+  viewPosition.xy += corner * sizeAt(life) * 0.5;
+  gl_Position = projection * viewPosition;
+  color = colorAt(life);
+}
@@ -0,0 +1,275 @@
+#version 410
+
+const int maxLights = 64;
+const int pointLight = 0;
+const int spotLight = 1;
+const int directionalLight = 2;
+
+struct Light {
+  vec4 position;  // xyz is the location, w the type
+  vec4 direction; // xyz is the direction it shines in, w the range, or 0 to reach forever
+  vec4 color;     // rgb is the color scaled by intensity
+  vec4 cone;      // x and y are the cosines of the inner and outer cone angles of spot lights
+};
+
+layout(std140) uniform Lights {
+  vec4 ambientColor;
+  int lightCount;
+  Light lights[maxLights];
+};
+
+const int maxCascades = 4;
+const int maxSpotShadows = 4;
+const int maxPointShadows = 4;
+
+layout(std140) uniform Shadows {
+  mat4 cascadeMatrices[maxCascades];
+  mat4 spotShadowMatrices[maxSpotShadows];
+  vec4 pointShadowFar; // the far plane of each point light shadow
+  vec4 shadowSettings; // x is the normal bias, y the PCF radius in texels, z the number of cascades
+};
+
+uniform sampler2DArrayShadow cascadeShadows;
+uniform sampler2DArrayShadow spotShadows;
+uniform samplerCubeArrayShadow pointShadows;
+
+uniform sampler2D tex;
+uniform vec3 diffuseColor;
+uniform float metallic;
+uniform float roughness;
+uniform vec3 emissiveColor;
+uniform bool hasDiffuseMap;
+uniform bool hasMetallicRoughnessMap;
+uniform sampler2D metallicRoughnessMap;
+uniform bool hasNormalMap;
+uniform sampler2D normalMap;
+uniform bool hasEmissiveMap;
+uniform sampler2D emissiveMap;
+// alphaMode is how the alpha of the surface, opacity times the alpha of the diffuse map, is used.
+uniform int alphaMode;
+uniform float opacity;
+uniform float alphaCutoff;
+uniform mat4 camera;
+uniform mat4 model;
+uniform vec3 camPosition;
+
+uniform bool hasEnvironment;
+uniform float environmentLevels;
+uniform samplerCube irradianceMap;
+uniform samplerCube reflectionMap;
+uniform sampler2D brdfMap;
+
+in vec2 fragTexCoord;
+in vec3 fragNormal;
+in vec3 fragPosition;
+in vec4 fragTangent;
+
+out vec4 outputColor;
+
+#define ALPHA_MASK 1
+#define ALPHA_BLEND 2
+
+const float PI = 3.14159265359;
+
+// srgbToLinear decodes an sRGB color, as color textures are stored, to the linear color lighting is
+// done in.
+vec3 srgbToLinear(vec3 c) {
+  return mix(c / 12.92, pow((c + 0.055) / 1.055, vec3(2.4)), step(0.04045, c));
+}
+
+// distributionGGX returns the density of microfacets facing halfway between the light and view.
+float distributionGGX(float nDotH, float rough) {
+  float a2 = rough*rough*rough*rough;
+  float d = nDotH*nDotH * (a2 - 1.0) + 1.0;
+  return a2 / (PI * d * d);
+}
+
+// geometrySmith returns the share of microfacets that neither the view nor the light is blocked from.
+float geometrySmith(float nDotV, float nDotL, float rough) {
+  float k = (rough + 1.0) * (rough + 1.0) / 8.0;
+  return nDotV / (nDotV*(1.0-k) + k) * nDotL / (nDotL*(1.0-k) + k);
+}
+
+// fresnelSchlick returns the share of light reflected at an angle with cosine cosTheta, from f0 head on.
+// Rough surfaces reflect less of the environment at grazing angles.
+vec3 fresnelSchlick(float cosTheta, vec3 f0, float rough) {
+  return f0 + (max(vec3(1.0 - rough), f0) - f0) * pow(1.0 - cosTheta, 5.0);
+}
+
+// lightAt returns the direction towards l from the fragment, and how much of l reaches it.
+float lightAt(Light l, out vec3 lightDir) {
+  int kind = int(l.position.w);
+  if (kind == directionalLight) {
+    lightDir = -normalize(l.direction.xyz);
+    return 1.0;
+  }
+
+  vec3 toLight = l.position.xyz - fragPosition;
+  float dist = length(toLight);
+  lightDir = toLight / max(dist, 0.0001);
+
+  float attenuation = 1.0;
+  float range = l.direction.w;
+  if (range > 0) {
+    float falloff = clamp(1.0 - dist/range, 0.0, 1.0);
+    attenuation = falloff * falloff;
+  }
+  if (kind == spotLight) {
+    float theta = dot(-lightDir, normalize(l.direction.xyz));
+    attenuation *= smoothstep(l.cone.y, l.cone.x, theta);
+  }
+  return attenuation;
+}
+
+// mapShadow returns how lit a point is in layer of map, from its position in the map's light space.
+float mapShadow(sampler2DArrayShadow map, vec4 lightSpace, int layer) {
+  vec3 p = lightSpace.xyz / lightSpace.w * 0.5 + 0.5;
+  if (any(lessThan(p, vec3(0))) || any(greaterThan(p, vec3(1)))) {
+    return 1.0;
+  }
+
+  int r = int(shadowSettings.y);
+  vec2 texel = 1.0 / vec2(textureSize(map, 0).xy);
+  float lit = 0.0;
+  for (int x = -r; x <= r; x++) {
+    for (int y = -r; y <= r; y++) {
+      lit += texture(map, vec4(p.xy + vec2(x, y)*texel, layer, p.z));
+    }
+  }
+  return lit / float((2*r+1) * (2*r+1));
+}
+
+// pointShadow returns how lit a point is by the point light with shadow index, from the light to it.
+float pointShadow(int index, vec3 fromLight) {
+  float depth = length(fromLight) / pointShadowFar[index];
+  if (depth > 1.0) {
+    return 1.0;
+  }
+
+  // Cube maps have no texel grid to step along, so filter over a small box around the direction.
+  int r = min(int(shadowSettings.y), 1);
+  float spread = length(fromLight) / float(textureSize(pointShadows, 0).x);
+  float lit = 0.0;
+  for (int x = -r; x <= r; x++) {
+    for (int y = -r; y <= r; y++) {
+      for (int z = -r; z <= r; z++) {
+        lit += texture(pointShadows, vec4(fromLight + vec3(x, y, z)*spread, index), depth);
+      }
+    }
+  }
+  return lit / float((2*r+1) * (2*r+1) * (2*r+1));
+}
+
+// shadowAt returns how much of l reaches the fragment, with normal, past the shadows l casts.
+float shadowAt(Light l, vec3 normal) {
+  int index = int(l.cone.z);
+  if (index < 0) {
+    return 1.0;
+  }
+
+  vec3 pos = fragPosition + normal * shadowSettings.x;
+  int kind = int(l.position.w);
+  if (kind == pointLight) {
+    return pointShadow(index, pos - l.position.xyz);
+  }
+  if (kind == spotLight) {
+    return mapShadow(spotShadows, spotShadowMatrices[index] * vec4(pos, 1), index);
+  }
+
+  // Use the finest cascade the fragment is in.
+  for (int i = 0; i < int(shadowSettings.z); i++) {
+    vec4 p = cascadeMatrices[i] * vec4(pos, 1);
+    if (all(lessThan(abs(p.xyz), vec3(1)))) {
+      return mapShadow(cascadeShadows, p, i);
+    }
+  }
+  return 1.0;
+}
+
+// surfaceNormal returns the normal of the fragment, bent by the material's normal map if it has one.
+vec3 surfaceNormal(vec2 uv) {
+  vec3 normal = normalize(fragNormal);
+  if (!hasNormalMap) {
+    return normal;
+  }
+
+  // Interpolation leaves the tangent slightly off perpendicular, so straighten it first.
+  vec3 tangent = normalize(fragTangent.xyz - normal * dot(normal, fragTangent.xyz));
+  vec3 bitangent = cross(normal, tangent) * fragTangent.w;
+  vec3 bent = texture(normalMap, uv).rgb * 2.0 - 1.0;
+  return normalize(mat3(tangent, bitangent, normal) * bent);
+}
+
+void main() {
+  vec2 uv = vec2(fragTexCoord.x, 1.0-fragTexCoord.y);
+  vec3 albedo = diffuseColor;
+  float alpha = opacity;
+  if (hasDiffuseMap) {
+    vec4 texel = texture(tex, uv);
+    albedo *= srgbToLinear(texel.rgb);
+    alpha *= texel.a;
+  }
+  if (alphaMode == ALPHA_MASK && alpha < alphaCutoff) {
+    discard;
+  }
+  vec3 emissive = emissiveColor;
+  if (hasEmissiveMap) {
+    emissive *= srgbToLinear(texture(emissiveMap, uv).rgb);
+  }
+  float metal = metallic;
+  float rough = roughness;
+  if (hasMetallicRoughnessMap) {
+    vec4 mr = texture(metallicRoughnessMap, uv);
+    metal *= mr.b;
+    rough *= mr.g;
+  }
+  // Perfectly smooth surfaces would reflect lights as infinitely small points.
+  rough = clamp(rough, 0.04, 1.0);
+
+  // Shadows are offset along the surface itself, not the bumps of its normal map.
+  vec3 surface = normalize(fragNormal);
+  vec3 normal = surfaceNormal(uv);
+  vec3 viewDir = normalize(camPosition-fragPosition);
+  float nDotV = max(dot(normal, viewDir), 0.0001);
+
+  // Dielectrics reflect about 4% of light head on, and metals reflect their own color.
+  vec3 f0 = mix(vec3(0.04), albedo, metal);
+
+  vec3 lit = vec3(0);
+  for (int i = 0; i < lightCount; i++) {
+    vec3 lightDir;
+    float attenuation = lightAt(lights[i], lightDir);
+    float nDotL = max(dot(normal, lightDir), 0.0);
+    if (attenuation <= 0 || nDotL <= 0) {
+      continue;
+    }
+    attenuation *= shadowAt(lights[i], surface);
+
+    vec3 halfDir = normalize(lightDir + viewDir);
+    vec3 f = fresnelSchlick(max(dot(halfDir, viewDir), 0.0), f0, 0.0);
+    float d = distributionGGX(max(dot(normal, halfDir), 0.0), rough);
+    float g = geometrySmith(nDotV, nDotL, rough);
+    vec3 specular = d * g * f / (4.0 * nDotV * nDotL + 0.0001);
+    vec3 diffuse = (1.0 - f) * (1.0 - metal) * albedo / PI;
+
+    // Light colors are scaled so a white light fully lights a white surface facing it in the other
+    // programs, so scale them by pi to match.
+    lit += (diffuse + specular) * lights[i].color.rgb * attenuation * PI * nDotL;
+  }
+
+  // Light from the environment, or the ambient light from every direction without one.
+  vec3 irradiance = ambientColor.rgb;
+  vec3 reflected = ambientColor.rgb;
+  if (hasEnvironment) {
+    irradiance = texture(irradianceMap, normal).rgb;
+    vec3 r = reflect(-viewDir, normal);
+    reflected = textureLod(reflectionMap, r, rough * (environmentLevels - 1.0)).rgb;
+  }
+  vec3 f = fresnelSchlick(nDotV, f0, rough);
+  vec2 brdf = texture(brdfMap, vec2(nDotV, rough)).rg;
+  vec3 ambient = (1.0 - f) * (1.0 - metal) * irradiance * albedo + reflected * (f0 * brdf.x + brdf.y);
+
+  vec3 color = ambient + lit + emissive;
+  // The window's post-processing tone maps and encodes the linear color for the screen.
+  outputColor = vec4(color, alphaMode == ALPHA_BLEND ? alpha : 1.0);
+}
@@ -0,0 +1,10 @@
+#version 410
+
+out vec2 uv;
+
+void main() {
+  // One triangle covering the screen, made from the vertex index alone.
+  vec2 pos = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2) * 2.0 - 1.0;
+  gl_Position = vec4(pos, 0, 1);
+  uv = pos * 0.5 + 0.5;
+}
@@ -0,0 +1,20 @@
+#version 410
+
+uniform sampler2D source;
+uniform sampler2D bloom;
+// bloomTexel is the size of a texel of bloom.
+uniform vec2 bloomTexel;
+uniform float intensity;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+void main() {
+  vec3 glow = texture(bloom, uv).rgb * 4;
+  glow += (texture(bloom, uv + vec2(-bloomTexel.x, 0)).rgb + texture(bloom, uv + vec2(bloomTexel.x, 0)).rgb +
+    texture(bloom, uv + vec2(0, -bloomTexel.y)).rgb + texture(bloom, uv + vec2(0, bloomTexel.y)).rgb) * 2;
+  glow += texture(bloom, uv - bloomTexel).rgb + texture(bloom, uv + bloomTexel).rgb +
+    texture(bloom, uv + vec2(-bloomTexel.x, bloomTexel.y)).rgb + texture(bloom, uv + vec2(bloomTexel.x, -bloomTexel.y)).rgb;
+  outputColor = vec4(texture(source, uv).rgb + glow / 16 * intensity, 1);
+}
@@ -0,0 +1,37 @@
+#version 410
+
+uniform sampler2D source;
+// texel is the size of a texel of source.
+uniform vec2 texel;
+// prefilter keeps only the light brighter than threshold, fading in over knee, when downsampling the
+// scene into the first bloom level.
+uniform bool prefilter;
+uniform float threshold;
+uniform float knee;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+vec3 tap(float x, float y) {
+  return texture(source, uv + texel * vec2(x, y)).rgb;
+}
+
+void main() {
+  // A 13 tap filter of overlapping boxes, which keeps bloom from flickering as bright pixels move.
+  vec3 a = tap(-2, 2), b = tap(0, 2), c = tap(2, 2);
+  vec3 d = tap(-2, 0), e = tap(0, 0), f = tap(2, 0);
+  vec3 g = tap(-2, -2), h = tap(0, -2), i = tap(2, -2);
+  vec3 j = tap(-1, 1), k = tap(1, 1), l = tap(-1, -1), m = tap(1, -1);
+
+  vec3 color = e * 0.125 + (a + c + g + i) * 0.03125 + (b + d + f + h) * 0.0625 + (j + k + l + m) * 0.125;
+
+  if (prefilter) {
+    // A soft threshold, so light starts to bloom gradually rather than all at once.
+    float brightness = max(color.r, max(color.g, color.b));
+    float soft = clamp(brightness - threshold + knee, 0, 2 * knee);
+    soft = soft * soft / (4 * knee + 1e-5);
+    color *= max(soft, brightness - threshold) / max(brightness, 1e-5);
+  }
+  outputColor = vec4(color, 1);
+}
@@ -0,0 +1,47 @@
+#version 410
+
+uniform sampler2D source;
+// texel is the size of a texel of source.
+uniform vec2 texel;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+const float spanMax = 8.0;
+const float reduceMul = 1.0 / 8.0;
+const float reduceMin = 1.0 / 128.0;
+
+// luma returns the perceived brightness of a linear color, roughly as it will be shown.
+float luma(vec3 c) {
+  return sqrt(dot(c, vec3(0.299, 0.587, 0.114)));
+}
+
+void main() {
+  // Fast approximate anti-aliasing: blur along edges found from the contrast of neighbouring pixels.
+  vec3 rgbNW = texture(source, uv + vec2(-1, -1) * texel).rgb;
+  vec3 rgbNE = texture(source, uv + vec2(1, -1) * texel).rgb;
+  vec3 rgbSW = texture(source, uv + vec2(-1, 1) * texel).rgb;
+  vec3 rgbSE = texture(source, uv + vec2(1, 1) * texel).rgb;
+  vec3 rgbM = texture(source, uv).rgb;
+
+  float lumaNW = luma(rgbNW), lumaNE = luma(rgbNE), lumaSW = luma(rgbSW), lumaSE = luma(rgbSE);
+  float lumaM = luma(rgbM);
+  float lumaMin = min(lumaM, min(min(lumaNW, lumaNE), min(lumaSW, lumaSE)));
+  float lumaMax = max(lumaM, max(max(lumaNW, lumaNE), max(lumaSW, lumaSE)));
+
+  vec2 dir = vec2(-((lumaNW + lumaNE) - (lumaSW + lumaSE)), (lumaNW + lumaSW) - (lumaNE + lumaSE));
+  float reduce = max((lumaNW + lumaNE + lumaSW + lumaSE) * 0.25 * reduceMul, reduceMin);
+  float scale = 1.0 / (min(abs(dir.x), abs(dir.y)) + reduce);
+  dir = clamp(dir * scale, vec2(-spanMax), vec2(spanMax)) * texel;
+
+  vec3 rgbA = 0.5 * (texture(source, uv + dir * (1.0/3.0 - 0.5)).rgb + texture(source, uv + dir * (2.0/3.0 - 0.5)).rgb);
+  vec3 rgbB = rgbA * 0.5 + 0.25 * (texture(source, uv - dir * 0.5).rgb + texture(source, uv + dir * 0.5).rgb);
+
+  float lumaB = luma(rgbB);
+  if (lumaB < lumaMin || lumaB > lumaMax) {
+    outputColor = vec4(rgbA, 1);
+  } else {
+    outputColor = vec4(rgbB, 1);
+  }
+}
@@ -0,0 +1,28 @@
+#version 410
+
+uniform sampler2D source;
+uniform float contrast;
+uniform float saturation;
+uniform vec3 lift;
+uniform vec3 gamma;
+uniform vec3 gain;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+const vec3 luma = vec3(0.2126, 0.7152, 0.0722);
+
+void main() {
+  vec3 color = texture(source, uv).rgb;
+
+  // Lift raises the shadows, gain scales the highlights and gamma bends the midtones, per channel.
+  color = color * gain + lift * (1 - color);
+  color = pow(max(color, vec3(0)), 1 / max(gamma, vec3(1e-3)));
+
+  // Contrast pushes colors away from middle grey, and saturation away from their own grey.
+  color = (color - 0.18) * contrast + 0.18;
+  color = mix(vec3(dot(color, luma)), color, saturation);
+
+  outputColor = vec4(max(color, vec3(0)), 1);
+}
@@ -0,0 +1,17 @@
+#version 410
+
+uniform sampler2D source;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+// linearToSrgb encodes a linear color as sRGB, as screens show it.
+vec3 linearToSrgb(vec3 c) {
+  return mix(c * 12.92, 1.055 * pow(c, vec3(1.0/2.4)) - 0.055, step(0.0031308, c));
+}
+
+void main() {
+  vec3 color = texture(source, uv).rgb;
+  outputColor = vec4(linearToSrgb(clamp(color, 0.0, 1.0)), 1);
+}
@@ -0,0 +1,28 @@
+#version 410
+
+#define TONE_MAP_ACES 0
+#define TONE_MAP_REINHARD 1
+#define TONE_MAP_CLAMP 2
+
+uniform sampler2D source;
+uniform int operator;
+uniform float exposure;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+// aces fits the ACES filmic curve, which rolls highlights off to white.
+vec3 aces(vec3 x) {
+  return clamp((x * (2.51 * x + 0.03)) / (x * (2.43 * x + 0.59) + 0.14), 0, 1);
+}
+
+void main() {
+  vec3 color = texture(source, uv).rgb * exposure;
+  if (operator == TONE_MAP_ACES) {
+    color = aces(color);
+  } else if (operator == TONE_MAP_REINHARD) {
+    color = color / (1 + color);
+  }
+  outputColor = vec4(clamp(color, 0, 1), 1);
+}
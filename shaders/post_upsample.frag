@@ -0,0 +1,21 @@
+#version 410
+
+uniform sampler2D source;
+// texel is the size of a texel of source.
+uniform vec2 texel;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+vec3 tap(float x, float y) {
+  return texture(source, uv + texel * vec2(x, y)).rgb;
+}
+
+void main() {
+  // A 3x3 tent filter, blended onto the larger level beneath.
+  vec3 color = tap(0, 0) * 4;
+  color += (tap(-1, 0) + tap(1, 0) + tap(0, -1) + tap(0, 1)) * 2;
+  color += tap(-1, -1) + tap(1, -1) + tap(-1, 1) + tap(1, 1);
+  outputColor = vec4(color / 16, 1);
+}
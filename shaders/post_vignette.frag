@@ -0,0 +1,17 @@
+#version 410
+
+uniform sampler2D source;
+uniform float aspect;
+uniform float intensity;
+uniform float radius;
+uniform float softness;
+
+in vec2 uv;
+
+out vec4 outputColor;
+
+void main() {
+  vec2 d = (uv - 0.5) * vec2(aspect, 1);
+  float fade = smoothstep(radius, radius - softness, length(d));
+  outputColor = vec4(texture(source, uv).rgb * mix(1 - intensity, 1, fade), 1);
+}
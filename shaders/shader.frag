@@ -1,43 +1,228 @@
 #version 410
 
+const int maxLights = 64;
+const int pointLight = 0;
+const int spotLight = 1;
+const int directionalLight = 2;
+
+struct Light {
+  vec4 position;  // xyz is the location, w the type
+  vec4 direction; // xyz is the direction it shines in, w the range, or 0 to reach forever
+  vec4 color;     // rgb is the color scaled by intensity
+  vec4 cone;      // x and y are the cosines of the inner and outer cone angles of spot lights
+};
+
+layout(std140) uniform Lights {
+  vec4 ambientColor;
+  int lightCount;
+  Light lights[maxLights];
+};
+
+const int maxCascades = 4;
+const int maxSpotShadows = 4;
+const int maxPointShadows = 4;
+
+layout(std140) uniform Shadows {
+  mat4 cascadeMatrices[maxCascades];
+  mat4 spotShadowMatrices[maxSpotShadows];
+  vec4 pointShadowFar; // the far plane of each point light shadow
+  vec4 shadowSettings; // x is the normal bias, y the PCF radius in texels, z the number of cascades
+};
+
+uniform sampler2DArrayShadow cascadeShadows;
+uniform sampler2DArrayShadow spotShadows;
+uniform samplerCubeArrayShadow pointShadows;
+
 uniform sampler2D tex;
+uniform vec3 diffuseColor;
+uniform vec3 specularColor;
+uniform float shininess;
+uniform vec3 emissiveColor;
+uniform bool hasDiffuseMap;
+uniform bool hasSpecularMap;
+uniform sampler2D specularMap;
+uniform bool hasNormalMap;
+uniform sampler2D normalMap;
+uniform bool hasEmissiveMap;
+uniform sampler2D emissiveMap;
+// alphaMode is how the alpha of the surface, opacity times the alpha of the diffuse map, is used.
+uniform int alphaMode;
+uniform float opacity;
+uniform float alphaCutoff;
 uniform mat4 camera;
 uniform mat4 model;
 uniform vec3 camPosition;
 
+#define ALPHA_MASK 1
+#define ALPHA_BLEND 2
+
 in vec2 fragTexCoord;
 in vec3 fragNormal;
 in vec3 fragPosition;
+in vec4 fragTangent;
 
 out vec4 outputColor;
 
-const float shininess = 3.0;
-const vec3 lightColor = vec3(1, 1, 1);
-const vec3 lightPosition = vec3(0,100,0);
-const float lightPower = 40.0;
-const vec3 ambientColor = vec3(0.4,0.4,0.4);
-const vec3 diffuseColor = vec3(0.6, 0.6, 0.6);
-const vec3 specColor = vec3(0.4,0.4,0.4);
-const float phongExponent = 1;
+// srgbToLinear decodes an sRGB color to a linear one. Colors brighter than white stay brighter.
+vec3 srgbToLinear(vec3 c) {
+  c = max(c, vec3(0));
+  return mix(c / 12.92, pow((c + 0.055) / 1.055, vec3(2.4)), step(0.04045, c));
+}
 
-void main() {
-  vec3 color = texture(tex, vec2(fragTexCoord.x, 1.0-fragTexCoord.y)).rgb;
+// lightAt returns the direction towards l from the fragment, and how much of l reaches it.
+float lightAt(Light l, out vec3 lightDir) {
+  int kind = int(l.position.w);
+  if (kind == directionalLight) {
+    lightDir = -normalize(l.direction.xyz);
+    return 1.0;
+  }
+
+  vec3 toLight = l.position.xyz - fragPosition;
+  float dist = length(toLight);
+  lightDir = toLight / max(dist, 0.0001);
+
+  float attenuation = 1.0;
+  float range = l.direction.w;
+  if (range > 0) {
+    float falloff = clamp(1.0 - dist/range, 0.0, 1.0);
+    attenuation = falloff * falloff;
+  }
+  if (kind == spotLight) {
+    float theta = dot(-lightDir, normalize(l.direction.xyz));
+    attenuation *= smoothstep(l.cone.y, l.cone.x, theta);
+  }
+  return attenuation;
+}
+
+// mapShadow returns how lit a point is in layer of map, from its position in the map's light space.
+float mapShadow(sampler2DArrayShadow map, vec4 lightSpace, int layer) {
+  vec3 p = lightSpace.xyz / lightSpace.w * 0.5 + 0.5;
+  if (any(lessThan(p, vec3(0))) || any(greaterThan(p, vec3(1)))) {
+    return 1.0;
+  }
+
+  int r = int(shadowSettings.y);
+  vec2 texel = 1.0 / vec2(textureSize(map, 0).xy);
+  float lit = 0.0;
+  for (int x = -r; x <= r; x++) {
+    for (int y = -r; y <= r; y++) {
+      lit += texture(map, vec4(p.xy + vec2(x, y)*texel, layer, p.z));
+    }
+  }
+  return lit / float((2*r+1) * (2*r+1));
+}
+
+// pointShadow returns how lit a point is by the point light with shadow index, from the light to it.
+float pointShadow(int index, vec3 fromLight) {
+  float depth = length(fromLight) / pointShadowFar[index];
+  if (depth > 1.0) {
+    return 1.0;
+  }
+
+  // Cube maps have no texel grid to step along, so filter over a small box around the direction.
+  int r = min(int(shadowSettings.y), 1);
+  float spread = length(fromLight) / float(textureSize(pointShadows, 0).x);
+  float lit = 0.0;
+  for (int x = -r; x <= r; x++) {
+    for (int y = -r; y <= r; y++) {
+      for (int z = -r; z <= r; z++) {
+        lit += texture(pointShadows, vec4(fromLight + vec3(x, y, z)*spread, index), depth);
+      }
+    }
+  }
+  return lit / float((2*r+1) * (2*r+1) * (2*r+1));
+}
+
+// shadowAt returns how much of l reaches the fragment, with normal, past the shadows l casts.
+float shadowAt(Light l, vec3 normal) {
+  int index = int(l.cone.z);
+  if (index < 0) {
+    return 1.0;
+  }
+
+  vec3 pos = fragPosition + normal * shadowSettings.x;
+  int kind = int(l.position.w);
+  if (kind == pointLight) {
+    return pointShadow(index, pos - l.position.xyz);
+  }
+  if (kind == spotLight) {
+    return mapShadow(spotShadows, spotShadowMatrices[index] * vec4(pos, 1), index);
+  }
+
+  // Use the finest cascade the fragment is in.
+  for (int i = 0; i < int(shadowSettings.z); i++) {
+    vec4 p = cascadeMatrices[i] * vec4(pos, 1);
+    if (all(lessThan(abs(p.xyz), vec3(1)))) {
+      return mapShadow(cascadeShadows, p, i);
+    }
+  }
+  return 1.0;
+}
+
+// surfaceNormal returns the normal of the fragment, bent by the material's normal map if it has one.
+vec3 surfaceNormal(vec2 uv) {
   vec3 normal = normalize(fragNormal);
-  vec3 lightDir = normalize(lightPosition - fragPosition);
-  
-  float lambertian = max(dot(lightDir, normal), 0.0);
-  float spec = 0.0;
-
-  if (lambertian > 0) {
-    vec3 viewDir = normalize(camPosition-fragPosition);
-    vec3 halfDir = normalize(lightDir + viewDir);
-    float specAngle = max(dot(halfDir, normal), 0);
-    spec = pow(specAngle, shininess);
-  }
-
-  vec3 ambient = ambientColor * color;
-  vec3 diffuse = lambertian * color;
-  vec3 specular = specColor * spec;
-  
-  outputColor = vec4(ambient + diffuse + specular, 1);
+  if (!hasNormalMap) {
+    return normal;
+  }
+
+  // Interpolation leaves the tangent slightly off perpendicular, so straighten it first.
+  vec3 tangent = normalize(fragTangent.xyz - normal * dot(normal, fragTangent.xyz));
+  vec3 bitangent = cross(normal, tangent) * fragTangent.w;
+  vec3 bent = texture(normalMap, uv).rgb * 2.0 - 1.0;
+  return normalize(mat3(tangent, bitangent, normal) * bent);
+}
+
+void main() {
+  vec2 uv = vec2(fragTexCoord.x, 1.0-fragTexCoord.y);
+  vec3 color = diffuseColor;
+  float alpha = opacity;
+  if (hasDiffuseMap) {
+    vec4 texel = texture(tex, uv);
+    color *= texel.rgb;
+    alpha *= texel.a;
+  }
+  if (alphaMode == ALPHA_MASK && alpha < alphaCutoff) {
+    discard;
+  }
+  vec3 emissive = emissiveColor;
+  if (hasEmissiveMap) {
+    emissive *= texture(emissiveMap, uv).rgb;
+  }
+  // Specular maps hold the color of highlights, and their gloss in alpha.
+  vec3 specColor = specularColor;
+  float gloss = shininess;
+  if (hasSpecularMap) {
+    vec4 s = texture(specularMap, uv);
+    specColor *= s.rgb;
+    gloss = max(gloss * s.a, 1.0);
+  }
+  // Shadows are offset along the surface itself, not the bumps of its normal map.
+  vec3 surface = normalize(fragNormal);
+  vec3 normal = surfaceNormal(uv);
+  vec3 viewDir = normalize(camPosition-fragPosition);
+
+  vec3 diffuse = vec3(0);
+  vec3 specular = vec3(0);
+  for (int i = 0; i < lightCount; i++) {
+    vec3 lightDir;
+    float attenuation = lightAt(lights[i], lightDir);
+    if (attenuation <= 0) {
+      continue;
+    }
+    attenuation *= shadowAt(lights[i], surface);
+
+    float lambertian = max(dot(lightDir, normal), 0.0);
+    if (lambertian > 0) {
+      vec3 halfDir = normalize(lightDir + viewDir);
+      float specAngle = max(dot(halfDir, normal), 0);
+      specular += lights[i].color.rgb * attenuation * pow(specAngle, gloss);
+    }
+    diffuse += lights[i].color.rgb * attenuation * lambertian;
+  }
+
+  vec3 ambient = ambientColor.rgb * color;
+  // Lighting is done on colors as they are stored, as it always has been. Decode the result to the linear
+  // color the window's post-processing works in.
+  outputColor = vec4(srgbToLinear(ambient + diffuse*color + specColor*specular + emissive), alphaMode == ALPHA_BLEND ? alpha : 1.0);
 }
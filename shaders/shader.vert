@@ -7,16 +7,20 @@ uniform mat4 model;
 in vec3 vert;
 in vec2 vertTexCoord;
 in vec3 vertNormal;
+in vec4 vertTangent;
 
 out vec2 fragTexCoord;
 out vec3 fragNormal;
 out vec3 fragPosition;
+out vec4 fragTangent;
 
 void main() {
   mat4 modelview = camera * model;
 
   gl_Position = projection * modelview * vec4(vert, 1);
   fragTexCoord = vertTexCoord;
-  fragPosition = vert;
-  fragNormal = vertNormal;
+  // Lights are in world space, so light in world space too.
+  fragPosition = vec3(model * vec4(vert, 1));
+  fragNormal = mat3(model) * vertNormal;
+  fragTangent = vec4(mat3(model) * vertTangent.xyz, vertTangent.w);
 }
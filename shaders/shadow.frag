@@ -0,0 +1,15 @@
+#version 410
+
+uniform vec3 lightPosition;
+uniform float far;
+
+in vec3 fragPosition;
+
+void main() {
+  // Point light shadows store the distance from the light, so every face of their cube compares alike.
+  if (far > 0) {
+    gl_FragDepth = length(fragPosition - lightPosition) / far;
+  } else {
+    gl_FragDepth = gl_FragCoord.z;
+  }
+}
@@ -0,0 +1,14 @@
+#version 410
+
+uniform mat4 lightMatrix;
+uniform mat4 model;
+
+in vec3 vert;
+
+out vec3 fragPosition;
+
+void main() {
+  vec4 pos = model * vec4(vert, 1);
+  fragPosition = pos.xyz;
+  gl_Position = lightMatrix * pos;
+}
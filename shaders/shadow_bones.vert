@@ -0,0 +1,22 @@
+#version 410
+
+uniform mat4 lightMatrix;
+uniform mat4 model;
+uniform mat4 bones[30];
+
+in vec3 vert;
+in ivec4 vertBones;
+in vec4 vertWeights;
+
+out vec3 fragPosition;
+
+void main() {
+  mat4 BoneTransform = bones[vertBones[0]] * vertWeights[0];
+  BoneTransform += bones[vertBones[1]] * vertWeights[1];
+  BoneTransform += bones[vertBones[2]] * vertWeights[2];
+  BoneTransform += bones[vertBones[3]] * vertWeights[3];
+
+  vec4 pos = model * vec4((BoneTransform * vec4(vert, 1)).xyz, 1);
+  fragPosition = pos.xyz;
+  gl_Position = lightMatrix * pos;
+}
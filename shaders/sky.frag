@@ -0,0 +1,12 @@
+#version 410
+
+uniform samplerCube sky;
+
+in vec4 ray;
+
+out vec4 outputColor;
+
+void main() {
+  vec3 color = texture(sky, normalize(ray.xyz / ray.w)).rgb;
+  outputColor = vec4(color, 1);
+}
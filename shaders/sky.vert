@@ -0,0 +1,13 @@
+#version 410
+
+uniform mat4 inverseViewProjection;
+
+out vec4 ray;
+
+void main() {
+  // One triangle covering the screen, made from the vertex index alone.
+  vec2 pos = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2) * 2.0 - 1.0;
+  gl_Position = vec4(pos, 0, 1);
+  // Unproject in homogeneous coordinates, which interpolate correctly across the screen.
+  ray = inverseViewProjection * vec4(pos, 0, 1);
+}
@@ -1,6 +1,7 @@
 package univ
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/go-gl/mathgl/mgl32"
@@ -12,8 +13,10 @@ import (
 //
 // All body functions are safe to use concurrently.
 type Body struct {
-	meshes  []*draw.Mesh
-	program draw.Program
+	meshes    []*draw.Mesh
+	program   draw.Program
+	modelPath string
+	radius    float32
 	// observerMut sync.RWMutex
 	// observers   map[Observer]struct{}
 	locMut   sync.RWMutex
@@ -31,6 +34,18 @@ type Body struct {
 	lastAngularV mgl32.Vec3
 	ticker       *draw.Ticker
 	animators    []*draw.Animator
+
+	lightsMut sync.Mutex
+	lights    []attachedLight
+
+	emittersMut sync.Mutex
+	emitters    []attachedEmitter
+
+	accelerationsMut sync.Mutex
+	accelerations    []*Acceleration
+
+	// materialNames holds the name of the material of each mesh in the model.
+	materialNames []string
 }
 
 // AddObserver adds an observer to b. o.BodyUpdated will be called whenever b is updated.
@@ -66,6 +81,8 @@ func (b *Body) Translate(offset mgl32.Vec3) {
 	}
 
 	b.locMut.Unlock()
+	b.placeLights()
+	b.placeEmitters()
 	b.notifyTranslation()
 }
 
@@ -77,6 +94,8 @@ func (b *Body) SetLocation(loc mgl32.Vec3) {
 		m.SetLocation(loc)
 	}
 	b.locMut.Unlock()
+	b.placeLights()
+	b.placeEmitters()
 	b.notifyTranslation()
 }
 
@@ -95,6 +114,8 @@ func (b *Body) Rotate(offset mgl32.Quat) {
 		m.SetRotation(b.rotation)
 	}
 	b.rotMut.Unlock()
+	b.placeLights()
+	b.placeEmitters()
 	b.notifyRotation()
 }
 
@@ -106,9 +127,56 @@ func (b *Body) SetRotation(rot mgl32.Quat) {
 		m.SetRotation(rot)
 	}
 	b.rotMut.Unlock()
+	b.placeLights()
+	b.placeEmitters()
 	b.notifyRotation()
 }
 
+// ModelPath returns the path of the model file b was loaded from.
+func (b *Body) ModelPath() string {
+	return b.modelPath
+}
+
+// Radius returns the distance from b's location to the furthest vertex of its meshes.
+func (b *Body) Radius() float32 {
+	return b.radius
+}
+
+// Triangles returns the triangles of all of b's meshes, transformed by b's current location and rotation.
+func (b *Body) Triangles() []Triangle {
+	transform := mgl32.Translate3D(b.Location().Elem()).Mul4(b.Rotation().Normalize().Mat4())
+
+	var tris []Triangle
+	for _, mesh := range b.meshes {
+		verts := mesh.Vertexes()
+		for _, face := range mesh.Faces() {
+			var tri Triangle
+			for i, index := range face {
+				tri[i] = mgl32.TransformCoordinate(verts[index], transform)
+			}
+			tris = append(tris, tri)
+		}
+	}
+	return tris
+}
+
+// Hidden returns whether b is hidden.
+func (b *Body) Hidden() bool {
+	for _, m := range b.meshes {
+		if !m.Hidden() {
+			return false
+		}
+	}
+	return len(b.meshes) > 0
+}
+
+// SetHidden sets whether b is drawn. Hidden bodies continue to move and notify their observers.
+func (b *Body) SetHidden(hidden bool) {
+	for _, m := range b.meshes {
+		m.SetHidden(hidden)
+	}
+}
+
 // Draw draws b's meshes at the current location and rotation.
 //
 // Draw allows b to conform to draw.Drawable, and should not usually be called directly
@@ -190,10 +258,27 @@ func (b *Body) velocityTick(elapsed float32) {
 
 	b.Translate(b.velocity.Add(b.lastVelocity).Mul(elapsed / 2))
 	b.lastVelocity = b.velocity
+	b.setEmittersVelocity(b.velocity)
 
+	// angularV is in world space, with a magnitude in radians per second.
 	angularV := b.angularV.Add(b.lastAngularV).Mul(0.5)
-	// TODO: verify the use of elapsed as the angle (assuming angularV includes the magnitude of rotation per second) correct.
-	deltaRotation := mgl32.QuatRotate(elapsed, angularV)
-	b.Rotate(deltaRotation)
+	if angle := angularV.Len() * elapsed; angle > 0 {
+		deltaRotation := mgl32.QuatRotate(angle, angularV.Normalize())
+		b.SetRotation(deltaRotation.Mul(b.Rotation()).Normalize())
+	}
 	b.lastAngularV = b.angularV
 }
+
+// PlayAnimation switches every animated mesh of b with an animation called name to that animation.
+func (b *Body) PlayAnimation(name string) error {
+	played := false
+	for _, a := range b.animators {
+		if a != nil && a.Play(name) == nil {
+			played = true
+		}
+	}
+	if !played {
+		return fmt.Errorf("play animation %s on %s: no such animation", name, b.modelPath)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package univ
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// DefaultFOV is the vertical field of view of new cameras, in radians.
+var DefaultFOV = draw.DefaultProjection.FOV
+
+// CameraView is the point of view of a camera.
+type CameraView struct {
+	// Eye is the location of the camera.
+	Eye mgl32.Vec3
+	// Rotation is the orientation of the camera, which looks along its -z axis with +y up.
+	Rotation mgl32.Quat
+	// Projection is how the camera projects the scene, including its field of view.
+	Projection draw.Projection
+}
+
+// LookAtView returns the view from eye looking at center, with up pointing as close to up as possible.
+func LookAtView(eye, center, up mgl32.Vec3, projection draw.Projection) CameraView {
+	return CameraView{
+		Eye:        eye,
+		Rotation:   LookRotation(eye.Sub(center), up),
+		Projection: projection,
+	}
+}
+
+// Matrix returns the view matrix of v.
+func (v CameraView) Matrix() mgl32.Mat4 {
+	return v.Rotation.Normalize().Inverse().Mat4().Mul4(mgl32.Translate3D(v.Eye.Mul(-1).Elem()))
+}
+
+// Forward returns the direction v is looking in.
+func (v CameraView) Forward() mgl32.Vec3 {
+	return v.Rotation.Rotate(mgl32.Vec3{0, 0, -1})
+}
+
+// blendViews returns the view t of the way from a to b.
+func blendViews(a, b CameraView, t float32) CameraView {
+	return CameraView{
+		Eye:        a.Eye.Add(b.Eye.Sub(a.Eye).Mul(t)),
+		Rotation:   mgl32.QuatSlerp(a.Rotation.Normalize(), b.Rotation.Normalize(), t),
+		Projection: a.Projection.Lerp(b.Projection, t),
+	}
+}
+
+// Camera is a point of view the universe can be drawn from. See CameraManager for how cameras are
+// made active.
+type Camera interface {
+	// View returns the current point of view of the camera.
+	View() CameraView
+}
+
+// CameraManager draws the universe from exactly one active camera at a time. Changing the active camera
+// blends smoothly from the old point of view to the new one. The active camera is drawn in the window's
+// main viewport, and other viewports can be bound to cameras of their own with Bind.
+//
+// All CameraManager functions are safe to use concurrently.
+type CameraManager struct {
+	mut        sync.Mutex
+	window     *draw.Window
+	cameras    map[string]Camera
+	active     Camera
+	activeName string
+
+	blendFrom     CameraView
+	blendStart    time.Time
+	blendDuration time.Duration
+
+	bindings map[*draw.Viewport]string
+
+	ticker *draw.Ticker
+}
+
+// NewCameraManager creates a new CameraManager with no cameras, which sets the view of window. Each
+// Universe creates its own, so NewCameraManager should not usually be called directly.
+func NewCameraManager(window *draw.Window) *CameraManager {
+	m := &CameraManager{
+		window:   window,
+		cameras:  make(map[string]Camera),
+		bindings: make(map[*draw.Viewport]string),
+	}
+	m.ticker = draw.NewTicker(DefaultRefreshRate, m.tick)
+	return m
+}
+
+// Destroy stops m and cleans up its resources. m should not be used after it is destroyed.
+func (m *CameraManager) Destroy() {
+	m.ticker.Close()
+}
+
+// Add adds cam to m under name, replacing any camera already called name. The first camera added
+// becomes active.
+func (m *CameraManager) Add(name string, cam Camera) {
+	m.mut.Lock()
+	if m.activeName == name || m.active == nil {
+		m.active = cam
+		m.activeName = name
+	}
+	m.cameras[name] = cam
+	m.mut.Unlock()
+}
+
+// Remove removes the camera called name from m. If it is active, the view stays where it is until
+// another camera is activated.
+func (m *CameraManager) Remove(name string) {
+	m.mut.Lock()
+	if m.activeName == name {
+		m.blendFrom = m.view(time.Now())
+		m.active = fixedCamera(m.blendFrom)
+		m.activeName = ""
+	}
+	delete(m.cameras, name)
+	m.mut.Unlock()
+}
+
+// Bind draws v from the camera called name, adding v to the window if it hasn't been added. Bound
+// viewports switch cameras immediately rather than blending. If the camera is removed, v keeps its last
+// view until it is bound again.
+func (m *CameraManager) Bind(v *draw.Viewport, name string) error {
+	m.mut.Lock()
+	if _, ok := m.cameras[name]; !ok {
+		m.mut.Unlock()
+		return fmt.Errorf("bind viewport: no camera %q", name)
+	}
+	m.bindings[v] = name
+	m.mut.Unlock()
+
+	m.window.AddViewport(v)
+	return nil
+}
+
+// Unbind stops drawing v from its camera and removes it from the window.
+func (m *CameraManager) Unbind(v *draw.Viewport) {
+	m.mut.Lock()
+	delete(m.bindings, v)
+	m.mut.Unlock()
+
+	m.window.RemoveViewport(v)
+}
+
+// Get returns the camera called name, or nil if there is none.
+func (m *CameraManager) Get(name string) Camera {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.cameras[name]
+}
+
+// Active returns the name of the active camera.
+func (m *CameraManager) Active() string {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.activeName
+}
+
+// Activate makes the camera called name active, blending to it over blend.
+func (m *CameraManager) Activate(name string, blend time.Duration) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	cam, ok := m.cameras[name]
+	if !ok {
+		return fmt.Errorf("no camera %q", name)
+	}
+	if name == m.activeName {
+		return nil
+	}
+
+	now := time.Now()
+	if m.active != nil {
+		m.blendFrom = m.view(now)
+	} else {
+		m.blendFrom = cam.View()
+	}
+	m.blendStart = now
+	m.blendDuration = blend
+	m.active = cam
+	m.activeName = name
+	return nil
+}
+
+// View returns the current point of view of m, including any blend in progress.
+func (m *CameraManager) View() CameraView {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.view(time.Now())
+}
+
+// view returns the point of view of m at now. m.mut must be held.
+func (m *CameraManager) view(now time.Time) CameraView {
+	if m.active == nil {
+		return CameraView{Rotation: mgl32.QuatIdent(), Projection: draw.DefaultProjection}
+	}
+
+	target := m.active.View()
+	if m.blendDuration <= 0 {
+		return target
+	}
+	t := float32(now.Sub(m.blendStart)) / float32(m.blendDuration)
+	if t >= 1 {
+		return target
+	}
+	// Ease in and out so the camera doesn't jolt at either end of the blend.
+	t = t * t * (3 - 2*t)
+	return blendViews(m.blendFrom, target, t)
+}
+
+func (m *CameraManager) tick(elapsed float32) {
+	m.mut.Lock()
+	bound := make(map[*draw.Viewport]Camera, len(m.bindings))
+	for vp, name := range m.bindings {
+		if cam, ok := m.cameras[name]; ok {
+			bound[vp] = cam
+		}
+	}
+	active := m.active != nil
+	v := m.view(time.Now())
+	m.mut.Unlock()
+
+	if active {
+		m.window.SetView(v.Matrix(), v.Eye)
+		m.window.SetProjection(v.Projection)
+	}
+	for vp, cam := range bound {
+		v := cam.View()
+		vp.SetView(v.Matrix(), v.Eye)
+		vp.SetProjection(v.Projection)
+	}
+}
+
+// fixedCamera is a camera that never moves.
+type fixedCamera CameraView
+
+func (c fixedCamera) View() CameraView {
+	return CameraView(c)
+}
@@ -1,103 +1,376 @@
 package univ
 
 import (
+	"math"
 	"sync"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lsmith130/space/draw"
 )
 
-// ChaseCam is a camera that keeps a relative location to a body.
+const (
+	// chaseCamClearance is how far a ChaseCam stays in front of a body blocking its view.
+	chaseCamClearance = 0.5
+	// chaseCamRecovery is the fraction of the distance back to its full offset a ChaseCam recovers each
+	// second once its view is no longer blocked.
+	chaseCamRecovery = 3
+	// chaseCamStep is the longest step the camera springs are integrated over at once.
+	chaseCamStep = 1.0 / 120
+	// chaseCamRecast is how often in seconds a ChaseCam checks for obstructions while neither it nor its
+	// target moves, to catch bodies moving into its view.
+	chaseCamRecast = 0.25
+	// chaseCamRayTolerance is how far the ends of a ChaseCam's obstruction ray move before it's cast
+	// again.
+	chaseCamRayTolerance = 0.01
+)
+
+// Spring is a damped spring that pulls a camera towards where it should be. Stiffer springs follow more
+// closely, and a Spring with zero Stiffness follows rigidly. Damping of about 2√Stiffness settles
+// without overshooting.
+type Spring struct {
+	Stiffness float32
+	Damping   float32
+}
+
+var (
+	// DefaultPositionSpring is the spring ChaseCams follow their target's location with.
+	DefaultPositionSpring = Spring{Stiffness: 60, Damping: 15.5}
+	// DefaultRotationSpring is the spring ChaseCams turn to face their target with.
+	DefaultRotationSpring = Spring{Stiffness: 120, Damping: 22}
+)
+
+// ChaseCam is a camera that keeps a relative location to a body, lagging behind it on springs.
 type ChaseCam struct {
-	locMut   sync.RWMutex
-	rotMut   sync.RWMutex
-	location mgl32.Vec3
-	rotation mgl32.Quat
-	target   *Body
-	window   *draw.Window
+	locMut    sync.RWMutex
+	rotMut    sync.RWMutex
+	targetMut sync.RWMutex
+	location  mgl32.Vec3
+	rotation  mgl32.Quat
+	target    *Body
+	focusMut  sync.RWMutex
+	focus     mgl32.Vec3
+
+	mut            sync.Mutex
+	positionSpring Spring
+	rotationSpring Spring
+	lookAhead      float32
+	u              *Universe
+
+	// The sprung state of the camera, updated each tick. offset is relative to the target's location so
+	// that the camera lags behind the target turning but not moving.
+	offset     mgl32.Vec3
+	offsetV    mgl32.Vec3
+	look       mgl32.Quat
+	lookV      mgl32.Vec3
+	reach      float32
+	projection draw.Projection
+	view       CameraView
+	needsSnap  bool
+
+	// The last obstruction ray cast from the target, and how far it reached.
+	rayOrigin, rayEnd mgl32.Vec3
+	rayReach          float32
+	raySince          float32
+
+	ticker *draw.Ticker
 }
 
-// NewChaseCam creates a new ChaseCam with the specified target and update interval.
-func NewChaseCam(target *Body, window *draw.Window) *ChaseCam {
+// NewChaseCam creates a new ChaseCam with the specified target. The camera isn't drawn from until it
+// is added to a CameraManager and activated.
+func NewChaseCam(target *Body) *ChaseCam {
 	cam := &ChaseCam{
-		target: target,
-		window: window,
+		target:         target,
+		rotation:       mgl32.QuatIdent(),
+		focus:          mgl32.Vec3{0, 0, 5},
+		positionSpring: DefaultPositionSpring,
+		rotationSpring: DefaultRotationSpring,
+		projection:     draw.DefaultProjection,
+		needsSnap:      true,
 	}
-	target.AddObserver(cam)
+	cam.tick(0)
+	cam.ticker = draw.NewTicker(DefaultRefreshRate, cam.tick)
 	return cam
 }
 
 // Remove stops the camera from updating. Always call Remove on ChaseCams that are no longer needed.
 func (cam *ChaseCam) Remove() {
-	cam.target.RemoveObserver(cam)
+	cam.ticker.Close()
+}
+
+// Target returns the body cam is chasing.
+func (cam *ChaseCam) Target() *Body {
+	cam.targetMut.RLock()
+	defer cam.targetMut.RUnlock()
+	return cam.target
+}
+
+// SetTarget moves cam to chase target, keeping its relative location and rotation. cam swings over to
+// target on its springs.
+func (cam *ChaseCam) SetTarget(target *Body) {
+	cam.targetMut.Lock()
+	cam.target = target
+	cam.targetMut.Unlock()
+}
+
+// Snap moves cam straight to where it should be, skipping any lag.
+func (cam *ChaseCam) Snap() {
+	cam.mut.Lock()
+	cam.needsSnap = true
+	cam.mut.Unlock()
+}
+
+// View conforms to Camera.View
+func (cam *ChaseCam) View() CameraView {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+	return cam.view
+}
+
+// FOV returns the vertical field of view of cam, in radians.
+func (cam *ChaseCam) FOV() float32 {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+	return cam.projection.FOV
+}
+
+// SetFOV sets the vertical field of view of cam, in radians.
+func (cam *ChaseCam) SetFOV(fov float32) {
+	cam.mut.Lock()
+	cam.projection.FOV = fov
+	cam.mut.Unlock()
+}
+
+// Projection returns how cam projects the scene.
+func (cam *ChaseCam) Projection() draw.Projection {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+	return cam.projection
+}
+
+// SetProjection sets how cam projects the scene.
+func (cam *ChaseCam) SetProjection(p draw.Projection) {
+	cam.mut.Lock()
+	cam.projection = p
+	cam.mut.Unlock()
+}
+
+// PositionSpring returns the spring cam follows its target's location with.
+func (cam *ChaseCam) PositionSpring() Spring {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+	return cam.positionSpring
+}
+
+// SetPositionSpring sets the spring cam follows its target's location with.
+func (cam *ChaseCam) SetPositionSpring(s Spring) {
+	cam.mut.Lock()
+	cam.positionSpring = s
+	cam.mut.Unlock()
+}
+
+// RotationSpring returns the spring cam turns to face its target with.
+func (cam *ChaseCam) RotationSpring() Spring {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+	return cam.rotationSpring
+}
+
+// SetRotationSpring sets the spring cam turns to face its target with.
+func (cam *ChaseCam) SetRotationSpring(s Spring) {
+	cam.mut.Lock()
+	cam.rotationSpring = s
+	cam.mut.Unlock()
+}
+
+// LookAhead returns how many seconds ahead of its target's motion cam looks.
+func (cam *ChaseCam) LookAhead() float32 {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+	return cam.lookAhead
+}
+
+// SetLookAhead sets how many seconds ahead of its target's motion cam looks, so that fast targets
+// can see where they are going. Zero looks directly at the target.
+func (cam *ChaseCam) SetLookAhead(seconds float32) {
+	cam.mut.Lock()
+	cam.lookAhead = seconds
+	cam.mut.Unlock()
+}
+
+// AvoidObstructions makes cam pull in front of any visible body in u that blocks its view of its
+// target. Passing nil lets cam see through bodies again.
+func (cam *ChaseCam) AvoidObstructions(u *Universe) {
+	cam.mut.Lock()
+	cam.u = u
+	cam.mut.Unlock()
 }
 
-// GetLocation returns the current location of b
+// Location returns the location of cam relative to its target
 func (cam *ChaseCam) Location() mgl32.Vec3 {
 	cam.locMut.RLock()
 	defer cam.locMut.RUnlock()
 	return cam.location
 }
 
-// Translate translates the location of b by offset
+// Translate translates the location of cam by offset
 func (cam *ChaseCam) Translate(offset mgl32.Vec3) {
 	cam.locMut.Lock()
 	cam.location = cam.location.Add(offset)
 	cam.locMut.Unlock()
-	cam.update()
 }
 
-// SetLocation sets the location of b
+// SetLocation sets the location of cam
 func (cam *ChaseCam) SetLocation(loc mgl32.Vec3) {
 	cam.locMut.Lock()
 	cam.location = loc
 	cam.locMut.Unlock()
-	cam.update()
 }
 
-// GetRotation gets the rotation of b
+// Focus returns the point cam looks at, relative to its target.
+func (cam *ChaseCam) Focus() mgl32.Vec3 {
+	cam.focusMut.RLock()
+	defer cam.focusMut.RUnlock()
+	return cam.focus
+}
+
+// SetFocus sets the point cam looks at, relative to its target. It starts just ahead of the target.
+func (cam *ChaseCam) SetFocus(focus mgl32.Vec3) {
+	cam.focusMut.Lock()
+	cam.focus = focus
+	cam.focusMut.Unlock()
+}
+
+// Rotation gets the rotation of cam
 func (cam *ChaseCam) Rotation() mgl32.Quat {
 	cam.rotMut.RLock()
 	defer cam.rotMut.RUnlock()
 	return cam.rotation
 }
 
-// Rotate rotates b by offset
+// Rotate rotates cam by offset
 func (cam *ChaseCam) Rotate(offset mgl32.Quat) {
 	cam.rotMut.Lock()
 	cam.rotation = cam.rotation.Mul(offset)
 	cam.rotMut.Unlock()
-	cam.update()
 }
 
-// SetRotation sets the rotation of b to rot
+// SetRotation sets the rotation of cam to rot
 func (cam *ChaseCam) SetRotation(rot mgl32.Quat) {
 	cam.rotMut.Lock()
 	cam.rotation = rot
 	cam.rotMut.Unlock()
-	cam.update()
 }
 
-// BodyTranslated conforms to Observer.BodyTranslated and should not be called directly
-func (cam *ChaseCam) BodyTranslated(b *Body) {
-	cam.update()
-}
+func (cam *ChaseCam) tick(elapsed float32) {
+	// set the position of the camera and the look at point as relative positions to the direction and position of the target
+	target := cam.Target()
+	origin := target.Location()
+	rot := target.Rotation().Normalize()
+	lookAtMatRot := mgl32.Translate3D(origin.Elem()).Mul4(rot.Mat4())
+	lookFrom := lookAtMatRot.Mul4(mgl32.Translate3D(cam.Location().Elem())).Col(3).Vec3()
+	lookAt := lookAtMatRot.Mul4(mgl32.Translate3D(cam.Focus().Elem())).Col(3).Vec3()
+
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+
+	lookAt = lookAt.Add(target.Velocity().Mul(cam.lookAhead))
 
-// BodyRotated conforms to Observer.BodyRotated and should not be called directly
-func (cam *ChaseCam) BodyRotated(b *Body) {
-	cam.update()
+	if cam.needsSnap {
+		cam.offset, cam.offsetV = lookFrom.Sub(origin), mgl32.Vec3{}
+		cam.reach = cam.offset.Len()
+	}
+	for remaining := elapsed; remaining > 0; {
+		dt := remaining
+		if dt > chaseCamStep {
+			dt = chaseCamStep
+		}
+		remaining -= dt
+		cam.offset, cam.offsetV = springVec3(cam.positionSpring, cam.offset, cam.offsetV, lookFrom.Sub(origin), dt)
+	}
+
+	// Pull the camera in front of anything between it and the target, then ease back out once the view
+	// is clear again.
+	eye := origin.Add(cam.offset)
+	full := cam.offset.Len()
+	if cam.u != nil && full > 0 {
+		dir := cam.offset.Mul(1 / full)
+		reach := cam.obstruction(origin, dir, full, target, elapsed)
+		if reach < cam.reach {
+			cam.reach = reach
+		} else {
+			cam.reach += (reach - cam.reach) * mgl32.Clamp(chaseCamRecovery*elapsed, 0, 1)
+		}
+		if cam.reach < full {
+			eye = origin.Add(dir.Mul(cam.reach))
+		}
+	}
+
+	// Turn to face the look at point from wherever the camera ended up.
+	look := LookRotation(eye.Sub(lookAt), mgl32.Vec3{0, 1, 0}).Mul(cam.Rotation())
+	if cam.needsSnap {
+		cam.look, cam.lookV = look, mgl32.Vec3{}
+		cam.needsSnap = false
+	}
+	for remaining := elapsed; remaining > 0; {
+		dt := remaining
+		if dt > chaseCamStep {
+			dt = chaseCamStep
+		}
+		remaining -= dt
+		cam.look, cam.lookV = springQuat(cam.rotationSpring, cam.look, cam.lookV, look, dt)
+	}
+
+	cam.view = CameraView{Eye: eye, Rotation: cam.look, Projection: cam.projection}
 }
 
-func (cam *ChaseCam) update() {
-	// set the position of the camera and the look at point as relative positions to the direction and position of the target
-	rot := cam.target.Rotation()
-	lookAtMat := mgl32.Translate3D(cam.target.Location().Elem())
-	lookAtMatRot := lookAtMat.Mul4(rot.Normalize().Mat4())
-	lookAt := lookAtMatRot.Mul4(mgl32.Translate3D(0.0, 0.0, 5.0)).Col(3).Vec3()
-	lookFrom := lookAtMatRot.Mul4(mgl32.Translate3D(cam.Location().Elem())).Col(3).Vec3()
+// obstruction returns how far the camera can be from origin along dir, up to full, before a body other
+// than target blocks its view. Raycasts test every triangle near the ray, so the last result is reused
+// until the ray moves or chaseCamRecast passes. cam.mut must be held.
+func (cam *ChaseCam) obstruction(origin, dir mgl32.Vec3, full float32, target *Body, elapsed float32) float32 {
+	end := origin.Add(dir.Mul(full))
+	cam.raySince += elapsed
+	if !cam.needsSnap && cam.raySince < chaseCamRecast &&
+		origin.Sub(cam.rayOrigin).Len() < chaseCamRayTolerance && end.Sub(cam.rayEnd).Len() < chaseCamRayTolerance {
+		return cam.rayReach
+	}
 
-	transform := mgl32.LookAtV(lookFrom, lookAt, mgl32.Vec3{0, 1, 0})
-	cam.window.SetView(transform, lookFrom)
+	reach := full
+	if hit, ok := cam.u.Raycast(origin, dir, full+chaseCamClearance, target); ok {
+		reach = float32(math.Max(float64(hit.Distance-chaseCamClearance), 0))
+	}
+	cam.rayOrigin, cam.rayEnd, cam.rayReach, cam.raySince = origin, end, reach, 0
+	return reach
 }
 
-// func (cam *ChaseCam) GetLocation
+// springVec3 moves pos and its velocity vel towards target on s over dt.
+func springVec3(s Spring, pos, vel, target mgl32.Vec3, dt float32) (mgl32.Vec3, mgl32.Vec3) {
+	if s.Stiffness <= 0 {
+		return target, mgl32.Vec3{}
+	}
+	accel := target.Sub(pos).Mul(s.Stiffness).Sub(vel.Mul(s.Damping))
+	vel = vel.Add(accel.Mul(dt))
+	return pos.Add(vel.Mul(dt)), vel
+}
+
+// springQuat turns rot and its world space angular velocity vel towards target on s over dt.
+func springQuat(s Spring, rot mgl32.Quat, vel mgl32.Vec3, target mgl32.Quat, dt float32) (mgl32.Quat, mgl32.Vec3) {
+	if s.Stiffness <= 0 {
+		return target, mgl32.Vec3{}
+	}
+	delta := target.Mul(rot.Inverse()).Normalize()
+	if delta.W < 0 {
+		delta = delta.Scale(-1)
+	}
+	var axisAngle mgl32.Vec3
+	if l := delta.V.Len(); l > 1e-6 {
+		angle := 2 * float32(math.Acos(float64(mgl32.Clamp(delta.W, -1, 1))))
+		axisAngle = delta.V.Mul(angle / l)
+	}
+
+	accel := axisAngle.Mul(s.Stiffness).Sub(vel.Mul(s.Damping))
+	vel = vel.Add(accel.Mul(dt))
+	if l := vel.Len(); l > 0 {
+		rot = mgl32.QuatRotate(l*dt, vel.Mul(1/l)).Mul(rot).Normalize()
+	}
+	return rot, vel
+}
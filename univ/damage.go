@@ -0,0 +1,491 @@
+package univ
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// DamageType is the kind of harm done by a source of damage.
+type DamageType int
+
+const (
+	// DamageImpact is done by collisions between bodies.
+	DamageImpact DamageType = iota
+	// DamageProjectile is done by projectiles hitting a body.
+	DamageProjectile
+	// DamageHeat is done by fire, exhaust and other hot hazards.
+	DamageHeat
+	// DamageRadiation is done by radiation hazards.
+	DamageRadiation
+	// DamageSuffocation is done to astronauts that run out of oxygen.
+	DamageSuffocation
+)
+
+// Damage is an amount of harm done to a body.
+type Damage struct {
+	Type   DamageType
+	Amount float32
+	// Source is the body that caused the damage, or nil if it has no body.
+	Source *Body
+}
+
+// DamageObserver is an observer of the damage done to a Health. See Health.AddObserver and
+// Health.RemoveObserver for details on how to manage observers of a health.
+type DamageObserver interface {
+	// Damaged is called on each observer when damage is done, after armor and resistances are applied.
+	Damaged(h *Health, d Damage)
+	// Destroyed is called on each observer when h runs out of hit points, before its body is removed.
+	Destroyed(h *Health)
+}
+
+// Collision is a collision between two bodies of a DamageSystem.
+type Collision struct {
+	A, B *Body
+	// Point is where the bodies touched, and Normal the direction from A to B there.
+	Point, Normal mgl32.Vec3
+	// Speed is how fast the bodies were closing on each other.
+	Speed float32
+	// Velocity is the velocity of the point where they touched, halfway between theirs.
+	Velocity mgl32.Vec3
+}
+
+// CollisionObserver is an observer of the collisions between the bodies of a DamageSystem. See
+// DamageSystem.AddCollisionObserver and DamageSystem.RemoveCollisionObserver for details on how to
+// manage observers of collisions.
+type CollisionObserver interface {
+	// Collided is called on each observer when two bodies collide, after they have bounced apart and
+	// before any damage from the impact is done.
+	Collided(c Collision)
+}
+
+// Debris describes the pieces a body breaks into when it is destroyed.
+type Debris struct {
+	Model string
+	// Materials overrides the materials of Model by name.
+	Materials Materials
+	Count     int
+	// Speed is the greatest speed pieces fly away from the destroyed body at.
+	Speed    float32
+	Lifetime time.Duration
+}
+
+// Health tracks the hit points of a body and what it takes to destroy it. Health fields other than Body
+// may be changed until the health is added to a DamageSystem.
+//
+// All Health functions are safe to use concurrently.
+type Health struct {
+	Body *Body
+	// Armor is subtracted from every hit before resistances are applied.
+	Armor float32
+	// Resistances is the fraction of each type of damage that is ignored, between 0 and 1.
+	Resistances map[DamageType]float32
+	// ImpactThreshold is the collision speed below which impacts do no damage.
+	ImpactThreshold float32
+	// ImpactDamage is the damage done per unit of collision speed above ImpactThreshold.
+	ImpactDamage float32
+	// Static bodies are never moved by collisions.
+	Static bool
+	// Debris is what the body breaks into when it is destroyed, or nil for nothing.
+	Debris *Debris
+
+	mut       sync.Mutex
+	hp, maxHP float32
+	destroyed bool
+	observers map[DamageObserver]struct{}
+}
+
+// NewHealth creates a Health for b with maxHP hit points and an impact threshold suited to ships.
+func NewHealth(b *Body, maxHP float32) *Health {
+	return &Health{
+		Body:            b,
+		Resistances:     make(map[DamageType]float32),
+		ImpactThreshold: 5,
+		ImpactDamage:    2,
+		hp:              maxHP,
+		maxHP:           maxHP,
+		observers:       make(map[DamageObserver]struct{}),
+	}
+}
+
+// HP returns the remaining hit points of h.
+func (h *Health) HP() float32 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.hp
+}
+
+// MaxHP returns the hit points h starts with.
+func (h *Health) MaxHP() float32 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.maxHP
+}
+
+// Destroyed reports whether h has run out of hit points.
+func (h *Health) Destroyed() bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.destroyed
+}
+
+// Heal restores amount hit points to h, up to its maximum. Destroyed bodies can't be healed.
+func (h *Health) Heal(amount float32) {
+	h.mut.Lock()
+	if !h.destroyed {
+		h.hp = mgl32.Clamp(h.hp+amount, 0, h.maxHP)
+	}
+	h.mut.Unlock()
+}
+
+// AddObserver adds an observer to h. If o is already observing h, AddObserver has no effect.
+func (h *Health) AddObserver(o DamageObserver) {
+	h.mut.Lock()
+	h.observers[o] = struct{}{}
+	h.mut.Unlock()
+}
+
+// RemoveObserver removes an observer from h. If o isn't observing h, RemoveObserver has no effect.
+func (h *Health) RemoveObserver(o DamageObserver) {
+	h.mut.Lock()
+	delete(h.observers, o)
+	h.mut.Unlock()
+}
+
+// Apply does d to h after reducing it by h's armor and resistances, and returns the damage done.
+// Bodies that run out of hit points are destroyed by the DamageSystem h belongs to.
+func (h *Health) Apply(d Damage) float32 {
+	h.mut.Lock()
+	if h.destroyed {
+		h.mut.Unlock()
+		return 0
+	}
+
+	amount := mgl32.Clamp(d.Amount-h.Armor, 0, d.Amount) * (1 - mgl32.Clamp(h.Resistances[d.Type], 0, 1))
+	if amount == 0 {
+		h.mut.Unlock()
+		return 0
+	}
+	h.hp -= amount
+	if h.hp <= 0 {
+		h.hp = 0
+		h.destroyed = true
+	}
+	observers := h.observerList()
+	h.mut.Unlock()
+
+	d.Amount = amount
+	for _, o := range observers {
+		o.Damaged(h, d)
+	}
+	return amount
+}
+
+// observerList returns the observers of h. h.mut must be held.
+func (h *Health) observerList() []DamageObserver {
+	observers := make([]DamageObserver, 0, len(h.observers))
+	for o := range h.observers {
+		observers = append(observers, o)
+	}
+	return observers
+}
+
+// Projectile is a body that damages the first body it hits and is then removed.
+type Projectile struct {
+	Body *Body
+	// Owner is never hit by the projectile, and may be nil.
+	Owner  *Body
+	Damage float32
+	// Lifetime is how long the projectile flies before it is removed without hitting anything.
+	Lifetime time.Duration
+
+	fired time.Time
+}
+
+// Hazard is a spherical region that damages bodies inside it over time, such as a fire or radiation leak.
+type Hazard struct {
+	// Body is the body the hazard moves with, or nil if it is fixed in place.
+	Body *Body
+	// Location is the center of the hazard, relative to Body if it is set.
+	Location mgl32.Vec3
+	Radius   float32
+	Type     DamageType
+	// DamagePerSecond is the damage done to each body inside the hazard every second.
+	DamagePerSecond float32
+}
+
+// center returns the current location of the center of z.
+func (z *Hazard) center() mgl32.Vec3 {
+	if z.Body == nil {
+		return z.Location
+	}
+	return z.Body.Location().Add(z.Body.Rotation().Rotate(z.Location))
+}
+
+// collisionRestitution is the fraction of their closing speed colliding bodies bounce apart with.
+const collisionRestitution = 0.4
+
+// DamageSystem applies damage from collisions, projectiles and hazards to the bodies of a universe,
+// and destroys those that run out of hit points.
+//
+// Bodies collide as spheres of their Radius.
+//
+// All DamageSystem functions are safe to use concurrently.
+type DamageSystem struct {
+	u           *Universe
+	mut         sync.Mutex
+	healths     map[*Body]*Health
+	projectiles map[*Projectile]struct{}
+	hazards     map[*Hazard]struct{}
+	observers   map[CollisionObserver]struct{}
+	ticker      *draw.Ticker
+}
+
+// NewDamageSystem creates a new DamageSystem for the bodies of u. Each Universe creates its own, so
+// NewDamageSystem should not usually be called directly.
+func NewDamageSystem(u *Universe) *DamageSystem {
+	d := &DamageSystem{
+		u:           u,
+		healths:     make(map[*Body]*Health),
+		projectiles: make(map[*Projectile]struct{}),
+		hazards:     make(map[*Hazard]struct{}),
+		observers:   make(map[CollisionObserver]struct{}),
+	}
+	d.ticker = draw.NewTicker(DefaultRefreshRate, d.tick)
+	return d
+}
+
+// Destroy stops d and cleans up its resources. d should not be used after it is destroyed.
+func (d *DamageSystem) Destroy() {
+	d.ticker.Close()
+}
+
+// AddHealth adds h to d, so that its body can be damaged and destroyed.
+func (d *DamageSystem) AddHealth(h *Health) {
+	d.mut.Lock()
+	d.healths[h.Body] = h
+	d.mut.Unlock()
+}
+
+// RemoveHealth removes h from d. Its body will no longer be damaged.
+func (d *DamageSystem) RemoveHealth(h *Health) {
+	d.mut.Lock()
+	delete(d.healths, h.Body)
+	d.mut.Unlock()
+}
+
+// Health returns the health of b, or nil if b can't be damaged.
+func (d *DamageSystem) Health(b *Body) *Health {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.healths[b]
+}
+
+// Fire adds p to d, starting its lifetime.
+func (d *DamageSystem) Fire(p *Projectile) {
+	d.mut.Lock()
+	p.fired = time.Now()
+	d.projectiles[p] = struct{}{}
+	d.mut.Unlock()
+}
+
+// AddCollisionObserver adds an observer of the collisions between bodies in d. If o is already
+// observing d, AddCollisionObserver has no effect.
+func (d *DamageSystem) AddCollisionObserver(o CollisionObserver) {
+	d.mut.Lock()
+	d.observers[o] = struct{}{}
+	d.mut.Unlock()
+}
+
+// RemoveCollisionObserver removes an observer of collisions from d. If o isn't observing d,
+// RemoveCollisionObserver has no effect.
+func (d *DamageSystem) RemoveCollisionObserver(o CollisionObserver) {
+	d.mut.Lock()
+	delete(d.observers, o)
+	d.mut.Unlock()
+}
+
+// AddHazard adds z to d, so that it damages the bodies inside it.
+func (d *DamageSystem) AddHazard(z *Hazard) {
+	d.mut.Lock()
+	d.hazards[z] = struct{}{}
+	d.mut.Unlock()
+}
+
+// RemoveHazard removes z from d.
+func (d *DamageSystem) RemoveHazard(z *Hazard) {
+	d.mut.Lock()
+	delete(d.hazards, z)
+	d.mut.Unlock()
+}
+
+type pendingDamage struct {
+	h *Health
+	d Damage
+}
+
+func (d *DamageSystem) tick(elapsed float32) {
+	d.mut.Lock()
+	healths := make([]*Health, 0, len(d.healths))
+	for _, h := range d.healths {
+		healths = append(healths, h)
+	}
+
+	var hits []pendingDamage
+	var spent []*Projectile
+	var collisions []Collision
+
+	// Collide every pair of bodies, bouncing them apart and damaging both when the impact is hard enough.
+	for i, a := range healths {
+		for _, b := range healths[i+1:] {
+			offset := b.Body.Location().Sub(a.Body.Location())
+			dist := offset.Len()
+			if dist == 0 || dist > a.Body.Radius()+b.Body.Radius() {
+				continue
+			}
+			normal := offset.Mul(1 / dist)
+			closing := a.Body.Velocity().Sub(b.Body.Velocity()).Dot(normal)
+			if closing <= 0 {
+				continue
+			}
+
+			collisions = append(collisions, Collision{
+				A:        a.Body,
+				B:        b.Body,
+				Point:    a.Body.Location().Add(normal.Mul(dist * a.Body.Radius() / (a.Body.Radius() + b.Body.Radius()))),
+				Normal:   normal,
+				Speed:    closing,
+				Velocity: a.Body.Velocity().Add(b.Body.Velocity()).Mul(0.5),
+			})
+
+			impulse := normal.Mul(closing * (1 + collisionRestitution))
+			switch {
+			case a.Static && b.Static:
+			case a.Static:
+				b.Body.AddVelocity(impulse)
+			case b.Static:
+				a.Body.AddVelocity(impulse.Mul(-1))
+			default:
+				a.Body.AddVelocity(impulse.Mul(-0.5))
+				b.Body.AddVelocity(impulse.Mul(0.5))
+			}
+
+			if closing > a.ImpactThreshold {
+				hits = append(hits, pendingDamage{a, Damage{DamageImpact, (closing - a.ImpactThreshold) * a.ImpactDamage, b.Body}})
+			}
+			if closing > b.ImpactThreshold {
+				hits = append(hits, pendingDamage{b, Damage{DamageImpact, (closing - b.ImpactThreshold) * b.ImpactDamage, a.Body}})
+			}
+		}
+	}
+
+	// Hit the first body in the path of each projectile.
+	now := time.Now()
+	for p := range d.projectiles {
+		if now.Sub(p.fired) > p.Lifetime {
+			spent = append(spent, p)
+			continue
+		}
+		for _, h := range healths {
+			if h.Body == p.Owner || h.Body.Location().Sub(p.Body.Location()).Len() > h.Body.Radius()+p.Body.Radius() {
+				continue
+			}
+			hits = append(hits, pendingDamage{h, Damage{DamageProjectile, p.Damage, p.Owner}})
+			spent = append(spent, p)
+			break
+		}
+	}
+	for _, p := range spent {
+		delete(d.projectiles, p)
+	}
+
+	for z := range d.hazards {
+		center := z.center()
+		for _, h := range healths {
+			if h.Body != z.Body && h.Body.Location().Sub(center).Len() <= z.Radius+h.Body.Radius() {
+				hits = append(hits, pendingDamage{h, Damage{z.Type, z.DamagePerSecond * elapsed, z.Body}})
+			}
+		}
+	}
+	observers := make([]CollisionObserver, 0, len(d.observers))
+	for o := range d.observers {
+		observers = append(observers, o)
+	}
+	d.mut.Unlock()
+
+	for _, c := range collisions {
+		for _, o := range observers {
+			o.Collided(c)
+		}
+	}
+	for _, p := range spent {
+		d.u.RemoveBody(p.Body)
+	}
+	for _, hit := range hits {
+		hit.h.Apply(hit.d)
+	}
+
+	for _, h := range healths {
+		if h.Destroyed() {
+			d.destroy(h)
+		}
+	}
+}
+
+// destroy notifies the observers of h that it was destroyed, then replaces its body with debris.
+func (d *DamageSystem) destroy(h *Health) {
+	d.mut.Lock()
+	if d.healths[h.Body] != h {
+		d.mut.Unlock()
+		return
+	}
+	delete(d.healths, h.Body)
+	d.mut.Unlock()
+
+	h.mut.Lock()
+	observers := h.observerList()
+	h.mut.Unlock()
+	for _, o := range observers {
+		o.Destroyed(h)
+	}
+
+	location, velocity := h.Body.Location(), h.Body.Velocity()
+	d.u.RemoveBody(h.Body)
+
+	if h.Debris != nil {
+		// Creating bodies uses OpenGL, so it must happen on the window's thread.
+		d.u.Window.Do(func() {
+			d.spawnDebris(h.Debris, location, velocity)
+		})
+	}
+}
+
+func (d *DamageSystem) spawnDebris(debris *Debris, location, velocity mgl32.Vec3) {
+	for i := 0; i < debris.Count; i++ {
+		b, err := d.u.NewBody(debris.Model, d.u.Window.GetStandardProgram(), debris.Materials)
+		if err != nil {
+			log.Printf("spawn debris: %v", err)
+			return
+		}
+		b.SetLocation(location)
+		b.SetVelocity(velocity.Add(randomDirection().Mul(debris.Speed * rand.Float32())))
+		b.SetAngularV(randomDirection().Mul(rand.Float32() * 3))
+		time.AfterFunc(debris.Lifetime, func() {
+			d.u.RemoveBody(b)
+		})
+	}
+}
+
+// randomDirection returns a random unit vector.
+func randomDirection() mgl32.Vec3 {
+	for {
+		v := mgl32.Vec3{rand.Float32()*2 - 1, rand.Float32()*2 - 1, rand.Float32()*2 - 1}
+		if l := v.Len(); l > 0.01 && l <= 1 {
+			return v.Mul(1 / l)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package univ
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// DebugLayer is a kind of state a DebugView shows. Layers are flags, combined with |.
+type DebugLayer int
+
+const (
+	// DebugBounds shows the bounds of each body's meshes and the sphere it collides as, labelled with
+	// its model.
+	DebugBounds DebugLayer = 1 << iota
+	// DebugVelocity shows the velocity and angular velocity of each body.
+	DebugVelocity
+	// DebugAccelerations shows the linear and angular accelerations started on each body.
+	DebugAccelerations
+	// DebugSkeletons shows the bones of animated bodies in their current pose.
+	DebugSkeletons
+	// DebugCameras shows the frustums of the cameras that aren't active.
+	DebugCameras
+	// DebugTriggers shows the capture range of docking ports and the extent of hazards.
+	DebugTriggers
+
+	// DebugAll shows every layer.
+	DebugAll = DebugBounds | DebugVelocity | DebugAccelerations | DebugSkeletons | DebugCameras | DebugTriggers
+)
+
+// debugFrustumDepth is the furthest a camera's frustum is shown reaching.
+const debugFrustumDepth = 20
+
+// Colors of the layers of a DebugView.
+var (
+	debugBoundsColor       = mgl32.Vec4{0.3, 0.9, 1, 1}
+	debugRadiusColor       = mgl32.Vec4{0.3, 0.9, 1, 0.3}
+	debugVelocityColor     = mgl32.Vec4{0.2, 1, 0.2, 1}
+	debugAngularColor      = mgl32.Vec4{0.2, 0.5, 1, 1}
+	debugAccelerationColor = mgl32.Vec4{1, 0.6, 0.1, 1}
+	debugTorqueColor       = mgl32.Vec4{0.9, 0.3, 1, 1}
+	debugSkeletonColor     = mgl32.Vec4{1, 1, 0.3, 1}
+	debugCameraColor       = mgl32.Vec4{1, 1, 1, 0.8}
+	debugPortColor         = mgl32.Vec4{0.3, 1, 0.6, 0.6}
+	debugHazardColor       = mgl32.Vec4{1, 0.2, 0.2, 0.6}
+)
+
+// DebugView shows the physics, animation, cameras and triggers of a universe with its window's debug
+// drawing, which must be enabled for anything to be seen.
+//
+// All DebugView functions are safe to use concurrently.
+type DebugView struct {
+	u      *Universe
+	mut    sync.Mutex
+	layers DebugLayer
+}
+
+// NewDebugView creates a DebugView of u showing every layer.
+func NewDebugView(u *Universe) *DebugView {
+	v := &DebugView{u: u, layers: DebugAll}
+	u.Window.Debug().AddDrawer(v)
+	return v
+}
+
+// Remove stops v showing anything.
+func (v *DebugView) Remove() {
+	v.u.Window.Debug().RemoveDrawer(v)
+}
+
+// Layers returns the layers v shows.
+func (v *DebugView) Layers() DebugLayer {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	return v.layers
+}
+
+// SetLayers sets the layers v shows.
+func (v *DebugView) SetLayers(layers DebugLayer) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	v.layers = layers
+}
+
+// DrawDebug conforms to draw.DebugDrawer.DrawDebug and should not be called directly.
+func (v *DebugView) DrawDebug(d *draw.DebugDraw) {
+	layers := v.Layers()
+
+	v.u.bodiesMut.Lock()
+	bodies := make([]*Body, 0, len(v.u.bodies))
+	for b := range v.u.bodies {
+		bodies = append(bodies, b)
+	}
+	v.u.bodiesMut.Unlock()
+
+	for _, b := range bodies {
+		location, rotation := b.Location(), b.Rotation().Normalize()
+		transform := mgl32.Translate3D(location.Elem()).Mul4(rotation.Mat4())
+
+		if layers&DebugBounds != 0 {
+			for _, m := range b.meshes {
+				d.Bounds(m.Bounds(), transform, debugBoundsColor)
+			}
+			d.Sphere(location, b.Radius(), debugRadiusColor)
+			d.Text(location.Add(mgl32.Vec3{0, b.Radius(), 0}), filepath.Base(b.modelPath), debugBoundsColor)
+		}
+		if layers&DebugVelocity != 0 {
+			d.Arrow(location, b.Velocity(), debugVelocityColor)
+			d.Arrow(location, b.AngularV(), debugAngularColor)
+		}
+		if layers&DebugAccelerations != 0 {
+			for _, a := range b.Accelerations() {
+				d.Arrow(location, rotation.Rotate(a.LinearVector()), debugAccelerationColor)
+				d.Arrow(location, rotation.Rotate(a.AngularVector()), debugTorqueColor)
+			}
+		}
+		if layers&DebugSkeletons != 0 {
+			for _, a := range b.animators {
+				if a != nil {
+					drawSkeleton(d, a.Joints(), transform)
+				}
+			}
+		}
+	}
+
+	if layers&DebugCameras != 0 {
+		v.drawCameras(d)
+	}
+	if layers&DebugTriggers != 0 {
+		v.drawTriggers(d)
+	}
+}
+
+// drawSkeleton draws a line from each of joints to its parent, moved into the world by transform.
+func drawSkeleton(d *draw.DebugDraw, joints []draw.Joint, transform mgl32.Mat4) {
+	for _, j := range joints {
+		p := mgl32.TransformCoordinate(j.Position, transform)
+		d.Cross(p, 0.1, debugSkeletonColor)
+		if j.Parent >= 0 && j.Parent < len(joints) {
+			d.Line(mgl32.TransformCoordinate(joints[j.Parent].Position, transform), p, debugSkeletonColor)
+		}
+	}
+}
+
+// drawCameras draws the frustums of the cameras of v's universe that aren't active, as far as
+// debugFrustumDepth, in the shape of the window.
+func (v *DebugView) drawCameras(d *draw.DebugDraw) {
+	m := v.u.Cameras
+	m.mut.Lock()
+	var cams []Camera
+	for name, cam := range m.cameras {
+		if name != m.activeName {
+			cams = append(cams, cam)
+		}
+	}
+	m.mut.Unlock()
+
+	width, height := v.u.Window.GetWidth(), v.u.Window.GetHeight()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	aspect := float32(width) / float32(height)
+	for _, cam := range cams {
+		view := cam.View()
+		p := view.Projection
+		p.Infinite = false
+		if p.Far <= p.Near || p.Far > debugFrustumDepth {
+			p.Far = debugFrustumDepth
+		}
+		d.Frustum(p.Matrix(aspect, false).Mul4(view.Matrix()), debugCameraColor)
+	}
+}
+
+// drawTriggers draws the capture range of the docking ports of v's universe and the extent of its
+// hazards.
+func (v *DebugView) drawTriggers(d *draw.DebugDraw) {
+	docking := v.u.Docking
+	docking.mut.Lock()
+	ports := make([]*DockingPort, 0, len(docking.ports))
+	for p := range docking.ports {
+		ports = append(ports, p)
+	}
+	docking.mut.Unlock()
+
+	for _, p := range ports {
+		location := p.Location()
+		d.Sphere(location, p.Range, debugPortColor)
+		d.Arrow(location, p.Body.Rotation().Rotate(p.Forward), debugPortColor)
+		d.Text(location, p.Name, debugPortColor)
+	}
+
+	damage := v.u.Damage
+	damage.mut.Lock()
+	hazards := make([]*Hazard, 0, len(damage.hazards))
+	for z := range damage.hazards {
+		hazards = append(hazards, z)
+	}
+	damage.mut.Unlock()
+
+	for _, z := range hazards {
+		d.Sphere(z.center(), z.Radius, debugHazardColor)
+	}
+}
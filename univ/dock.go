@@ -0,0 +1,402 @@
+package univ
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/dae"
+	"github.com/lsmith130/space/draw"
+)
+
+const (
+	// DefaultDockingRange is the distance at which docking ports begin to pull each other in.
+	DefaultDockingRange = 8
+	// DefaultDockingTolerance is the greatest misalignment, in radians, at which docking ports will capture.
+	DefaultDockingTolerance = math.Pi / 12
+
+	// dockingPull is the speed, per unit of distance, at which a port is pulled towards its partner.
+	dockingPull = 1.5
+	// dockingTurn is the fraction of its misalignment a captured port turns each second.
+	dockingTurn = 2
+	// dockingSnapDistance is the distance at which a captured port locks in place.
+	dockingSnapDistance = 0.1
+	// undockingSpeed is the speed at which undocked bodies are pushed apart.
+	undockingSpeed = 2
+)
+
+// DockingPort is a place on a body where it can dock with another body's port.
+type DockingPort struct {
+	Name string
+	Body *Body
+	// Position is the location of the port relative to its body.
+	Position mgl32.Vec3
+	// Forward points out of the port and Up points along its top, relative to its body.
+	Forward, Up mgl32.Vec3
+	// Range is the distance within which the port captures another port.
+	Range float32
+	// Tolerance is the greatest misalignment, in radians, at which the port captures another port.
+	Tolerance float32
+	// Passive ports are never moved by docking, such as those on stations.
+	Passive bool
+}
+
+// NewDockingPort creates a port on b with the default range and tolerance.
+func NewDockingPort(b *Body, name string, position, forward, up mgl32.Vec3) *DockingPort {
+	return &DockingPort{
+		Name:      name,
+		Body:      b,
+		Position:  position,
+		Forward:   forward.Normalize(),
+		Up:        up.Normalize(),
+		Range:     DefaultDockingRange,
+		Tolerance: DefaultDockingTolerance,
+	}
+}
+
+// LoadDockingPorts creates a port for every node of b's model with a name starting with "dock". Ports
+// face along the node's z axis with their top along its y axis.
+func LoadDockingPorts(b *Body) ([]*DockingPort, error) {
+	f, err := dae.Open(b.ModelPath())
+	if err != nil {
+		return nil, fmt.Errorf("load docking ports: %v", err)
+	}
+
+	var ports []*DockingPort
+	for _, n := range f.Nodes() {
+		if !strings.HasPrefix(strings.ToLower(n.Name), "dock") {
+			continue
+		}
+		ports = append(ports, NewDockingPort(b, n.Name,
+			n.Transform.Col(3).Vec3(),
+			n.Transform.Mul4x1(mgl32.Vec4{0, 0, 1, 0}).Vec3(),
+			n.Transform.Mul4x1(mgl32.Vec4{0, 1, 0, 0}).Vec3(),
+		))
+	}
+	return ports, nil
+}
+
+// Location returns the current location of p.
+func (p *DockingPort) Location() mgl32.Vec3 {
+	return p.Body.Location().Add(p.Body.Rotation().Rotate(p.Position))
+}
+
+// alignment returns the rotation p's body needs to apply for p to face other, and the angle of that rotation.
+func (p *DockingPort) alignment(other *DockingPort) (mgl32.Quat, float32) {
+	rot := p.Body.Rotation().Normalize()
+	otherRot := other.Body.Rotation().Normalize()
+
+	// The orientation p's body would have if p faced directly into other with their tops aligned.
+	target := LookRotation(otherRot.Rotate(other.Forward).Mul(-1), otherRot.Rotate(other.Up)).
+		Mul(LookRotation(p.Forward, p.Up).Inverse())
+
+	delta := target.Mul(rot.Inverse()).Normalize()
+	if delta.W < 0 {
+		delta = delta.Scale(-1)
+	}
+	return delta, 2 * float32(math.Acos(float64(mgl32.Clamp(delta.W, -1, 1))))
+}
+
+// DockingObserver is an observer of docking events. See Docking.AddObserver and Docking.RemoveObserver
+// for details on how to manage observers of docking.
+type DockingObserver interface {
+	// Docked is called on each observer when two ports lock together.
+	Docked(a, b *DockingPort)
+	// Undocked is called on each observer when two docked ports separate.
+	Undocked(a, b *DockingPort)
+}
+
+// Docking captures docking ports that come within range and alignment of each other, pulls them
+// together, and holds their bodies rigidly joined once they dock.
+//
+// All Docking functions are safe to use concurrently.
+type Docking struct {
+	mut       sync.Mutex
+	ports     map[*DockingPort]struct{}
+	joints    map[*DockingPort]*dockingJoint
+	released  map[[2]*DockingPort]struct{}
+	observers map[DockingObserver]struct{}
+	ticker    *draw.Ticker
+}
+
+// dockingJoint holds the body of childPort at a fixed offset from the body of parentPort.
+type dockingJoint struct {
+	parentPort, childPort *DockingPort
+	offset                mgl32.Vec3
+	rotation              mgl32.Quat
+}
+
+// NewDocking creates a new Docking with no ports.
+func NewDocking() *Docking {
+	d := &Docking{
+		ports:     make(map[*DockingPort]struct{}),
+		joints:    make(map[*DockingPort]*dockingJoint),
+		released:  make(map[[2]*DockingPort]struct{}),
+		observers: make(map[DockingObserver]struct{}),
+	}
+	d.ticker = draw.NewTicker(DefaultRefreshRate, d.tick)
+	return d
+}
+
+// Destroy stops d and cleans up its resources. d should not be used after it is destroyed.
+func (d *Docking) Destroy() {
+	d.ticker.Close()
+}
+
+// AddPort adds p to the ports d can dock together.
+func (d *Docking) AddPort(p *DockingPort) {
+	d.mut.Lock()
+	d.ports[p] = struct{}{}
+	d.mut.Unlock()
+}
+
+// RemovePort undocks p if it is docked and removes it from d.
+func (d *Docking) RemovePort(p *DockingPort) {
+	d.Undock(p)
+	d.mut.Lock()
+	delete(d.ports, p)
+	d.mut.Unlock()
+}
+
+// AddObserver adds an observer to d. If o is already observing d, AddObserver has no effect.
+func (d *Docking) AddObserver(o DockingObserver) {
+	d.mut.Lock()
+	d.observers[o] = struct{}{}
+	d.mut.Unlock()
+}
+
+// RemoveObserver removes an observer from d. If o isn't observing d, RemoveObserver has no effect.
+func (d *Docking) RemoveObserver(o DockingObserver) {
+	d.mut.Lock()
+	delete(d.observers, o)
+	d.mut.Unlock()
+}
+
+// DockedWith returns the port p is docked to, or nil if p isn't docked.
+func (d *Docking) DockedWith(p *DockingPort) *DockingPort {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if j, ok := d.joints[p]; ok {
+		if j.parentPort == p {
+			return j.childPort
+		}
+		return j.parentPort
+	}
+	return nil
+}
+
+// Docked reports whether any port of a is docked to a port of b.
+func (d *Docking) Docked(a, b *Body) bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	for _, j := range d.joints {
+		if (j.parentPort.Body == a && j.childPort.Body == b) || (j.parentPort.Body == b && j.childPort.Body == a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Undock separates p from the port it is docked to and pushes their bodies apart. Undock reports
+// whether p was docked.
+func (d *Docking) Undock(p *DockingPort) bool {
+	d.mut.Lock()
+	j, ok := d.joints[p]
+	if !ok {
+		d.mut.Unlock()
+		return false
+	}
+	delete(d.joints, j.parentPort)
+	delete(d.joints, j.childPort)
+	d.released[[2]*DockingPort{j.parentPort, j.childPort}] = struct{}{}
+	observers := d.observerList()
+	d.mut.Unlock()
+
+	push := j.parentPort.Body.Rotation().Rotate(j.parentPort.Forward).Mul(undockingSpeed)
+	velocity := j.parentPort.Body.Velocity()
+	j.childPort.Body.SetVelocity(velocity.Add(push))
+	if !j.parentPort.Passive {
+		j.parentPort.Body.SetVelocity(velocity.Sub(push))
+	}
+
+	for _, o := range observers {
+		o.Undocked(j.parentPort, j.childPort)
+	}
+	return true
+}
+
+// UndockBody undocks every docked port of b.
+func (d *Docking) UndockBody(b *Body) {
+	d.mut.Lock()
+	var ports []*DockingPort
+	for p := range d.joints {
+		if p.Body == b {
+			ports = append(ports, p)
+		}
+	}
+	d.mut.Unlock()
+
+	for _, p := range ports {
+		d.Undock(p)
+	}
+}
+
+// observerList returns the observers of d. d.mut must be held.
+func (d *Docking) observerList() []DockingObserver {
+	observers := make([]DockingObserver, 0, len(d.observers))
+	for o := range d.observers {
+		observers = append(observers, o)
+	}
+	return observers
+}
+
+// bodyMove is a change to the motion of a body that tick makes once d.mut is released, since moving a
+// body notifies its observers, which may use d.
+type bodyMove struct {
+	body                         *Body
+	rotation                     mgl32.Quat
+	location, velocity, angularV mgl32.Vec3
+}
+
+func (m bodyMove) apply() {
+	m.body.SetRotation(m.rotation)
+	m.body.SetLocation(m.location)
+	m.body.SetVelocity(m.velocity)
+	m.body.SetAngularV(m.angularV)
+}
+
+func (d *Docking) tick(elapsed float32) {
+	d.mut.Lock()
+	var moves []bodyMove
+
+	// Hold docked bodies together.
+	for p, j := range d.joints {
+		if p != j.parentPort {
+			continue
+		}
+		parent := j.parentPort.Body
+		rot := parent.Rotation()
+		moves = append(moves, bodyMove{
+			body:     j.childPort.Body,
+			rotation: rot.Mul(j.rotation),
+			location: parent.Location().Add(rot.Rotate(j.offset)),
+			velocity: parent.Velocity(),
+			angularV: parent.AngularV(),
+		})
+	}
+
+	// Pull free ports towards the nearest aligned port in range.
+	var docked [][2]*DockingPort
+	for a := range d.ports {
+		if _, ok := d.joints[a]; ok || a.Passive {
+			continue
+		}
+
+		var target *DockingPort
+		var targetDist float32
+		for b := range d.ports {
+			if b.Body == a.Body {
+				continue
+			}
+			if _, ok := d.joints[b]; ok {
+				continue
+			}
+			dist := b.Location().Sub(a.Location()).Len()
+			if d.isReleased(a, b) {
+				if dist > a.Range && dist > b.Range {
+					delete(d.released, [2]*DockingPort{a, b})
+					delete(d.released, [2]*DockingPort{b, a})
+				}
+				continue
+			}
+			if dist > a.Range || dist > b.Range {
+				continue
+			}
+			if _, angle := a.alignment(b); angle > a.Tolerance || angle > b.Tolerance {
+				continue
+			}
+			if target == nil || dist < targetDist {
+				target = b
+				targetDist = dist
+			}
+		}
+		if target == nil {
+			continue
+		}
+
+		if targetDist < dockingSnapDistance {
+			moves = append(moves, d.join(target, a))
+			docked = append(docked, [2]*DockingPort{target, a})
+			continue
+		}
+
+		// Magnetic capture: match the target's velocity, close the gap and turn into alignment.
+		delta, angle := a.alignment(target)
+		turn := mgl32.QuatSlerp(mgl32.QuatIdent(), delta, mgl32.Clamp(dockingTurn*elapsed, 0, 1))
+		gap := target.Location().Sub(a.Location())
+		m := bodyMove{
+			body:     a.Body,
+			rotation: turn.Mul(a.Body.Rotation()).Normalize(),
+			location: a.Body.Location(),
+			velocity: target.Body.Velocity().Add(gap.Mul(dockingPull)),
+			angularV: target.Body.AngularV(),
+		}
+		if angle < a.Tolerance/10 && targetDist < dockingSnapDistance*10 {
+			// Close enough to lock without a visible jump.
+			m.location = m.location.Add(gap)
+		}
+		moves = append(moves, m)
+	}
+
+	observers := d.observerList()
+	d.mut.Unlock()
+
+	for _, m := range moves {
+		m.apply()
+	}
+
+	for _, pair := range docked {
+		for _, o := range observers {
+			o.Docked(pair[0], pair[1])
+		}
+	}
+}
+
+// join locks child to parent, returning the move that snaps child's body into place. d.mut must be
+// held.
+func (d *Docking) join(parent, child *DockingPort) bodyMove {
+	delta, _ := child.alignment(parent)
+	childRot := delta.Mul(child.Body.Rotation()).Normalize()
+	childPort := child.Body.Location().Add(childRot.Rotate(child.Position))
+	childLoc := child.Body.Location().Add(parent.Location().Sub(childPort))
+
+	rot := parent.Body.Rotation().Normalize()
+	j := &dockingJoint{
+		parentPort: parent,
+		childPort:  child,
+		offset:     rot.Inverse().Rotate(childLoc.Sub(parent.Body.Location())),
+		rotation:   rot.Inverse().Mul(childRot),
+	}
+	d.joints[parent] = j
+	d.joints[child] = j
+
+	return bodyMove{
+		body:     child.Body,
+		rotation: childRot,
+		location: childLoc,
+		velocity: parent.Body.Velocity(),
+		angularV: parent.Body.AngularV(),
+	}
+}
+
+// isReleased reports whether a and b were undocked and haven't yet moved out of range of each other.
+// d.mut must be held.
+func (d *Docking) isReleased(a, b *DockingPort) bool {
+	if _, ok := d.released[[2]*DockingPort{a, b}]; ok {
+		return true
+	}
+	_, ok := d.released[[2]*DockingPort{b, a}]
+	return ok
+}
@@ -0,0 +1,116 @@
+package univ
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// attachedEmitter is a particle emitter carried by a body, at an offset and pointing in a direction in
+// the body's local space, or in the space of one of its bones.
+type attachedEmitter struct {
+	emitter   *draw.Emitter
+	offset    mgl32.Vec3
+	direction mgl32.Vec3
+	// mesh is the animated mesh whose bone carries the emitter, or nil if the body carries it.
+	mesh *draw.Mesh
+	bone int32
+}
+
+// AddEmitter adds e to the particle emitters drawn in u.
+func (u *Universe) AddEmitter(e *draw.Emitter) {
+	u.Window.AddEmitter(e)
+}
+
+// RemoveEmitter removes e from the particle emitters drawn in u, along with its particles.
+func (u *Universe) RemoveEmitter(e *draw.Emitter) {
+	u.Window.RemoveEmitter(e)
+}
+
+// AttachEmitter attaches e to b at offset, pointing in direction, both in b's local space, so that e
+// moves and turns with b and its particles inherit b's velocity. Attaching an emitter doesn't draw it;
+// it must also be added to b's universe. Emitters attached to a body are removed from its universe
+// along with it.
+func (b *Body) AttachEmitter(e *draw.Emitter, offset, direction mgl32.Vec3) {
+	b.emittersMut.Lock()
+	b.emitters = append(b.emitters, attachedEmitter{emitter: e, offset: offset, direction: direction})
+	b.emittersMut.Unlock()
+	b.placeEmitters()
+}
+
+// AttachEmitterToBone attaches e to the bone of b's model called bone, at offset and pointing in
+// direction in b's local space as the model was made, so that e follows the bone as b is animated.
+func (b *Body) AttachEmitterToBone(e *draw.Emitter, bone string, offset, direction mgl32.Vec3) error {
+	for i, a := range b.animators {
+		if a == nil {
+			continue
+		}
+		if id, ok := a.BoneID(bone); ok {
+			b.emittersMut.Lock()
+			b.emitters = append(b.emitters, attachedEmitter{
+				emitter:   e,
+				offset:    offset,
+				direction: direction,
+				mesh:      b.meshes[i],
+				bone:      id,
+			})
+			b.emittersMut.Unlock()
+			b.placeEmitters()
+			return nil
+		}
+	}
+	return fmt.Errorf("attach emitter to %s of %s: no such bone", bone, b.modelPath)
+}
+
+// DetachEmitter detaches e from b, leaving it where it is. If e isn't attached to b, DetachEmitter has
+// no effect.
+func (b *Body) DetachEmitter(e *draw.Emitter) {
+	b.emittersMut.Lock()
+	defer b.emittersMut.Unlock()
+	for i, a := range b.emitters {
+		if a.emitter == e {
+			b.emitters = append(b.emitters[:i], b.emitters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Emitters returns the particle emitters attached to b.
+func (b *Body) Emitters() []*draw.Emitter {
+	b.emittersMut.Lock()
+	defer b.emittersMut.Unlock()
+	emitters := make([]*draw.Emitter, len(b.emitters))
+	for i, a := range b.emitters {
+		emitters[i] = a.emitter
+	}
+	return emitters
+}
+
+// placeEmitters moves the emitters attached to b to follow its location and rotation, and the bones
+// they are attached to.
+func (b *Body) placeEmitters() {
+	loc, rot := b.Location(), b.Rotation().Normalize()
+
+	b.emittersMut.Lock()
+	defer b.emittersMut.Unlock()
+	for _, a := range b.emitters {
+		offset, direction := a.offset, a.direction
+		if a.mesh != nil {
+			bone := a.mesh.BoneTransform(a.bone)
+			offset = mgl32.TransformCoordinate(offset, bone)
+			direction = mgl32.TransformNormal(direction, bone)
+		}
+		a.emitter.SetLocation(loc.Add(rot.Rotate(offset)))
+		a.emitter.SetDirection(rot.Rotate(direction))
+	}
+}
+
+// setEmittersVelocity sets the velocity the particles of the emitters attached to b inherit.
+func (b *Body) setEmittersVelocity(velocity mgl32.Vec3) {
+	b.emittersMut.Lock()
+	defer b.emittersMut.Unlock()
+	for _, a := range b.emitters {
+		a.emitter.SetVelocity(velocity)
+	}
+}
@@ -47,16 +47,58 @@ func NewAngularAcceleration(b *Body, vector mgl32.Vec3) *Acceleration {
 // Start starts this force accelerating its body.
 func (a *Acceleration) Start() {
 	a.accelTicker.Start()
+	a.body.addAcceleration(a)
 }
 
 // Pause stops this force from accelerating its body, but continues applying its velocity.
 func (a *Acceleration) Pause() {
 	a.accelTicker.Stop()
+	a.body.removeAcceleration(a)
+}
+
+// LinearVector returns the linear acceleration a applies, in its body's local space.
+func (a *Acceleration) LinearVector() mgl32.Vec3 {
+	return a.linearVector
+}
+
+// AngularVector returns the angular acceleration a applies, in its body's local space.
+func (a *Acceleration) AngularVector() mgl32.Vec3 {
+	return a.angularVector
 }
 
 // Destroy stops f and cleans up its resources. f should not be used after it is destroyed.
 func (a *Acceleration) Destroy() {
 	a.accelTicker.Close()
+	a.body.removeAcceleration(a)
+}
+
+// Accelerations returns the accelerations that are started on b.
+func (b *Body) Accelerations() []*Acceleration {
+	b.accelerationsMut.Lock()
+	defer b.accelerationsMut.Unlock()
+	return append([]*Acceleration(nil), b.accelerations...)
+}
+
+func (b *Body) addAcceleration(a *Acceleration) {
+	b.accelerationsMut.Lock()
+	defer b.accelerationsMut.Unlock()
+	for _, other := range b.accelerations {
+		if other == a {
+			return
+		}
+	}
+	b.accelerations = append(b.accelerations, a)
+}
+
+func (b *Body) removeAcceleration(a *Acceleration) {
+	b.accelerationsMut.Lock()
+	defer b.accelerationsMut.Unlock()
+	for i, other := range b.accelerations {
+		if other == a {
+			b.accelerations = append(b.accelerations[:i], b.accelerations[i+1:]...)
+			return
+		}
+	}
 }
 
 func (a *Acceleration) accelTick(elapsed float32) {
@@ -1,67 +1,235 @@
 package univ
 
 import (
+	"math"
 	"sync"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lsmith130/space/draw"
 )
 
-// FreeCam is a camera that moves independantly of any body.
-type FreeCam struct {
-	mut      sync.RWMutex
-	location mgl32.Vec3
-	rotation mgl32.Quat
-	ticker   *draw.Ticker
+// FreeCamControl is a fly control of a FreeCam that can be switched on and off.
+type FreeCamControl int
+
+const (
+	FreeCamForward FreeCamControl = iota
+	FreeCamBack
+	FreeCamLeft
+	FreeCamRight
+	FreeCamUp
+	FreeCamDown
+	// FreeCamFast and FreeCamSlow multiply and divide the speed of the camera while they are on.
+	FreeCamFast
+	FreeCamSlow
+	freeCamControlCount
+)
+
+const (
+	// DefaultFreeCamSpeed is the speed a FreeCam flies at, in units per second.
+	DefaultFreeCamSpeed = 10
+	// DefaultFreeCamSensitivity is how far a FreeCam turns per unit of look input, in radians.
+	DefaultFreeCamSensitivity = 0.003
+
+	// freeCamSpeedFactor is how much FreeCamFast and FreeCamSlow change the speed of a FreeCam by.
+	freeCamSpeedFactor = 5
+	// freeCamMaxPitch is how far a FreeCam can look up or down, short of straight up or down where yaw
+	// stops making sense.
+	freeCamMaxPitch = math.Pi/2 - 0.01
+)
 
-	window *draw.Window
+// FreeCam is a camera that moves independantly of any body. It can be flown around with SetControl and
+// Look.
+type FreeCam struct {
+	mut         sync.RWMutex
+	location    mgl32.Vec3
+	rotation    mgl32.Quat
+	projection  draw.Projection
+	controls    [freeCamControlCount]bool
+	speed       float32
+	sensitivity float32
+	ticker      *draw.Ticker
 }
 
-// NewFreeCam creates a new FreeCam with the specified target and update interval.
-func NewFreeCam(w *draw.Window, window *draw.Window) *FreeCam {
+// NewFreeCam creates a new FreeCam at the origin looking along -z. The camera isn't drawn from until
+// it is added to a CameraManager and activated.
+func NewFreeCam() *FreeCam {
 	cam := &FreeCam{
-		window: window,
+		rotation:    mgl32.QuatIdent(),
+		projection:  draw.DefaultProjection,
+		speed:       DefaultFreeCamSpeed,
+		sensitivity: DefaultFreeCamSensitivity,
 	}
 	cam.ticker = draw.NewTicker(DefaultRefreshRate, cam.tick)
-	cam.ticker.Start()
 	return cam
 }
 
-// Destroy stops the camera from updating. Always call Destroy on ChaseCams that are no longer needed.
+// Destroy stops the camera from updating. Always call Destroy on FreeCams that are no longer needed.
 func (cam *FreeCam) Destroy() {
 	cam.ticker.Close()
 }
 
 func (cam *FreeCam) tick(elapsed float32) {
 	cam.mut.Lock()
-	transform := cam.rotation.Normalize().Mat4().Mul4(mgl32.Translate3D(cam.location.Elem()))
-	cam.window.SetView(transform, cam.location)
+	defer cam.mut.Unlock()
+
+	axis := func(positive, negative FreeCamControl) float32 {
+		var v float32
+		if cam.controls[positive] {
+			v++
+		}
+		if cam.controls[negative] {
+			v--
+		}
+		return v
+	}
+
+	// The camera looks along -z with +x to its right.
+	move := mgl32.Vec3{
+		axis(FreeCamRight, FreeCamLeft),
+		axis(FreeCamUp, FreeCamDown),
+		axis(FreeCamBack, FreeCamForward),
+	}
+	if move.Len() == 0 {
+		return
+	}
+
+	speed := cam.speed
+	if cam.controls[FreeCamFast] {
+		speed *= freeCamSpeedFactor
+	}
+	if cam.controls[FreeCamSlow] {
+		speed /= freeCamSpeedFactor
+	}
+	cam.location = cam.location.Add(cam.rotation.Rotate(move.Normalize()).Mul(speed * elapsed))
+}
+
+// SetControl switches a fly control of cam on or off.
+func (cam *FreeCam) SetControl(control FreeCamControl, enable bool) {
+	cam.mut.Lock()
+	cam.controls[control] = enable
 	cam.mut.Unlock()
 }
 
-// GetLocation returns the current location of b
-func (cam *FreeCam) GetLocation() mgl32.Vec3 {
+// ClearControls switches all of cam's fly controls off.
+func (cam *FreeCam) ClearControls() {
+	cam.mut.Lock()
+	cam.controls = [freeCamControlCount]bool{}
+	cam.mut.Unlock()
+}
+
+// Look turns cam right by dx and down by dy, such as the distance the mouse moved. Turns are scaled by
+// cam's sensitivity, and cam can't look past straight up or down or roll.
+func (cam *FreeCam) Look(dx, dy float32) {
+	cam.mut.Lock()
+	defer cam.mut.Unlock()
+
+	forward := cam.rotation.Rotate(mgl32.Vec3{0, 0, -1})
+	yaw := float32(math.Atan2(float64(-forward.X()), float64(-forward.Z())))
+	pitch := float32(math.Asin(float64(mgl32.Clamp(forward.Y(), -1, 1))))
+
+	yaw -= dx * cam.sensitivity
+	pitch = mgl32.Clamp(pitch-dy*cam.sensitivity, -freeCamMaxPitch, freeCamMaxPitch)
+
+	cam.rotation = mgl32.QuatRotate(yaw, mgl32.Vec3{0, 1, 0}).Mul(mgl32.QuatRotate(pitch, mgl32.Vec3{1, 0, 0}))
+}
+
+// Speed returns the speed cam flies at, in units per second.
+func (cam *FreeCam) Speed() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.speed
+}
+
+// SetSpeed sets the speed cam flies at, in units per second.
+func (cam *FreeCam) SetSpeed(speed float32) {
+	cam.mut.Lock()
+	cam.speed = speed
+	cam.mut.Unlock()
+}
+
+// ScaleSpeed multiplies the speed cam flies at by factor.
+func (cam *FreeCam) ScaleSpeed(factor float32) {
+	cam.mut.Lock()
+	cam.speed *= factor
+	cam.mut.Unlock()
+}
+
+// Sensitivity returns how far cam turns per unit of look input, in radians.
+func (cam *FreeCam) Sensitivity() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.sensitivity
+}
+
+// SetSensitivity sets how far cam turns per unit of look input, in radians.
+func (cam *FreeCam) SetSensitivity(sensitivity float32) {
+	cam.mut.Lock()
+	cam.sensitivity = sensitivity
+	cam.mut.Unlock()
+}
+
+// View conforms to Camera.View
+func (cam *FreeCam) View() CameraView {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return CameraView{
+		Eye:        cam.location,
+		Rotation:   cam.rotation,
+		Projection: cam.projection,
+	}
+}
+
+// FOV returns the vertical field of view of cam, in radians.
+func (cam *FreeCam) FOV() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.projection.FOV
+}
+
+// SetFOV sets the vertical field of view of cam, in radians.
+func (cam *FreeCam) SetFOV(fov float32) {
+	cam.mut.Lock()
+	cam.projection.FOV = fov
+	cam.mut.Unlock()
+}
+
+// Projection returns how cam projects the scene.
+func (cam *FreeCam) Projection() draw.Projection {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.projection
+}
+
+// SetProjection sets how cam projects the scene.
+func (cam *FreeCam) SetProjection(p draw.Projection) {
+	cam.mut.Lock()
+	cam.projection = p
+	cam.mut.Unlock()
+}
+
+// Location returns the current location of cam
+func (cam *FreeCam) Location() mgl32.Vec3 {
 	cam.mut.RLock()
 	defer cam.mut.RUnlock()
 	return cam.location
 }
 
-// Translate translates the location of b by offset
+// Translate translates the location of cam by offset
 func (cam *FreeCam) Translate(offset mgl32.Vec3) {
 	cam.mut.Lock()
 	cam.location = cam.location.Add(offset)
 	cam.mut.Unlock()
 }
 
-// SetLocation sets the location of b
+// SetLocation sets the location of cam
 func (cam *FreeCam) SetLocation(loc mgl32.Vec3) {
 	cam.mut.Lock()
 	cam.location = loc
 	cam.mut.Unlock()
 }
 
-// GetRotation gets the rotation of cam
-func (cam *FreeCam) GetRotation() mgl32.Quat {
+// Rotation gets the rotation of cam
+func (cam *FreeCam) Rotation() mgl32.Quat {
 	cam.mut.RLock()
 	defer cam.mut.RUnlock()
 	return cam.rotation
@@ -80,3 +248,10 @@ func (cam *FreeCam) SetRotation(rot mgl32.Quat) {
 	cam.rotation = rot
 	cam.mut.Unlock()
 }
+
+// LookAt turns cam to face center, with its top pointing as close to up as possible.
+func (cam *FreeCam) LookAt(center, up mgl32.Vec3) {
+	cam.mut.Lock()
+	cam.rotation = LookRotation(cam.location.Sub(center), up)
+	cam.mut.Unlock()
+}
@@ -0,0 +1,72 @@
+package univ
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// attachedLight is a light carried by a body, at an offset and shining in a direction in the body's
+// local space.
+type attachedLight struct {
+	light     *draw.Light
+	offset    mgl32.Vec3
+	direction mgl32.Vec3
+}
+
+// AddLight adds l to the lights that shine on u, such as a sun or a light attached to a body.
+func (u *Universe) AddLight(l *draw.Light) {
+	u.Window.AddLight(l)
+}
+
+// RemoveLight removes l from the lights that shine on u.
+func (u *Universe) RemoveLight(l *draw.Light) {
+	u.Window.RemoveLight(l)
+}
+
+// AttachLight attaches l to b at offset, shining in direction, both in b's local space, so that l moves
+// and turns with b. Attaching a light doesn't make it shine; it must also be added to b's universe.
+// Lights attached to a body are removed from its universe along with it.
+func (b *Body) AttachLight(l *draw.Light, offset, direction mgl32.Vec3) {
+	b.lightsMut.Lock()
+	b.lights = append(b.lights, attachedLight{light: l, offset: offset, direction: direction})
+	b.lightsMut.Unlock()
+	b.placeLights()
+}
+
+// DetachLight detaches l from b, leaving it where it is. If l isn't attached to b, DetachLight has no
+// effect.
+func (b *Body) DetachLight(l *draw.Light) {
+	b.lightsMut.Lock()
+	defer b.lightsMut.Unlock()
+	for i, a := range b.lights {
+		if a.light == l {
+			b.lights = append(b.lights[:i], b.lights[i+1:]...)
+			return
+		}
+	}
+}
+
+// Lights returns the lights attached to b.
+func (b *Body) Lights() []*draw.Light {
+	b.lightsMut.Lock()
+	defer b.lightsMut.Unlock()
+	lights := make([]*draw.Light, len(b.lights))
+	for i, a := range b.lights {
+		lights[i] = a.light
+	}
+	return lights
+}
+
+// placeLights moves the lights attached to b to follow its location and rotation.
+func (b *Body) placeLights() {
+	loc, rot := b.Location(), b.Rotation().Normalize()
+
+	b.lightsMut.Lock()
+	defer b.lightsMut.Unlock()
+	for _, a := range b.lights {
+		a.light.SetLocation(loc.Add(rot.Rotate(a.offset)))
+		if a.direction.Len() > 0 {
+			a.light.SetDirection(rot.Rotate(a.direction))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package univ
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lsmith130/space/dae"
+	"github.com/lsmith130/space/draw"
+	"github.com/tbogdala/gombz"
+)
+
+// AllMaterials is the name in Materials that overrides every material of a model not overridden by its
+// own name.
+const AllMaterials = "*"
+
+// Materials overrides the materials of a model by name.
+type Materials map[string]*draw.Material
+
+// Texture returns the texture of the image file at path, loading it the first time it is asked for.
+// Textures are shared by every body in u that uses the same file.
+func (u *Universe) Texture(path string) (*draw.Texture, error) {
+	u.texturesMut.Lock()
+	defer u.texturesMut.Unlock()
+
+	if t, ok := u.textures[path]; ok {
+		return t, nil
+	}
+	t, err := draw.NewTexture(path)
+	if err != nil {
+		return nil, fmt.Errorf("load texture %s: %v", path, err)
+	}
+	u.textures[path] = t
+	return t, nil
+}
+
+// modelMaterials returns the material name of each of meshes, loaded from the model at modelPath, and
+// the material each is drawn with. Materials are read from Collada models, and overrides replace them by
+// name. Meshes of other models, or without a material, are drawn with a plain material unless
+// overridden.
+func (u *Universe) modelMaterials(modelPath string, meshes []*gombz.Mesh, overrides Materials) ([]string, []*draw.Material, error) {
+	names := make([]string, len(meshes))
+	fileMaterials := make(map[string]dae.Material)
+
+	if strings.EqualFold(filepath.Ext(modelPath), ".dae") {
+		f, err := dae.Open(modelPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load materials of %s: %v", modelPath, err)
+		}
+		fileMaterials = f.Materials()
+		if n, err := meshMaterialNames(meshes, f.MeshMaterials()); err != nil {
+			// Without knowing which mesh is which, draw them all as unnamed, which overrides of
+			// AllMaterials still apply to.
+			log.Printf("load materials of %s: %v", modelPath, err)
+		} else {
+			names = n
+		}
+	}
+
+	loaded := make(map[string]*draw.Material)
+	materials := make([]*draw.Material, len(meshes))
+	for i, name := range names {
+		if m, ok := overrides[name]; ok {
+			materials[i] = m
+			continue
+		}
+		if m, ok := overrides[AllMaterials]; ok {
+			materials[i] = m
+			continue
+		}
+		if m, ok := loaded[name]; ok {
+			materials[i] = m
+			continue
+		}
+
+		m := draw.NewMaterial(name)
+		if fm, ok := fileMaterials[name]; ok {
+			m = u.material(fm, filepath.Dir(modelPath))
+		}
+		loaded[name] = m
+		materials[i] = m
+	}
+	return names, materials, nil
+}
+
+// material creates the material fm of a model in dir. Maps whose images can't be loaded are left off,
+// leaving their channel a plain color. See-through materials are blended.
+func (u *Universe) material(fm dae.Material, dir string) *draw.Material {
+	m := draw.NewMaterial(fm.Name)
+	m.DiffuseColor = fm.DiffuseColor
+	m.SpecularColor = fm.SpecularColor
+	m.Shininess = fm.Shininess
+	m.Roughness = draw.ShininessRoughness(fm.Shininess)
+	m.EmissiveColor = fm.EmissiveColor
+	m.Opacity = fm.Opacity
+	if fm.Opacity < 1 {
+		m.Alpha = draw.AlphaBlend
+	} else if fm.TransparentMap != "" {
+		// A transparency map in a solid material cuts it out, by the alpha of its diffuse map.
+		m.Alpha = draw.AlphaMask
+	}
+
+	load := func(image string) *draw.Texture {
+		if image == "" {
+			return nil
+		}
+		t, err := u.Texture(imagePath(image, dir))
+		if err != nil {
+			log.Printf("load material %s: %v", fm.Name, err)
+			return nil
+		}
+		return t
+	}
+	m.DiffuseMap = load(fm.DiffuseMap)
+	m.SpecularMap = load(fm.SpecularMap)
+	m.NormalMap = load(fm.NormalMap)
+	m.EmissiveMap = load(fm.EmissiveMap)
+	return m
+}
+
+// imagePath finds the image a model in dir refers to. Models are often exported with the paths images
+// had on the artist's machine, so when image isn't found where it says, it is looked for by name in dir.
+func imagePath(image, dir string) string {
+	path := image
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	// Paths may have been written on a system with different separators.
+	base := image[strings.LastIndexAny(image, `/\`)+1:]
+	return filepath.Join(dir, base)
+}
+
+// meshMaterialNames returns the material name of each of meshes from the meshes of a Collada file.
+// Model loaders can split large meshes in parts, so each mesh of the file covers loaded meshes until
+// their faces add up to its triangles. They can also merge meshes with the same material, so a loaded
+// mesh may cover several meshes of the file. It returns an error if the meshes don't line up.
+func meshMaterialNames(meshes []*gombz.Mesh, fileMeshes []dae.MeshMaterial) ([]string, error) {
+	// Loaders create no mesh for a group without triangles.
+	var file []dae.MeshMaterial
+	for _, fm := range fileMeshes {
+		if fm.Triangles > 0 {
+			file = append(file, fm)
+		}
+	}
+
+	names := make([]string, len(meshes))
+	j, remaining := 0, 0
+	if len(file) > 0 {
+		remaining = file[0].Triangles
+	}
+	for i, m := range meshes {
+		if j >= len(file) {
+			return nil, fmt.Errorf("%v meshes loaded, more than the file's triangles cover", len(meshes))
+		}
+		names[i] = file[j].Material
+		for faces := int(m.FaceCount); faces > 0; {
+			if j >= len(file) {
+				return nil, fmt.Errorf("mesh %v has %v more faces than the file's triangles", i, faces)
+			}
+			if file[j].Material != names[i] {
+				return nil, fmt.Errorf("mesh %v covers meshes of the file with materials %q and %q", i, names[i], file[j].Material)
+			}
+			if faces < remaining {
+				remaining -= faces
+				break
+			}
+			faces -= remaining
+			j++
+			if j < len(file) {
+				remaining = file[j].Triangles
+			}
+		}
+	}
+	if j < len(file) {
+		return nil, fmt.Errorf("%v meshes loaded cover fewer triangles than the file has", len(meshes))
+	}
+	return names, nil
+}
+
+// MaterialNames returns the names of the materials of b's model, in the order they first appear.
+func (b *Body) MaterialNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range b.materialNames {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Material returns the material drawn on the meshes of b's model whose material is called name, or nil
+// if no mesh has that material.
+func (b *Body) Material(name string) *draw.Material {
+	for i, n := range b.materialNames {
+		if n == name {
+			return b.meshes[i].Material()
+		}
+	}
+	return nil
+}
+
+// SetMaterial draws m on the meshes of b's model whose material is called name. It returns an error if
+// no mesh has that material.
+func (b *Body) SetMaterial(name string, m *draw.Material) error {
+	found := false
+	for i, n := range b.materialNames {
+		if n == name {
+			b.meshes[i].SetMaterial(m)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("set material %s of %s: no such material", name, b.modelPath)
+	}
+	return nil
+}
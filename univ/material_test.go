@@ -0,0 +1,113 @@
+package univ
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lsmith130/space/dae"
+	"github.com/lsmith130/space/draw"
+	"github.com/tbogdala/gombz"
+)
+
+func TestMeshMaterialNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		faces      []uint32
+		fileMeshes []dae.MeshMaterial
+		want       []string
+	}{
+		{
+			name:       "one to one",
+			faces:      []uint32{10, 4},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "metal", Triangles: 4}},
+			want:       []string{"paint", "metal"},
+		},
+		{
+			name:       "split",
+			faces:      []uint32{6, 4, 4},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "metal", Triangles: 4}},
+			want:       []string{"paint", "paint", "metal"},
+		},
+		{
+			name:       "merged",
+			faces:      []uint32{12, 3},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "paint", Triangles: 2}, {Material: "metal", Triangles: 3}},
+			want:       []string{"paint", "metal"},
+		},
+		{
+			name:       "merged and split",
+			faces:      []uint32{5, 7, 3},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "paint", Triangles: 2}, {Material: "metal", Triangles: 3}},
+			want:       []string{"paint", "paint", "metal"},
+		},
+		{
+			name:       "empty groups",
+			faces:      []uint32{10, 4},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "glass", Triangles: 0}, {Material: "metal", Triangles: 4}},
+			want:       []string{"paint", "metal"},
+		},
+		{
+			name:       "merged across materials",
+			faces:      []uint32{14},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "metal", Triangles: 4}},
+		},
+		{
+			name:       "too many faces",
+			faces:      []uint32{10, 4, 2},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "metal", Triangles: 4}},
+		},
+		{
+			name:       "too few faces",
+			faces:      []uint32{10, 2},
+			fileMeshes: []dae.MeshMaterial{{Material: "paint", Triangles: 10}, {Material: "metal", Triangles: 4}},
+		},
+		{
+			name:       "no file meshes",
+			faces:      []uint32{10},
+			fileMeshes: nil,
+		},
+	}
+	for _, test := range tests {
+		meshes := make([]*gombz.Mesh, len(test.faces))
+		for i, f := range test.faces {
+			meshes[i] = &gombz.Mesh{FaceCount: f}
+		}
+		got, err := meshMaterialNames(meshes, test.fileMeshes)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: got names %q, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMaterialAlpha(t *testing.T) {
+	tests := []struct {
+		name  string
+		fm    dae.Material
+		alpha draw.AlphaMode
+	}{
+		{"opaque", dae.Material{Name: "paint", Opacity: 1}, draw.AlphaOpaque},
+		{"see-through", dae.Material{Name: "glass", Opacity: 0.5}, draw.AlphaBlend},
+		{"cut out", dae.Material{Name: "grate", Opacity: 1, TransparentMap: "grate.png"}, draw.AlphaMask},
+		{"see-through and cut out", dae.Material{Name: "visor", Opacity: 0.5, TransparentMap: "visor.png"}, draw.AlphaBlend},
+	}
+	u := &Universe{}
+	for _, test := range tests {
+		m := u.material(test.fm, "")
+		if m.Alpha != test.alpha {
+			t.Errorf("%s: got alpha mode %v, want %v", test.name, m.Alpha, test.alpha)
+		}
+		if m.Opacity != test.fm.Opacity {
+			t.Errorf("%s: got opacity %v, want %v", test.name, m.Opacity, test.fm.Opacity)
+		}
+	}
+}
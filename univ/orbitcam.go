@@ -0,0 +1,219 @@
+package univ
+
+import (
+	"math"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+const (
+	// DefaultOrbitSensitivity is how far an OrbitCam circles per unit of drag, in radians.
+	DefaultOrbitSensitivity = 0.005
+	// DefaultOrbitZoom is the fraction each step of zoom changes the radius of an OrbitCam by.
+	DefaultOrbitZoom = 0.1
+
+	// orbitMaxElevation keeps an OrbitCam short of directly above or below its target, where azimuth
+	// stops making sense.
+	orbitMaxElevation = math.Pi/2 - 0.01
+)
+
+// OrbitCam is a camera that circles a body at a distance, always looking at it. Its position around the
+// body is set by an azimuth around the world's y axis and an elevation above the body, and it follows
+// the body as it moves but not as it turns.
+type OrbitCam struct {
+	mut         sync.RWMutex
+	target      *Body
+	radius      float32
+	minRadius   float32
+	maxRadius   float32
+	azimuth     float32
+	elevation   float32
+	autoRotate  float32
+	sensitivity float32
+	projection  draw.Projection
+	ticker      *draw.Ticker
+}
+
+// NewOrbitCam creates a new OrbitCam circling target at radius, level with it and on its -z side. The
+// camera isn't drawn from until it is added to a CameraManager and activated.
+func NewOrbitCam(target *Body, radius float32) *OrbitCam {
+	cam := &OrbitCam{
+		target:      target,
+		radius:      radius,
+		maxRadius:   float32(math.Inf(1)),
+		sensitivity: DefaultOrbitSensitivity,
+		projection:  draw.DefaultProjection,
+	}
+	cam.ticker = draw.NewTicker(DefaultRefreshRate, cam.tick)
+	return cam
+}
+
+// Destroy stops the camera from updating. Always call Destroy on OrbitCams that are no longer needed.
+func (cam *OrbitCam) Destroy() {
+	cam.ticker.Close()
+}
+
+func (cam *OrbitCam) tick(elapsed float32) {
+	cam.mut.Lock()
+	cam.azimuth = wrapAngle(cam.azimuth + cam.autoRotate*elapsed)
+	cam.mut.Unlock()
+}
+
+// View conforms to Camera.View
+func (cam *OrbitCam) View() CameraView {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+
+	center := cam.target.Location()
+	sinA, cosA := math.Sincos(float64(cam.azimuth))
+	sinE, cosE := math.Sincos(float64(cam.elevation))
+	offset := mgl32.Vec3{
+		float32(sinA * cosE),
+		float32(sinE),
+		float32(-cosA * cosE),
+	}.Mul(cam.radius)
+
+	return LookAtView(center.Add(offset), center, mgl32.Vec3{0, 1, 0}, cam.projection)
+}
+
+// Target returns the body cam is circling.
+func (cam *OrbitCam) Target() *Body {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.target
+}
+
+// SetTarget moves cam to circle target, keeping its radius, azimuth and elevation.
+func (cam *OrbitCam) SetTarget(target *Body) {
+	cam.mut.Lock()
+	cam.target = target
+	cam.mut.Unlock()
+}
+
+// FOV returns the vertical field of view of cam, in radians.
+func (cam *OrbitCam) FOV() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.projection.FOV
+}
+
+// SetFOV sets the vertical field of view of cam, in radians.
+func (cam *OrbitCam) SetFOV(fov float32) {
+	cam.mut.Lock()
+	cam.projection.FOV = fov
+	cam.mut.Unlock()
+}
+
+// Projection returns how cam projects the scene.
+func (cam *OrbitCam) Projection() draw.Projection {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.projection
+}
+
+// SetProjection sets how cam projects the scene.
+func (cam *OrbitCam) SetProjection(p draw.Projection) {
+	cam.mut.Lock()
+	cam.projection = p
+	cam.mut.Unlock()
+}
+
+// Radius returns the distance of cam from its target.
+func (cam *OrbitCam) Radius() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.radius
+}
+
+// SetRadius sets the distance of cam from its target, clamped to its radius limits.
+func (cam *OrbitCam) SetRadius(radius float32) {
+	cam.mut.Lock()
+	cam.radius = mgl32.Clamp(radius, cam.minRadius, cam.maxRadius)
+	cam.mut.Unlock()
+}
+
+// SetRadiusLimits sets how close and how far cam can zoom from its target.
+func (cam *OrbitCam) SetRadiusLimits(min, max float32) {
+	cam.mut.Lock()
+	cam.minRadius = min
+	cam.maxRadius = max
+	cam.radius = mgl32.Clamp(cam.radius, min, max)
+	cam.mut.Unlock()
+}
+
+// Azimuth returns the angle of cam around the world's y axis, in radians. At zero, cam is on the -z
+// side of its target.
+func (cam *OrbitCam) Azimuth() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.azimuth
+}
+
+// SetAzimuth sets the angle of cam around the world's y axis, in radians.
+func (cam *OrbitCam) SetAzimuth(azimuth float32) {
+	cam.mut.Lock()
+	cam.azimuth = wrapAngle(azimuth)
+	cam.mut.Unlock()
+}
+
+// Elevation returns the angle of cam above its target, in radians.
+func (cam *OrbitCam) Elevation() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.elevation
+}
+
+// SetElevation sets the angle of cam above its target, in radians, short of directly above or below.
+func (cam *OrbitCam) SetElevation(elevation float32) {
+	cam.mut.Lock()
+	cam.elevation = mgl32.Clamp(elevation, -orbitMaxElevation, orbitMaxElevation)
+	cam.mut.Unlock()
+}
+
+// AutoRotate returns the speed cam circles its target on its own, in radians per second.
+func (cam *OrbitCam) AutoRotate() float32 {
+	cam.mut.RLock()
+	defer cam.mut.RUnlock()
+	return cam.autoRotate
+}
+
+// SetAutoRotate sets the speed cam circles its target on its own, in radians per second. Zero stops
+// cam from circling on its own.
+func (cam *OrbitCam) SetAutoRotate(speed float32) {
+	cam.mut.Lock()
+	cam.autoRotate = speed
+	cam.mut.Unlock()
+}
+
+// SetSensitivity sets how far cam circles per unit of drag, in radians.
+func (cam *OrbitCam) SetSensitivity(sensitivity float32) {
+	cam.mut.Lock()
+	cam.sensitivity = sensitivity
+	cam.mut.Unlock()
+}
+
+// Drag circles cam around its target by dx and dy, such as the distance the mouse moved while a button
+// was held. Dragging right circles cam left so the target appears to turn with the drag, and dragging
+// down raises cam.
+func (cam *OrbitCam) Drag(dx, dy float32) {
+	cam.mut.Lock()
+	cam.azimuth = wrapAngle(cam.azimuth + dx*cam.sensitivity)
+	cam.elevation = mgl32.Clamp(cam.elevation+dy*cam.sensitivity, -orbitMaxElevation, orbitMaxElevation)
+	cam.mut.Unlock()
+}
+
+// Zoom moves cam closer to its target by steps, such as the distance the scroll wheel moved. Negative
+// steps move cam away.
+func (cam *OrbitCam) Zoom(steps float32) {
+	cam.mut.Lock()
+	radius := cam.radius * float32(math.Pow(1-DefaultOrbitZoom, float64(steps)))
+	cam.radius = mgl32.Clamp(radius, cam.minRadius, cam.maxRadius)
+	cam.mut.Unlock()
+}
+
+// wrapAngle returns a wrapped into [-π, π).
+func wrapAngle(a float32) float32 {
+	return float32(math.Mod(math.Mod(float64(a)+math.Pi, 2*math.Pi)+2*math.Pi, 2*math.Pi) - math.Pi)
+}
@@ -0,0 +1,254 @@
+package univ
+
+import (
+	"math"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// PID is a proportional-integral-derivative controller for a single value.
+type PID struct {
+	P, I, D float32
+	// MaxIntegral limits the magnitude of the accumulated error to prevent windup. Zero means no limit.
+	MaxIntegral float32
+
+	integral  float32
+	lastError float32
+	primed    bool
+}
+
+// Update returns the output of c for the current error, elapsed seconds after the last update.
+func (c *PID) Update(err, elapsed float32) float32 {
+	c.integral += err * elapsed
+	if c.MaxIntegral > 0 {
+		c.integral = mgl32.Clamp(c.integral, -c.MaxIntegral, c.MaxIntegral)
+	}
+
+	var derivative float32
+	if c.primed && elapsed > 0 {
+		derivative = (err - c.lastError) / elapsed
+	}
+	c.lastError = err
+	c.primed = true
+
+	return c.P*err + c.I*c.integral + c.D*derivative
+}
+
+// Reset clears the accumulated state of c.
+func (c *PID) Reset() {
+	c.integral = 0
+	c.lastError = 0
+	c.primed = false
+}
+
+// PIDVec3 is a PID controller for each component of a vector.
+type PIDVec3 struct {
+	P, I, D float32
+	// MaxIntegral limits the length of the accumulated error to prevent windup. Zero means no limit.
+	MaxIntegral float32
+
+	integral  mgl32.Vec3
+	lastError mgl32.Vec3
+	primed    bool
+}
+
+// Update returns the output of c for the current error, elapsed seconds after the last update.
+func (c *PIDVec3) Update(err mgl32.Vec3, elapsed float32) mgl32.Vec3 {
+	c.integral = clampLen(c.integral.Add(err.Mul(elapsed)), c.MaxIntegral)
+
+	var derivative mgl32.Vec3
+	if c.primed && elapsed > 0 {
+		derivative = err.Sub(c.lastError).Mul(1 / elapsed)
+	}
+	c.lastError = err
+	c.primed = true
+
+	return err.Mul(c.P).Add(c.integral.Mul(c.I)).Add(derivative.Mul(c.D))
+}
+
+// Reset clears the accumulated state of c.
+func (c *PIDVec3) Reset() {
+	c.integral = mgl32.Vec3{}
+	c.lastError = mgl32.Vec3{}
+	c.primed = false
+}
+
+// Controller steers a body towards a target by accelerating it.
+type Controller interface {
+	// Control returns the linear and angular acceleration, in world space, to apply to b for the next
+	// elapsed seconds.
+	Control(b *Body, elapsed float32) (linear, angular mgl32.Vec3)
+}
+
+// AttitudeController turns a body to face a target rotation and holds it there.
+type AttitudeController struct {
+	Target mgl32.Quat
+	PID    PIDVec3
+	// MaxAccel limits the angular acceleration applied. Zero means no limit.
+	MaxAccel float32
+}
+
+// NewAttitudeController creates an AttitudeController with gains suited to ships.
+func NewAttitudeController(target mgl32.Quat, maxAccel float32) *AttitudeController {
+	return &AttitudeController{
+		Target:   target,
+		PID:      PIDVec3{P: 4, D: 3},
+		MaxAccel: maxAccel,
+	}
+}
+
+// Control conforms to Controller.Control.
+func (c *AttitudeController) Control(b *Body, elapsed float32) (linear, angular mgl32.Vec3) {
+	// Express the remaining rotation as an axis scaled by its angle, taking the short way around.
+	delta := c.Target.Normalize().Mul(b.Rotation().Normalize().Inverse())
+	if delta.W < 0 {
+		delta = delta.Scale(-1)
+	}
+	var err mgl32.Vec3
+	if sin := delta.V.Len(); sin > 1e-6 {
+		angle := 2 * float32(math.Atan2(float64(sin), float64(delta.W)))
+		err = delta.V.Mul(angle / sin)
+	}
+	return mgl32.Vec3{}, clampLen(c.PID.Update(err, elapsed), c.MaxAccel)
+}
+
+// SpinController brings a body's angular velocity to a target, such as zero to cancel spin.
+type SpinController struct {
+	Target mgl32.Vec3
+	PID    PIDVec3
+	// MaxAccel limits the angular acceleration applied. Zero means no limit.
+	MaxAccel float32
+}
+
+// NewSpinController creates a SpinController with gains suited to ships and astronauts.
+func NewSpinController(target mgl32.Vec3, maxAccel float32) *SpinController {
+	return &SpinController{
+		Target:   target,
+		PID:      PIDVec3{P: 3},
+		MaxAccel: maxAccel,
+	}
+}
+
+// Control conforms to Controller.Control.
+func (c *SpinController) Control(b *Body, elapsed float32) (linear, angular mgl32.Vec3) {
+	return mgl32.Vec3{}, clampLen(c.PID.Update(c.Target.Sub(b.AngularV()), elapsed), c.MaxAccel)
+}
+
+// VelocityController brings a body's velocity to a target, such as zero to kill drift.
+type VelocityController struct {
+	Target mgl32.Vec3
+	PID    PIDVec3
+	// MaxAccel limits the linear acceleration applied. Zero means no limit.
+	MaxAccel float32
+}
+
+// NewVelocityController creates a VelocityController with gains suited to ships.
+func NewVelocityController(target mgl32.Vec3, maxAccel float32) *VelocityController {
+	return &VelocityController{
+		Target:   target,
+		PID:      PIDVec3{P: 2, I: 0.2, MaxIntegral: 10},
+		MaxAccel: maxAccel,
+	}
+}
+
+// Control conforms to Controller.Control.
+func (c *VelocityController) Control(b *Body, elapsed float32) (linear, angular mgl32.Vec3) {
+	return clampLen(c.PID.Update(c.Target.Sub(b.Velocity()), elapsed), c.MaxAccel), mgl32.Vec3{}
+}
+
+// PositionController flies a body to a target location and holds it there. The target may be moving
+// at TargetVelocity, which the body matches on arrival.
+type PositionController struct {
+	Target         mgl32.Vec3
+	TargetVelocity mgl32.Vec3
+	PID            PIDVec3
+	// MaxAccel limits the linear acceleration applied. Zero means no limit.
+	MaxAccel float32
+	// MaxSpeed limits the speed at which the body approaches the target. Zero means no limit.
+	MaxSpeed float32
+}
+
+// NewPositionController creates a PositionController with gains suited to ships.
+func NewPositionController(target mgl32.Vec3, maxAccel, maxSpeed float32) *PositionController {
+	return &PositionController{
+		Target:   target,
+		PID:      PIDVec3{P: 0.8, I: 0.02, D: 1.6, MaxIntegral: 20},
+		MaxAccel: maxAccel,
+		MaxSpeed: maxSpeed,
+	}
+}
+
+// Control conforms to Controller.Control.
+func (c *PositionController) Control(b *Body, elapsed float32) (linear, angular mgl32.Vec3) {
+	accel := c.PID.Update(c.Target.Sub(b.Location()), elapsed)
+
+	// The derivative of the error is the velocity relative to the target, so the derivative term
+	// matches the target's velocity on arrival. Brake whenever the approach is faster than allowed.
+	relV := b.Velocity().Sub(c.TargetVelocity)
+	if c.MaxSpeed > 0 && relV.Len() > c.MaxSpeed {
+		accel = accel.Sub(relV.Normalize().Mul(relV.Len() - c.MaxSpeed).Mul(1 / mgl32.Clamp(elapsed, 1e-3, 1)))
+	}
+	return clampLen(accel, c.MaxAccel), mgl32.Vec3{}
+}
+
+// ControlLoop applies the output of a Controller to a body over time.
+type ControlLoop struct {
+	mut        sync.Mutex
+	body       *Body
+	controller Controller
+	ticker     *draw.Ticker
+}
+
+// NewControlLoop creates a new ControlLoop steering b with c.
+//
+// The new loop is initially paused, and will not steer the body until Start() is called on it.
+func NewControlLoop(b *Body, c Controller) *ControlLoop {
+	l := &ControlLoop{
+		body:       b,
+		controller: c,
+	}
+	l.ticker = draw.NewTicker(DefaultRefreshRate, l.tick)
+	l.ticker.Stop()
+	return l
+}
+
+// SetController replaces the controller l steers its body with.
+func (l *ControlLoop) SetController(c Controller) {
+	l.mut.Lock()
+	l.controller = c
+	l.mut.Unlock()
+}
+
+// Start starts l steering its body.
+func (l *ControlLoop) Start() {
+	l.ticker.Start()
+}
+
+// Pause stops l from steering its body.
+func (l *ControlLoop) Pause() {
+	l.ticker.Stop()
+}
+
+// Destroy stops l and cleans up its resources. l should not be used after it is destroyed.
+func (l *ControlLoop) Destroy() {
+	l.ticker.Close()
+}
+
+func (l *ControlLoop) tick(elapsed float32) {
+	l.mut.Lock()
+	linear, angular := l.controller.Control(l.body, elapsed)
+	l.mut.Unlock()
+
+	l.body.AddVelocity(linear.Mul(elapsed))
+	l.body.AddAngularV(angular.Mul(elapsed))
+}
+
+// clampLen returns v shortened to max if it is longer. A max of zero means no limit.
+func clampLen(v mgl32.Vec3, max float32) mgl32.Vec3 {
+	if l := v.Len(); max > 0 && l > max {
+		return v.Mul(max / l)
+	}
+	return v
+}
@@ -0,0 +1,111 @@
+package univ
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// RayHit is the point where a ray cast into a Universe hit a body.
+type RayHit struct {
+	Body *Body
+	// Point is the location of the hit and Normal is the unit normal of the triangle that was hit.
+	Point, Normal mgl32.Vec3
+	// Distance is how far along the ray the hit is.
+	Distance float32
+}
+
+// Raycast casts a ray from origin along dir and returns the first hit on the triangles of a visible
+// body in u within maxDist, skipping the bodies in ignore. Raycast reports whether anything was hit.
+func (u *Universe) Raycast(origin, dir mgl32.Vec3, maxDist float32, ignore ...*Body) (RayHit, bool) {
+	dir = dir.Normalize()
+
+	u.bodiesMut.Lock()
+	bodies := make([]*Body, 0, len(u.bodies))
+	for b := range u.bodies {
+		bodies = append(bodies, b)
+	}
+	u.bodiesMut.Unlock()
+
+	var hit RayHit
+	found := false
+bodies:
+	for _, b := range bodies {
+		for _, ig := range ignore {
+			if b == ig {
+				continue bodies
+			}
+		}
+		if b.Hidden() {
+			continue
+		}
+
+		// Skip bodies whose bounding sphere the ray misses.
+		loc := b.Location()
+		toCenter := loc.Sub(origin)
+		along := mgl32.Clamp(toCenter.Dot(dir), 0, maxDist)
+		if toCenter.Sub(dir.Mul(along)).Len() > b.Radius() {
+			continue
+		}
+
+		// Test against the body's untransformed triangles by moving the ray into its space.
+		rot := b.Rotation().Normalize()
+		inverse := rot.Inverse()
+		localOrigin := inverse.Rotate(origin.Sub(loc))
+		localDir := inverse.Rotate(dir)
+
+		for _, mesh := range b.meshes {
+			// Skip meshes whose bounds the ray misses, which is most of a large body like a station.
+			limit := maxDist
+			if found {
+				limit = hit.Distance
+			}
+			if !rayHitsBounds(localOrigin, localDir, limit, mesh.Bounds()) {
+				continue
+			}
+			verts := mesh.Vertexes()
+			for _, face := range mesh.Faces() {
+				tri := Triangle{verts[face[0]], verts[face[1]], verts[face[2]]}
+				dist, ok := tri.Intersect(localOrigin, localDir)
+				if !ok || dist > maxDist || (found && dist >= hit.Distance) {
+					continue
+				}
+				found = true
+				hit = RayHit{
+					Body:     b,
+					Point:    origin.Add(dir.Mul(dist)),
+					Normal:   rot.Rotate(tri.Normal()),
+					Distance: dist,
+				}
+			}
+		}
+	}
+	return hit, found
+}
+
+// rayHitsBounds reports whether the ray from origin along the unit vector dir passes through b within
+// maxDist.
+func rayHitsBounds(origin, dir mgl32.Vec3, maxDist float32, b draw.Bounds) bool {
+	near, far := float32(0), maxDist
+	for i := 0; i < 3; i++ {
+		if dir[i] == 0 {
+			if origin[i] < b.Min[i] || origin[i] > b.Max[i] {
+				return false
+			}
+			continue
+		}
+		t0, t1 := (b.Min[i]-origin[i])/dir[i], (b.Max[i]-origin[i])/dir[i]
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		if t0 > near {
+			near = t0
+		}
+		if t1 < far {
+			far = t1
+		}
+		if near > far {
+			return false
+		}
+	}
+	return true
+}
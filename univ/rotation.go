@@ -0,0 +1,25 @@
+package univ
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// LookRotation returns the rotation that turns +z to face forward and +y to point as close to up as possible.
+func LookRotation(forward, up mgl32.Vec3) mgl32.Quat {
+	z := forward.Normalize()
+	x := up.Cross(z)
+	if x.Len() < 1e-6 {
+		// up is parallel to forward, so any perpendicular axis will do.
+		x = mgl32.Vec3{0, 1, 0}.Cross(z)
+		if x.Len() < 1e-6 {
+			x = mgl32.Vec3{1, 0, 0}.Cross(z)
+		}
+	}
+	x = x.Normalize()
+	y := z.Cross(x)
+
+	return mgl32.Mat4ToQuat(mgl32.Mat4{
+		x[0], x[1], x[2], 0,
+		y[0], y[1], y[2], 0,
+		z[0], z[1], z[2], 0,
+		0, 0, 0, 1,
+	}).Normalize()
+}
@@ -0,0 +1,413 @@
+package univ
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lsmith130/space/draw"
+)
+
+// Scene is the metadata of a level that isn't stored in its models, loaded from a JSON scene file.
+type Scene struct {
+	// Bodies holds the metadata of each body in the level by name.
+	Bodies map[string]SceneBody `json:"bodies"`
+	// Cameras holds the cameras of the level by name.
+	Cameras map[string]SceneCamera `json:"cameras"`
+	// Camera is the name of the camera the level starts with.
+	Camera string `json:"camera"`
+	// Lights holds the lights of the level by name.
+	Lights map[string]SceneLight `json:"lights"`
+	// Ambient is the light that reaches every surface of the level. The default is used when it is zero.
+	Ambient mgl32.Vec3 `json:"ambient"`
+	// Sky is the background of the level.
+	Sky *SceneSky `json:"sky"`
+}
+
+// SceneBody is the metadata of a body in a Scene.
+type SceneBody struct {
+	DockingPorts []ScenePort `json:"dockingPorts"`
+	// Materials holds changes to the materials of the body's model by name.
+	Materials map[string]SceneMaterial `json:"materials"`
+}
+
+// ScenePort describes a docking port of a body in a Scene. Range and Tolerance use their defaults
+// when zero, and Tolerance is in degrees.
+type ScenePort struct {
+	Name      string     `json:"name"`
+	Position  mgl32.Vec3 `json:"position"`
+	Forward   mgl32.Vec3 `json:"forward"`
+	Up        mgl32.Vec3 `json:"up"`
+	Range     float32    `json:"range"`
+	Tolerance float32    `json:"tolerance"`
+	Passive   bool       `json:"passive"`
+}
+
+// SceneMaterial describes changes to a material of a body's model in a Scene. Maps are paths of image
+// files, and only the maps and values that are set replace those of the material.
+type SceneMaterial struct {
+	DiffuseMap    string      `json:"diffuseMap"`
+	DiffuseColor  *mgl32.Vec3 `json:"diffuseColor"`
+	SpecularMap   string      `json:"specularMap"`
+	SpecularColor *mgl32.Vec3 `json:"specularColor"`
+	Shininess     *float32    `json:"shininess"`
+	NormalMap     string      `json:"normalMap"`
+	EmissiveMap   string      `json:"emissiveMap"`
+	EmissiveColor *mgl32.Vec3 `json:"emissiveColor"`
+	// Metallic, Roughness and MetallicRoughnessMap are only used by the PBR program.
+	Metallic             *float32 `json:"metallic"`
+	Roughness            *float32 `json:"roughness"`
+	MetallicRoughnessMap string   `json:"metallicRoughnessMap"`
+	// Alpha is "opaque", "mask" to cut the surface away where its alpha is below AlphaCutoff, or "blend"
+	// to blend it by its alpha, which is Opacity times the alpha of its diffuse map.
+	Alpha       string   `json:"alpha"`
+	Opacity     *float32 `json:"opacity"`
+	AlphaCutoff *float32 `json:"alphaCutoff"`
+}
+
+// SceneCamera describes a camera in a Scene. Type is "chase" for a ChaseCam following the body called
+// Target at Offset and looking LookAhead seconds ahead, "free" for a FreeCam at Location looking at
+// LookAt, or "orbit" for an OrbitCam circling the body called Target at Radius, Azimuth and Elevation
+// and turning AutoRotate per second. FOV and all angles are in degrees. Near and Far set the clip planes,
+// and cameras are given a finite far plane when Far is set. Orthographic cameras show Height units from
+// the bottom of the screen to the top. Zero values use the defaults.
+type SceneCamera struct {
+	Type         string     `json:"type"`
+	Target       string     `json:"target"`
+	Offset       mgl32.Vec3 `json:"offset"`
+	LookAhead    float32    `json:"lookAhead"`
+	Location     mgl32.Vec3 `json:"location"`
+	LookAt       mgl32.Vec3 `json:"lookAt"`
+	Radius       float32    `json:"radius"`
+	Azimuth      float32    `json:"azimuth"`
+	Elevation    float32    `json:"elevation"`
+	AutoRotate   float32    `json:"autoRotate"`
+	FOV          float32    `json:"fov"`
+	Near         float32    `json:"near"`
+	Far          float32    `json:"far"`
+	Orthographic bool       `json:"orthographic"`
+	Height       float32    `json:"height"`
+}
+
+// SceneLight describes a light in a Scene. Type is "point", "spot" or "directional". Lights with a Body
+// are attached to the body called Body at Position, shining in Direction, both in the body's local
+// space; other lights are placed in the world. Color is white and Intensity is one when zero, and a zero
+// Range reaches forever. InnerCone and OuterCone are the angles of spot lights in degrees. Shadows is
+// whether the light casts shadows.
+type SceneLight struct {
+	Type      string     `json:"type"`
+	Body      string     `json:"body"`
+	Position  mgl32.Vec3 `json:"position"`
+	Direction mgl32.Vec3 `json:"direction"`
+	Color     mgl32.Vec3 `json:"color"`
+	Intensity float32    `json:"intensity"`
+	Range     float32    `json:"range"`
+	InnerCone float32    `json:"innerCone"`
+	OuterCone float32    `json:"outerCone"`
+	Shadows   bool       `json:"shadows"`
+}
+
+// SceneSky describes the sky of a Scene. Type is "cubemap" for a skybox of the six images Faces, in the
+// order +x, -x, +y, -y, +z, -z, "equirect" for the panorama Image, or "starfield" for a generated sky of
+// stars and nebulae. Starfields are generated from Seed, and Stars, StarBrightness, Nebula and
+// NebulaColors use the defaults when zero. Environment is whether the PBR program is lit by the sky.
+type SceneSky struct {
+	Type           string       `json:"type"`
+	Faces          [6]string    `json:"faces"`
+	Image          string       `json:"image"`
+	Seed           int64        `json:"seed"`
+	Stars          int          `json:"stars"`
+	StarBrightness float32      `json:"starBrightness"`
+	Nebula         float32      `json:"nebula"`
+	NebulaColors   []mgl32.Vec3 `json:"nebulaColors"`
+	Environment    bool         `json:"environment"`
+}
+
+// LoadScene reads the scene file at path.
+func LoadScene(path string) (*Scene, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open scene %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var s Scene
+	if err := json.NewDecoder(f).Decode(&s); err != nil {
+		return nil, fmt.Errorf("parse scene %s: %v", path, err)
+	}
+	return &s, nil
+}
+
+// DockingPorts creates the docking ports the scene describes for the body called name, attached to b.
+func (s *Scene) DockingPorts(name string, b *Body) []*DockingPort {
+	var ports []*DockingPort
+	for _, sp := range s.Bodies[name].DockingPorts {
+		p := NewDockingPort(b, sp.Name, sp.Position, sp.Forward, sp.Up)
+		if sp.Range != 0 {
+			p.Range = sp.Range
+		}
+		if sp.Tolerance != 0 {
+			p.Tolerance = mgl32.DegToRad(sp.Tolerance)
+		}
+		p.Passive = sp.Passive
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// SetupMaterials changes the materials of bodies as the scene describes. bodies holds the bodies of the
+// scene by name, and textures are loaded through u.
+func (s *Scene) SetupMaterials(u *Universe, bodies map[string]*Body) error {
+	for name, sb := range s.Bodies {
+		if len(sb.Materials) == 0 {
+			continue
+		}
+		b, ok := bodies[name]
+		if !ok {
+			return fmt.Errorf("setup materials: no body %q", name)
+		}
+		for material, sm := range sb.Materials {
+			current := b.Material(material)
+			if current == nil {
+				return fmt.Errorf("setup material %s of %s: no such material", material, name)
+			}
+			m, err := sm.apply(u, *current)
+			if err != nil {
+				return fmt.Errorf("setup material %s of %s: %v", material, name, err)
+			}
+			if err := b.SetMaterial(material, m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetupSky sets the sky of w to the one the scene describes, if it describes one.
+func (s *Scene) SetupSky(w *draw.Window) error {
+	if s.Sky == nil {
+		return nil
+	}
+
+	var sky *draw.Sky
+	switch s.Sky.Type {
+	case "cubemap":
+		var err error
+		if sky, err = draw.NewSkybox(s.Sky.Faces); err != nil {
+			return fmt.Errorf("setup sky: %v", err)
+		}
+	case "equirect":
+		var err error
+		if sky, err = draw.NewEquirectSky(s.Sky.Image); err != nil {
+			return fmt.Errorf("setup sky: %v", err)
+		}
+	case "starfield":
+		sf := draw.DefaultStarfield
+		sf.Seed = s.Sky.Seed
+		if s.Sky.Stars != 0 {
+			sf.Stars = s.Sky.Stars
+		}
+		if s.Sky.StarBrightness != 0 {
+			sf.StarBrightness = s.Sky.StarBrightness
+		}
+		if s.Sky.Nebula != 0 {
+			sf.Nebula = s.Sky.Nebula
+		}
+		if len(s.Sky.NebulaColors) == 2 {
+			sf.NebulaColors = [2]mgl32.Vec3{s.Sky.NebulaColors[0], s.Sky.NebulaColors[1]}
+		}
+		sky = draw.NewStarfieldSky(sf)
+	default:
+		return fmt.Errorf("setup sky: unknown type %q", s.Sky.Type)
+	}
+
+	w.SetSky(sky)
+	if s.Sky.Environment {
+		w.SetEnvironment(sky.Environment())
+	}
+	return nil
+}
+
+// SetupCameras creates the cameras the scene describes, adds them to u's camera manager and activates
+// the scene's starting camera. bodies holds the bodies chase cameras can target by name. Chase cameras
+// avoid being blocked by the bodies of u.
+func (s *Scene) SetupCameras(u *Universe, bodies map[string]*Body) error {
+	m := u.Cameras
+	for name, sc := range s.Cameras {
+		projection := sc.projection()
+
+		switch sc.Type {
+		case "chase":
+			target, ok := bodies[sc.Target]
+			if !ok {
+				return fmt.Errorf("setup camera %s: no body %q", name, sc.Target)
+			}
+			cam := NewChaseCam(target)
+			cam.SetProjection(projection)
+			cam.SetLocation(sc.Offset)
+			cam.SetLookAhead(sc.LookAhead)
+			cam.AvoidObstructions(u)
+			cam.Snap()
+			m.Add(name, cam)
+		case "free":
+			cam := NewFreeCam()
+			cam.SetProjection(projection)
+			cam.SetLocation(sc.Location)
+			cam.LookAt(sc.LookAt, mgl32.Vec3{0, 1, 0})
+			m.Add(name, cam)
+		case "orbit":
+			target, ok := bodies[sc.Target]
+			if !ok {
+				return fmt.Errorf("setup camera %s: no body %q", name, sc.Target)
+			}
+			cam := NewOrbitCam(target, sc.Radius)
+			cam.SetProjection(projection)
+			cam.SetAzimuth(mgl32.DegToRad(sc.Azimuth))
+			cam.SetElevation(mgl32.DegToRad(sc.Elevation))
+			cam.SetAutoRotate(mgl32.DegToRad(sc.AutoRotate))
+			m.Add(name, cam)
+		default:
+			return fmt.Errorf("setup camera %s: unknown type %q", name, sc.Type)
+		}
+	}
+
+	if s.Camera != "" {
+		if err := m.Activate(s.Camera, 0); err != nil {
+			return fmt.Errorf("setup cameras: %v", err)
+		}
+	}
+	return nil
+}
+
+// SetupLights creates the lights the scene describes, attaches them to their bodies and adds them to u.
+// bodies holds the bodies lights can be attached to by name. The created lights are returned by name.
+func (s *Scene) SetupLights(u *Universe, bodies map[string]*Body) (map[string]*draw.Light, error) {
+	if s.Ambient.Len() > 0 {
+		u.Window.SetAmbient(s.Ambient)
+	}
+
+	lights := make(map[string]*draw.Light, len(s.Lights))
+	for name, sl := range s.Lights {
+		color := sl.Color
+		if color.Len() == 0 {
+			color = mgl32.Vec3{1, 1, 1}
+		}
+		intensity := sl.Intensity
+		if intensity == 0 {
+			intensity = 1
+		}
+
+		var l *draw.Light
+		switch sl.Type {
+		case "point":
+			l = draw.NewPointLight(color, intensity, sl.Range)
+		case "spot":
+			l = draw.NewSpotLight(color, intensity, sl.Range, mgl32.DegToRad(sl.InnerCone), mgl32.DegToRad(sl.OuterCone))
+		case "directional":
+			l = draw.NewDirectionalLight(color, intensity, sl.Direction)
+		default:
+			return nil, fmt.Errorf("setup light %s: unknown type %q", name, sl.Type)
+		}
+		l.SetCastsShadows(sl.Shadows)
+
+		if sl.Body != "" {
+			b, ok := bodies[sl.Body]
+			if !ok {
+				return nil, fmt.Errorf("setup light %s: no body %q", name, sl.Body)
+			}
+			b.AttachLight(l, sl.Position, sl.Direction)
+		} else {
+			l.SetLocation(sl.Position)
+			if sl.Direction.Len() > 0 {
+				l.SetDirection(sl.Direction)
+			}
+		}
+
+		u.AddLight(l)
+		lights[name] = l
+	}
+	return lights, nil
+}
+
+// projection returns the projection sc describes.
+func (sc SceneCamera) projection() draw.Projection {
+	p := draw.DefaultProjection
+	if sc.FOV != 0 {
+		p.FOV = mgl32.DegToRad(sc.FOV)
+	}
+	if sc.Near != 0 {
+		p.Near = sc.Near
+	}
+	if sc.Far != 0 {
+		p.Far = sc.Far
+		p.Infinite = false
+	}
+	if sc.Height != 0 {
+		p.Height = sc.Height
+	}
+	p.Orthographic = sc.Orthographic
+	return p
+}
+
+// apply returns a copy of m with the changes sm describes.
+func (sm SceneMaterial) apply(u *Universe, m draw.Material) (*draw.Material, error) {
+	maps := []struct {
+		path string
+		dest **draw.Texture
+	}{
+		{sm.DiffuseMap, &m.DiffuseMap},
+		{sm.SpecularMap, &m.SpecularMap},
+		{sm.NormalMap, &m.NormalMap},
+		{sm.EmissiveMap, &m.EmissiveMap},
+		{sm.MetallicRoughnessMap, &m.MetallicRoughnessMap},
+	}
+	for _, mp := range maps {
+		if mp.path == "" {
+			continue
+		}
+		t, err := u.Texture(mp.path)
+		if err != nil {
+			return nil, err
+		}
+		*mp.dest = t
+	}
+
+	if sm.DiffuseColor != nil {
+		m.DiffuseColor = *sm.DiffuseColor
+	}
+	if sm.SpecularColor != nil {
+		m.SpecularColor = *sm.SpecularColor
+	}
+	if sm.Shininess != nil {
+		m.Shininess = *sm.Shininess
+	}
+	if sm.EmissiveColor != nil {
+		m.EmissiveColor = *sm.EmissiveColor
+	}
+	if sm.Metallic != nil {
+		m.Metallic = *sm.Metallic
+	}
+	if sm.Roughness != nil {
+		m.Roughness = *sm.Roughness
+	}
+
+	switch sm.Alpha {
+	case "":
+	case "opaque":
+		m.Alpha = draw.AlphaOpaque
+	case "mask":
+		m.Alpha = draw.AlphaMask
+	case "blend":
+		m.Alpha = draw.AlphaBlend
+	default:
+		return nil, fmt.Errorf("unknown alpha mode %q", sm.Alpha)
+	}
+	if sm.Opacity != nil {
+		m.Opacity = *sm.Opacity
+	}
+	if sm.AlphaCutoff != nil {
+		m.AlphaCutoff = *sm.AlphaCutoff
+	}
+	return &m, nil
+}
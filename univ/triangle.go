@@ -0,0 +1,45 @@
+package univ
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Triangle is a triangle defined by its three corners.
+type Triangle [3]mgl32.Vec3
+
+// Normal returns the unit normal of t, following the counter-clockwise winding of its corners.
+func (t Triangle) Normal() mgl32.Vec3 {
+	return t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).Normalize()
+}
+
+// Center returns the centroid of t.
+func (t Triangle) Center() mgl32.Vec3 {
+	return t[0].Add(t[1]).Add(t[2]).Mul(1.0 / 3.0)
+}
+
+// Intersect returns the distance along dir from origin at which the ray hits t, and whether it hits at
+// all. dir must be a unit vector. Both sides of t are hit.
+func (t Triangle) Intersect(origin, dir mgl32.Vec3) (float32, bool) {
+	const epsilon = 1e-7
+
+	e1 := t[1].Sub(t[0])
+	e2 := t[2].Sub(t[0])
+	p := dir.Cross(e2)
+	det := e1.Dot(p)
+	if det > -epsilon && det < epsilon {
+		return 0, false
+	}
+	inv := 1 / det
+
+	s := origin.Sub(t[0])
+	u := s.Dot(p) * inv
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := s.Cross(e1)
+	v := dir.Dot(q) * inv
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+
+	dist := e2.Dot(q) * inv
+	return dist, dist >= 0
+}
@@ -2,12 +2,14 @@ package univ
 
 import (
 	"fmt"
+	"sync"
 	"time"
 	"unsafe"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lsmith130/space/draw"
 	assimp "github.com/tbogdala/assimp-go"
+	"github.com/tbogdala/gombz"
 )
 
 // DefaultRefreshRate is the default refresh rate
@@ -17,30 +19,43 @@ const DefaultRefreshRate = time.Millisecond * 16
 // the univ package, and all Bodies are created in a Universe.
 type Universe struct {
 	// bodies is a set of bodies
-	bodies map[*Body]struct{}
-	Window *draw.Window
+	bodiesMut sync.Mutex
+	bodies    map[*Body]struct{}
+	Window    *draw.Window
+	Docking   *Docking
+	Damage    *DamageSystem
+	Cameras   *CameraManager
+
+	texturesMut sync.Mutex
+	textures    map[string]*draw.Texture
 }
 
 // NewUniverse constructs a new empty Universe
 func NewUniverse(window *draw.Window, updateRate time.Duration) *Universe {
 
 	u := &Universe{
-		bodies: make(map[*Body]struct{}),
-		Window: window,
+		bodies:   make(map[*Body]struct{}),
+		Window:   window,
+		Docking:  NewDocking(),
+		Cameras:  NewCameraManager(window),
+		textures: make(map[string]*draw.Texture),
 	}
+	u.Damage = NewDamageSystem(u)
 
 	return u
 }
 
-// NewBody constructs a new body in u with a given model and shader
-func (u *Universe) NewBody(modelPath string, program draw.Program, textures []*draw.Texture) (*Body, error) {
+// NewBody constructs a new body in u with a given model and shader. Meshes are drawn with the materials
+// of the model, except those overridden by name in overrides, which may be nil.
+func (u *Universe) NewBody(modelPath string, program draw.Program, overrides Materials) (*Body, error) {
 
 	meshes, err := assimp.ParseFile(modelPath)
 	if err != nil {
 		return nil, fmt.Errorf("load model %s: %v", modelPath, err)
 	}
-	if len(textures) > 0 && len(textures) != len(meshes) {
-		return nil, fmt.Errorf("%d textures dosen't match %d meshes", len(textures), len(meshes))
+	materialNames, materials, err := u.modelMaterials(modelPath, meshes, overrides)
+	if err != nil {
+		return nil, err
 	}
 
 	body := &Body{
@@ -48,46 +63,91 @@ func (u *Universe) NewBody(modelPath string, program draw.Program, textures []*d
 		animators: make([]*draw.Animator, len(meshes)),
 		rotation:  mgl32.QuatIdent(),
 		program:   program,
+		modelPath: modelPath,
 		observers: make(map[Observer]struct{}),
+
+		materialNames: materialNames,
 	}
 
 	switch program := program.(type) {
 	case *draw.BoneProgram:
 		for i, mesh := range meshes {
+			bones, vertBones := meshBones(mesh)
+			faces := *(*[]draw.MeshFace)(unsafe.Pointer(&mesh.Faces))
+			body.meshes[i] = program.NewMesh(mesh.Vertices, faces, mesh.UVChannels[0], mesh.Normals, mesh.Tangents, vertBones, mesh.VertexWeights, bones)
+			body.meshes[i].SetMaterial(materials[i])
 
-			bones := make([]mgl32.Mat4, mesh.BoneCount)
-			for _, bone := range mesh.Bones {
-				bones[bone.Id] = mgl32.Ident4()
-			}
-
-			vertBones := make([]draw.VertBone, len(mesh.VertexWeightIds))
-			for i, ids := range mesh.VertexWeightIds {
-				vertBones[i] = draw.VertBone{int32(ids.X()), int32(ids.Y()), int32(ids.Z()), int32(ids.W())}
+			body.animators[i] = draw.NewAnimator(mesh.Bones, mesh.Animations, body.meshes[i])
+		}
+	case *draw.PBRProgram:
+		for i, mesh := range meshes {
+			faces := *(*[]draw.MeshFace)(unsafe.Pointer(&mesh.Faces))
+			if mesh.BoneCount == 0 {
+				body.meshes[i] = program.NewMesh(mesh.Vertices, faces, mesh.UVChannels[0], mesh.Normals, mesh.Tangents)
+				body.meshes[i].SetMaterial(materials[i])
+				continue
 			}
 
-			faces := *(*[]draw.MeshFace)(unsafe.Pointer(&mesh.Faces))
-			body.meshes[i] = program.NewMesh(mesh.Vertices, faces, mesh.UVChannels[0], mesh.Normals, vertBones, mesh.VertexWeights, bones)
-			body.meshes[i].SetTexture(textures[i])
+			bones, vertBones := meshBones(mesh)
+			body.meshes[i] = program.NewSkinnedMesh(mesh.Vertices, faces, mesh.UVChannels[0], mesh.Normals, mesh.Tangents, vertBones, mesh.VertexWeights, bones)
+			body.meshes[i].SetMaterial(materials[i])
 
 			body.animators[i] = draw.NewAnimator(mesh.Bones, mesh.Animations, body.meshes[i])
 		}
 	case *draw.StandardProgram:
 		for i, mesh := range meshes {
 			faces := *(*[]draw.MeshFace)(unsafe.Pointer(&mesh.Faces))
-			body.meshes[i] = program.NewMesh(mesh.Vertices, faces, mesh.UVChannels[0], mesh.Normals)
-			body.meshes[i].SetTexture(textures[i])
+			body.meshes[i] = program.NewMesh(mesh.Vertices, faces, mesh.UVChannels[0], mesh.Normals, mesh.Tangents)
+			body.meshes[i].SetMaterial(materials[i])
 		}
 	}
 
+	for _, mesh := range meshes {
+		for _, v := range mesh.Vertices {
+			if l := v.Len(); l > body.radius {
+				body.radius = l
+			}
+		}
+	}
+
+	u.bodiesMut.Lock()
 	u.bodies[body] = struct{}{}
+	u.bodiesMut.Unlock()
 	body.ticker = draw.NewTicker(DefaultRefreshRate, body.velocityTick)
 	return body, nil
 }
 
+// meshBones returns the bones of mesh in their bind pose, and the bones each of its vertexes follows.
+func meshBones(mesh *gombz.Mesh) ([]mgl32.Mat4, []draw.VertBone) {
+	bones := make([]mgl32.Mat4, mesh.BoneCount)
+	for _, bone := range mesh.Bones {
+		bones[bone.Id] = mgl32.Ident4()
+	}
+
+	vertBones := make([]draw.VertBone, len(mesh.VertexWeightIds))
+	for i, ids := range mesh.VertexWeightIds {
+		vertBones[i] = draw.VertBone{int32(ids.X()), int32(ids.Y()), int32(ids.Z()), int32(ids.W())}
+	}
+	return bones, vertBones
+}
+
 // RemoveBody removes a body from u, such that it will no longer be drawn or recieve updates
 func (u *Universe) RemoveBody(body *Body) {
 	for _, mesh := range body.meshes {
 		body.program.RemoveMesh(mesh)
 	}
+	u.bodiesMut.Lock()
+	_, ok := u.bodies[body]
 	delete(u.bodies, body)
+	u.bodiesMut.Unlock()
+
+	if ok {
+		body.ticker.Close()
+		for _, l := range body.Lights() {
+			u.RemoveLight(l)
+		}
+		for _, e := range body.Emitters() {
+			u.RemoveEmitter(e)
+		}
+	}
 }